@@ -0,0 +1,358 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/RayMC17/bookclub-api/internal/data"
+	"github.com/RayMC17/bookclub-api/internal/validator"
+)
+
+// createAuthorInput is the body of POST /v1/authors.
+type createAuthorInput struct {
+	Name string `json:"name"`
+	Bio  string `json:"bio"`
+}
+
+func (a *applicationDependencies) createAuthorHandler(w http.ResponseWriter, r *http.Request) {
+	var input createAuthorInput
+	err := a.readJSON(w, r, &input)
+	if err != nil {
+		a.badRequestResponse(w, r, err)
+		return
+	}
+
+	author := &data.Author{
+		Name: input.Name,
+		Bio:  input.Bio,
+	}
+
+	v := validator.New()
+	data.ValidateAuthor(v, author)
+	if !v.Valid() {
+		a.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = a.models.Authors.Insert(r.Context(), author)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateAuthor):
+			v.AddError("name", "an author with this name already exists")
+			a.failedValidationResponse(w, r, v.Errors)
+		default:
+			a.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/authors/%d", author.ID))
+	err = a.writeJSON(w, http.StatusCreated, envelope{"author": author}, headers)
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+func (a *applicationDependencies) getAuthorHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := a.readIDParam(r)
+	if err != nil {
+		a.notFoundResponse(w, r)
+		return
+	}
+
+	author, err := a.models.Authors.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			a.notFoundResponse(w, r)
+		default:
+			a.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = a.writeTaggedJSON(w, r, http.StatusOK, envelope{"author": author}, nil, time.Time{})
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateAuthorInput is the body of PUT /v1/authors/:id. Omitted fields are
+// left unchanged.
+type updateAuthorInput struct {
+	Name *string `json:"name"`
+	Bio  *string `json:"bio"`
+}
+
+func (a *applicationDependencies) updateAuthorHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := a.readIDParam(r)
+	if err != nil {
+		a.notFoundResponse(w, r)
+		return
+	}
+
+	author, err := a.models.Authors.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			a.notFoundResponse(w, r)
+		default:
+			a.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if !a.checkIfMatch(w, r, envelope{"author": author}) {
+		return
+	}
+
+	var input updateAuthorInput
+	err = a.readJSON(w, r, &input)
+	if err != nil {
+		a.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Name != nil {
+		author.Name = *input.Name
+	}
+	if input.Bio != nil {
+		author.Bio = *input.Bio
+	}
+
+	v := validator.New()
+	data.ValidateAuthor(v, author)
+	if !v.Valid() {
+		a.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Check If-Match again in the transaction renaming the author, in case
+	// another request has changed them since
+	err = a.models.WithTx(r.Context(), func(tx data.Models) error {
+		current, err := tx.Authors.Get(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		err = ifMatch(r, envelope{"author": current})
+		if err != nil {
+			return err
+		}
+		return tx.Authors.Update(r.Context(), author)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateAuthor):
+			v.AddError("name", "an author with this name already exists; merge the authors instead")
+			a.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrRecordNotFound):
+			a.notFoundResponse(w, r)
+		case errors.Is(err, errPreconditionFailed):
+			a.preconditionFailedResponse(w, r)
+		default:
+			a.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = a.writeTaggedJSON(w, r, http.StatusOK, envelope{"author": author}, nil, time.Time{})
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+func (a *applicationDependencies) deleteAuthorHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := a.readIDParam(r)
+	if err != nil {
+		a.notFoundResponse(w, r)
+		return
+	}
+
+	author, err := a.models.Authors.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			a.notFoundResponse(w, r)
+		default:
+			a.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if !a.checkIfMatch(w, r, envelope{"author": author}) {
+		return
+	}
+
+	err = a.models.WithTx(r.Context(), func(tx data.Models) error {
+		current, err := tx.Authors.Get(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		err = ifMatch(r, envelope{"author": current})
+		if err != nil {
+			return err
+		}
+		return tx.Authors.Delete(r.Context(), id)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			a.notFoundResponse(w, r)
+		case errors.Is(err, errPreconditionFailed):
+			a.preconditionFailedResponse(w, r)
+		case errors.Is(err, data.ErrAuthorHasBooks):
+			message := "the author is credited on books, merge them into another author with POST /v1/authors/{id}/merge instead"
+			a.conflictResponse(w, r, message)
+		default:
+			a.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = a.writeJSON(w, http.StatusOK, envelope{"message": "author successfully deleted"}, nil)
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+func (a *applicationDependencies) listAuthorsHandler(w http.ResponseWriter, r *http.Request) {
+	var queryParams struct {
+		Name string
+		data.Filters
+	}
+
+	queryParams.Name = a.getSingleQueryParameter(r.URL.Query(), "name", "")
+
+	v := validator.New()
+	queryParams.Filters.Page = a.getSingleIntegerParameter(r.URL.Query(), "page", 1, v)
+	queryParams.Filters.PageSize = a.getSingleIntegerParameter(r.URL.Query(), "page_size", 10, v)
+	queryParams.Filters.Sort = a.getSingleQueryParameter(r.URL.Query(), "sort", "name")
+	queryParams.Filters.SortSafelist = []string{"id", "name", "-id", "-name"}
+
+	data.ValidateFilters(v, &queryParams.Filters)
+	if !v.Valid() {
+		a.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	authors, metadata, err := a.models.Authors.GetAll(r.Context(), queryParams.Name, queryParams.Filters)
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+		return
+	}
+
+	response := envelope{
+		"authors":  authors,
+		"metadata": metadata,
+	}
+	err = a.writeTaggedJSON(w, r, http.StatusOK, response, nil, time.Time{})
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+// listAuthorBooksHandler lists the books an author contributed to in any role.
+func (a *applicationDependencies) listAuthorBooksHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := a.readIDParam(r)
+	if err != nil {
+		a.notFoundResponse(w, r)
+		return
+	}
+
+	_, err = a.models.Authors.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			a.notFoundResponse(w, r)
+		default:
+			a.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var filters data.Filters
+	v := validator.New()
+	filters.Page = a.getSingleIntegerParameter(r.URL.Query(), "page", 1, v)
+	filters.PageSize = a.getSingleIntegerParameter(r.URL.Query(), "page_size", 10, v)
+	filters.Sort = a.getSingleQueryParameter(r.URL.Query(), "sort", "publication_date")
+	filters.SortSafelist = []string{"id", "title", "publication_date", "-id", "-title", "-publication_date"}
+
+	data.ValidateFilters(v, &filters)
+	if !v.Valid() {
+		a.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	books, metadata, err := a.models.Books.GetAllByAuthor(r.Context(), id, filters)
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+		return
+	}
+
+	response := envelope{
+		"books":    books,
+		"metadata": metadata,
+	}
+	err = a.writeTaggedJSON(w, r, http.StatusOK, response, nil, time.Time{})
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+// mergeAuthorsInput is the body of POST /v1/authors/:id/merge.
+type mergeAuthorsInput struct {
+	AuthorIDs []int `json:"author_ids"`
+}
+
+// mergeAuthorsHandler folds duplicate authors into the author in the URL.
+func (a *applicationDependencies) mergeAuthorsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := a.readIDParam(r)
+	if err != nil {
+		a.notFoundResponse(w, r)
+		return
+	}
+
+	var input mergeAuthorsInput
+	err = a.readJSON(w, r, &input)
+	if err != nil {
+		a.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(len(input.AuthorIDs) > 0, "author_ids", "must contain at least one author")
+	seen := make(map[int]bool)
+	for _, duplicateID := range input.AuthorIDs {
+		v.Check(duplicateID > 0, "author_ids", "must contain valid IDs")
+		v.Check(duplicateID != id, "author_ids", "must not contain the author being merged into")
+		v.Check(!seen[duplicateID], "author_ids", "must not contain duplicate values")
+		seen[duplicateID] = true
+	}
+	if !v.Valid() {
+		a.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = a.models.Authors.Merge(r.Context(), id, input.AuthorIDs)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			a.notFoundResponse(w, r)
+		default:
+			a.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	author, err := a.models.Authors.Get(r.Context(), id)
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = a.writeJSON(w, http.StatusOK, envelope{"author": author}, nil)
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/RayMC17/bookclub-api/internal/data"
+)
+
+func insertTestAuthor(t *testing.T, store *data.InMemory, name string) *data.Author {
+	t.Helper()
+	author := &data.Author{Name: name}
+	err := store.Authors.Insert(context.Background(), author)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return author
+}
+
+func TestCreateAuthorHandler(t *testing.T) {
+	app, store := newTestApplication(t)
+	insertTestAuthor(t, store, "Ursula K. Le Guin")
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantError  string
+	}{
+		{"valid", `{"name": "Frank Herbert", "bio": "Wrote Dune."}`, http.StatusCreated, ""},
+		{"missing name", `{"bio": "Anonymous."}`, http.StatusUnprocessableEntity, "name"},
+		{"duplicate name", `{"name": "ursula k. le guin"}`, http.StatusUnprocessableEntity, "name"},
+		{"unknown field", `{"name": "Frank Herbert", "born": 1920}`, http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := do(t, app, http.MethodPost, "/v1/authors", tt.body)
+			assertStatus(t, res, tt.wantStatus)
+
+			if tt.wantError != "" && res.field("error", tt.wantError) == nil {
+				t.Errorf("want a validation error for %q, got %v", tt.wantError, res.body)
+			}
+			if tt.wantStatus == http.StatusCreated {
+				if want := fmt.Sprintf("/v1/authors/%v", res.field("author", "id")); res.header.Get("Location") != want {
+					t.Errorf("got Location %q, want %q", res.header.Get("Location"), want)
+				}
+			}
+		})
+	}
+}
+
+func TestGetAuthorHandler(t *testing.T) {
+	app, store := newTestApplication(t)
+	author := insertTestAuthor(t, store, "Frank Herbert")
+
+	res := do(t, app, http.MethodGet, fmt.Sprintf("/v1/authors/%d", author.ID), "")
+	assertStatus(t, res, http.StatusOK)
+	if got := res.field("author", "name"); got != "Frank Herbert" {
+		t.Errorf("got name %v, want %q", got, "Frank Herbert")
+	}
+
+	res = do(t, app, http.MethodGet, "/v1/authors/999", "")
+	assertStatus(t, res, http.StatusNotFound)
+}
+
+func TestUpdateAuthorHandler(t *testing.T) {
+	app, store := newTestApplication(t)
+	author := insertTestAuthor(t, store, "Frank Herbet")
+	insertTestAuthor(t, store, "Brian Herbert")
+	book := insertTestBook(t, store, "Dune", "Frank Herbet")
+
+	path := fmt.Sprintf("/v1/authors/%d", author.ID)
+
+	res := doIfMatch(t, app, http.MethodPut, path, `{"name": "Brian Herbert"}`, path)
+	assertStatus(t, res, http.StatusUnprocessableEntity)
+
+	res = doIfMatch(t, app, http.MethodPut, path, `{"name": "Frank Herbert"}`, path)
+	assertStatus(t, res, http.StatusOK)
+	if got := res.field("author", "name"); got != "Frank Herbert" {
+		t.Errorf("got name %v, want %q", got, "Frank Herbert")
+	}
+
+	// The books the author is credited on show the new name
+	res = do(t, app, http.MethodGet, fmt.Sprintf("/v1/books/%d", book.ID), "")
+	assertStatus(t, res, http.StatusOK)
+	if got := fmt.Sprint(res.field("book", "authors")); got != "[Frank Herbert]" {
+		t.Errorf("got authors %s, want [Frank Herbert]", got)
+	}
+
+	res = doIfMatch(t, app, http.MethodPut, "/v1/authors/999", `{"name": "Nobody"}`, "/v1/authors/999")
+	assertStatus(t, res, http.StatusNotFound)
+}
+
+func TestDeleteAuthorHandler(t *testing.T) {
+	app, store := newTestApplication(t)
+	unused := insertTestAuthor(t, store, "Nobody In Particular")
+	credited := insertTestAuthor(t, store, "Frank Herbert")
+	insertTestBook(t, store, "Dune", "Frank Herbert")
+
+	path := fmt.Sprintf("/v1/authors/%d", credited.ID)
+	res := doIfMatch(t, app, http.MethodDelete, path, "", path)
+	assertStatus(t, res, http.StatusConflict)
+
+	path = fmt.Sprintf("/v1/authors/%d", unused.ID)
+	res = doIfMatch(t, app, http.MethodDelete, path, "", path)
+	assertStatus(t, res, http.StatusOK)
+
+	res = doIfMatch(t, app, http.MethodDelete, path, "", path)
+	assertStatus(t, res, http.StatusNotFound)
+}
+
+func TestListAuthorsHandler(t *testing.T) {
+	app, store := newTestApplication(t)
+	insertTestAuthor(t, store, "Frank Herbert")
+	insertTestAuthor(t, store, "Brian Herbert")
+	insertTestAuthor(t, store, "Ursula K. Le Guin")
+
+	res := do(t, app, http.MethodGet, "/v1/authors?name=herbert", "")
+	assertStatus(t, res, http.StatusOK)
+	authors, _ := res.field("authors").([]any)
+	if len(authors) != 2 {
+		t.Fatalf("got %d authors, want 2", len(authors))
+	}
+	if got := authors[0].(map[string]any)["name"]; got != "Brian Herbert" {
+		t.Errorf("got %v first, want Brian Herbert", got)
+	}
+
+	res = do(t, app, http.MethodGet, "/v1/authors?sort=bio", "")
+	assertStatus(t, res, http.StatusUnprocessableEntity)
+}
+
+func TestMergeAuthorsHandler(t *testing.T) {
+	app, store := newTestApplication(t)
+	target := insertTestAuthor(t, store, "Frank Herbert")
+	duplicate := insertTestAuthor(t, store, "F. Herbert")
+
+	// The book credits both spellings, which become one credit after the merge
+	book := &data.Book{
+		Title: "Dune",
+		Contributors: []data.Contributor{
+			{AuthorID: target.ID, Role: "author"},
+			{AuthorID: duplicate.ID, Role: "author"},
+		},
+		ISBN: "9780441172719",
+	}
+	err := store.Books.Insert(context.Background(), book)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := fmt.Sprintf("/v1/authors/%d/merge", target.ID)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"no authors", `{"author_ids": []}`, http.StatusUnprocessableEntity},
+		{"itself", fmt.Sprintf(`{"author_ids": [%d]}`, target.ID), http.StatusUnprocessableEntity},
+		{"repeated", fmt.Sprintf(`{"author_ids": [%d, %d]}`, duplicate.ID, duplicate.ID), http.StatusUnprocessableEntity},
+		{"unknown author", fmt.Sprintf(`{"author_ids": [%d, 999]}`, duplicate.ID), http.StatusNotFound},
+		{"valid", fmt.Sprintf(`{"author_ids": [%d]}`, duplicate.ID), http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := do(t, app, http.MethodPost, path, tt.body)
+			assertStatus(t, res, tt.wantStatus)
+		})
+	}
+
+	res := do(t, app, http.MethodGet, fmt.Sprintf("/v1/authors/%d", duplicate.ID), "")
+	assertStatus(t, res, http.StatusNotFound)
+
+	res = do(t, app, http.MethodGet, fmt.Sprintf("/v1/books/%d", book.ID), "")
+	assertStatus(t, res, http.StatusOK)
+	if got := fmt.Sprint(res.field("book", "authors")); got != "[Frank Herbert]" {
+		t.Errorf("got authors %s, want [Frank Herbert]", got)
+	}
+
+	res = do(t, app, http.MethodGet, fmt.Sprintf("/v1/authors/%d/books", target.ID), "")
+	assertStatus(t, res, http.StatusOK)
+	if books, _ := res.field("books").([]any); len(books) != 1 {
+		t.Errorf("got %d books for the merged author, want 1", len(books))
+	}
+}
+
+func TestCreateBookHandlerCreditsContributorsOnce(t *testing.T) {
+	app, store := newTestApplication(t)
+	fiction := insertTestGenre(t, store, "Science Fiction", nil)
+
+	body := fmt.Sprintf(`{
+		"title": "Dune",
+		"contributors": [
+			{"name": "Frank Herbert", "role": "author"},
+			{"name": "  frank   HERBERT ", "role": "author"},
+			{"name": "Frank Herbert", "role": "editor"}
+		],
+		"isbn": "9780441172719",
+		"genre_ids": [%d]
+	}`, fiction.ID)
+
+	res := do(t, app, http.MethodPost, "/v1/books", body)
+	assertStatus(t, res, http.StatusCreated)
+
+	contributors, _ := res.field("book", "contributors").([]any)
+	if len(contributors) != 2 {
+		t.Errorf("got %d contributors, want 2: %v", len(contributors), contributors)
+	}
+	if got := fmt.Sprint(res.field("book", "authors")); got != "[Frank Herbert]" {
+		t.Errorf("got authors %s, want [Frank Herbert]", got)
+	}
+}
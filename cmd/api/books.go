@@ -5,21 +5,32 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/RayMC17/bookclub-api/internal/data"
 	"github.com/RayMC17/bookclub-api/internal/validator"
 )
 
+// createBookInput is the body of POST /v1/books.
+type createBookInput struct {
+	Title           string             `json:"title"`
+	Authors         []string           `json:"authors"`
+	ISBN            string             `json:"isbn"`
+	PublicationDate string             `json:"publication_date"`
+	Genre           string             `json:"genre"`
+	GenreIDs        []int              `json:"genre_ids"`
+	Description     string             `json:"description"`
+	AverageRating   float64            `json:"average_rating"`
+	Contributors    []data.Contributor `json:"contributors"`
+	WorkID          int                `json:"work_id"`
+	Format          string             `json:"format"`
+	Language        string             `json:"language"`
+	PageCount       int                `json:"page_count"`
+	Publisher       string             `json:"publisher"`
+}
+
 func (a *applicationDependencies) createBookHandler(w http.ResponseWriter, r *http.Request) {
-	var incomingData struct {
-		Title           string   `json:"title"`
-		Authors         []string `json:"authors"`
-		ISBN            string   `json:"isbn"`
-		PublicationDate string   `json:"publication_date"`
-		Genre           string   `json:"genre"`
-		Description     string   `json:"description"`
-		AverageRating   float64  `json:"average_rating"`
-	}
+	var incomingData createBookInput
 	err := a.readJSON(w, r, &incomingData)
 	if err != nil {
 		a.badRequestResponse(w, r, err)
@@ -30,28 +41,59 @@ func (a *applicationDependencies) createBookHandler(w http.ResponseWriter, r *ht
 		Title:         incomingData.Title,
 		Authors:       incomingData.Authors,
 		ISBN:          incomingData.ISBN,
-		Genre:         incomingData.Genre,
 		Description:   incomingData.Description,
 		AverageRating: incomingData.AverageRating,
+		Contributors:  incomingData.Contributors,
+		WorkID:        incomingData.WorkID,
+		Format:        incomingData.Format,
+		Language:      incomingData.Language,
+		PageCount:     incomingData.PageCount,
+		Publisher:     incomingData.Publisher,
+	}
+	if book.Format == "" {
+		book.Format = "unknown"
+	}
+	if book.Language == "" {
+		book.Language = "en"
+	}
+	if book.Contributors == nil {
+		book.Contributors = data.ContributorsFromAuthors(book.Authors, nil)
 	}
 
 	v := validator.New()
+	book.Genres, err = a.resolveGenres(r.Context(), v, incomingData.Genre, incomingData.GenreIDs)
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+		return
+	}
 	data.ValidateBook(v, book)
 	if !v.Valid() {
 		a.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	err = a.bookModel.Insert(book)
+	err = a.models.Books.Insert(r.Context(), book)
 	if err != nil {
-		a.serverErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, data.ErrUnknownAuthor):
+			v.AddError("contributors", "must only reference existing authors")
+			a.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrUnknownWork):
+			v.AddError("work_id", "must reference an existing work")
+			a.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrUnknownGenre):
+			v.AddError("genre_ids", "must only reference existing genres")
+			a.failedValidationResponse(w, r, v.Errors)
+		default:
+			a.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
 	headers := make(http.Header)
-	headers.Set("Location", fmt.Sprintf("/api/v1/books/%d", book.ID))
+	headers.Set("Location", fmt.Sprintf("/v1/books/%d", book.ID))
 	data := envelope{"book": book}
-	err = a.writeJSON(w, http.StatusCreated, data, headers)
+	err = a.writeTaggedJSON(w, r, http.StatusCreated, data, headers, book.UpdatedAt)
 	if err != nil {
 		a.serverErrorResponse(w, r, err)
 	}
@@ -64,7 +106,7 @@ func (a *applicationDependencies) getBookHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
-	book, err := a.bookModel.Get(id)
+	book, err := a.models.Books.Get(r.Context(), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -76,12 +118,30 @@ func (a *applicationDependencies) getBookHandler(w http.ResponseWriter, r *http.
 	}
 
 	data := envelope{"book": book}
-	err = a.writeJSON(w, http.StatusOK, data, nil)
+	err = a.writeTaggedJSON(w, r, http.StatusOK, data, nil, book.UpdatedAt)
 	if err != nil {
 		a.serverErrorResponse(w, r, err)
 	}
 }
 
+// updateBookInput is the body of PUT /v1/books/:id. Omitted fields are left
+// unchanged.
+type updateBookInput struct {
+	Title         *string             `json:"title"`
+	Authors       *[]string           `json:"authors"`
+	ISBN          *string             `json:"isbn"`
+	Genre         *string             `json:"genre"`
+	GenreIDs      *[]int              `json:"genre_ids"`
+	Description   *string             `json:"description"`
+	AverageRating *float64            `json:"average_rating"`
+	Contributors  *[]data.Contributor `json:"contributors"`
+	WorkID        *int                `json:"work_id"`
+	Format        *string             `json:"format"`
+	Language      *string             `json:"language"`
+	PageCount     *int                `json:"page_count"`
+	Publisher     *string             `json:"publisher"`
+}
+
 func (a *applicationDependencies) updateBookHandler(w http.ResponseWriter, r *http.Request) {
 	id, err := a.readIDParam(r)
 	if err != nil {
@@ -89,7 +149,7 @@ func (a *applicationDependencies) updateBookHandler(w http.ResponseWriter, r *ht
 		return
 	}
 
-	book, err := a.bookModel.Get(id)
+	book, err := a.models.Books.Get(r.Context(), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -99,15 +159,11 @@ func (a *applicationDependencies) updateBookHandler(w http.ResponseWriter, r *ht
 		}
 		return
 	}
-
-	var incomingData struct {
-		Title         *string   `json:"title"`
-		Authors       *[]string `json:"authors"`
-		ISBN          *string   `json:"isbn"`
-		Genre         *string   `json:"genre"`
-		Description   *string   `json:"description"`
-		AverageRating *float64  `json:"average_rating"`
+	if !a.checkIfMatch(w, r, envelope{"book": book}) {
+		return
 	}
+
+	var incomingData updateBookInput
 	err = a.readJSON(w, r, &incomingData)
 	if err != nil {
 		a.badRequestResponse(w, r, err)
@@ -117,37 +173,94 @@ func (a *applicationDependencies) updateBookHandler(w http.ResponseWriter, r *ht
 	if incomingData.Title != nil {
 		book.Title = *incomingData.Title
 	}
-	if incomingData.Authors != nil {
-		book.Authors = *incomingData.Authors
+	if incomingData.Contributors != nil {
+		book.Contributors = *incomingData.Contributors
+	} else if incomingData.Authors != nil {
+		book.Contributors = data.ContributorsFromAuthors(*incomingData.Authors, book.Contributors)
 	}
 	if incomingData.ISBN != nil {
 		book.ISBN = *incomingData.ISBN
 	}
-	if incomingData.Genre != nil {
-		book.Genre = *incomingData.Genre
-	}
 	if incomingData.Description != nil {
 		book.Description = *incomingData.Description
 	}
 	if incomingData.AverageRating != nil {
 		book.AverageRating = *incomingData.AverageRating
 	}
+	if incomingData.WorkID != nil {
+		book.WorkID = *incomingData.WorkID
+	}
+	if incomingData.Format != nil {
+		book.Format = *incomingData.Format
+	}
+	if incomingData.Language != nil {
+		book.Language = *incomingData.Language
+	}
+	if incomingData.PageCount != nil {
+		book.PageCount = *incomingData.PageCount
+	}
+	if incomingData.Publisher != nil {
+		book.Publisher = *incomingData.Publisher
+	}
 
 	v := validator.New()
+	if incomingData.Genre != nil || incomingData.GenreIDs != nil {
+		var legacyGenre string
+		var genreIDs []int
+		if incomingData.Genre != nil {
+			legacyGenre = *incomingData.Genre
+		}
+		if incomingData.GenreIDs != nil {
+			genreIDs = *incomingData.GenreIDs
+		}
+		book.Genres, err = a.resolveGenres(r.Context(), v, legacyGenre, genreIDs)
+		if err != nil {
+			a.serverErrorResponse(w, r, err)
+			return
+		}
+	}
 	data.ValidateBook(v, book)
 	if !v.Valid() {
 		a.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	err = a.bookModel.Update(book)
+	// Check If-Match again in the transaction saving the book, in case
+	// another request has changed it since
+	err = a.models.WithTx(r.Context(), func(tx data.Models) error {
+		current, err := tx.Books.Get(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		err = ifMatch(r, envelope{"book": current})
+		if err != nil {
+			return err
+		}
+		return tx.Books.Update(r.Context(), book)
+	})
 	if err != nil {
-		a.serverErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			a.notFoundResponse(w, r)
+		case errors.Is(err, errPreconditionFailed):
+			a.preconditionFailedResponse(w, r)
+		case errors.Is(err, data.ErrUnknownAuthor):
+			v.AddError("contributors", "must only reference existing authors")
+			a.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrUnknownWork):
+			v.AddError("work_id", "must reference an existing work")
+			a.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrUnknownGenre):
+			v.AddError("genre_ids", "must only reference existing genres")
+			a.failedValidationResponse(w, r, v.Errors)
+		default:
+			a.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
 	data := envelope{"book": book}
-	err = a.writeJSON(w, http.StatusOK, data, nil)
+	err = a.writeTaggedJSON(w, r, http.StatusOK, data, nil, book.UpdatedAt)
 	if err != nil {
 		a.serverErrorResponse(w, r, err)
 	}
@@ -160,11 +273,37 @@ func (a *applicationDependencies) deleteBookHandler(w http.ResponseWriter, r *ht
 		return
 	}
 
-	err = a.bookModel.Delete(id)
+	book, err := a.models.Books.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			a.notFoundResponse(w, r)
+		default:
+			a.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if !a.checkIfMatch(w, r, envelope{"book": book}) {
+		return
+	}
+
+	err = a.models.WithTx(r.Context(), func(tx data.Models) error {
+		current, err := tx.Books.Get(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		err = ifMatch(r, envelope{"book": current})
+		if err != nil {
+			return err
+		}
+		return tx.Books.Delete(r.Context(), id)
+	})
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
 			a.notFoundResponse(w, r)
+		case errors.Is(err, errPreconditionFailed):
+			a.preconditionFailedResponse(w, r)
 		default:
 			a.serverErrorResponse(w, r, err)
 		}
@@ -180,8 +319,9 @@ func (a *applicationDependencies) deleteBookHandler(w http.ResponseWriter, r *ht
 
 func (a *applicationDependencies) listBooksHandler(w http.ResponseWriter, r *http.Request) {
 	var queryParametersData struct {
-		Title  string
-		Author string
+		Title   string
+		Author  string
+		GenreID int
 		data.Filters
 	}
 
@@ -192,6 +332,20 @@ func (a *applicationDependencies) listBooksHandler(w http.ResponseWriter, r *htt
 	queryParametersData.Author = a.getSingleQueryParameter(queryParameters, "author", "")
 	v := validator.New()
 
+	// Filtering by a genre includes all of its descendant genres
+	if genreName := a.getSingleQueryParameter(queryParameters, "genre", ""); genreName != "" {
+		genre, err := a.models.Genres.Resolve(r.Context(), genreName)
+		switch {
+		case errors.Is(err, data.ErrUnknownGenre):
+			v.AddError("genre", "must be a known genre or genre alias")
+		case err != nil:
+			a.serverErrorResponse(w, r, err)
+			return
+		default:
+			queryParametersData.GenreID = genre.ID
+		}
+	}
+
 	queryParametersData.Filters.Page = a.getSingleIntegerParameter(queryParameters, "page", 1, v)
 	queryParametersData.Filters.PageSize = a.getSingleIntegerParameter(queryParameters, "page_size", 10, v)
 	queryParametersData.Filters.Sort = a.getSingleQueryParameter(queryParameters, "sort", "id")
@@ -204,9 +358,10 @@ func (a *applicationDependencies) listBooksHandler(w http.ResponseWriter, r *htt
 		return
 	}
 
-	books, metadata, err := a.bookModel.GetAll(
+	books, metadata, err := a.models.Books.GetAll(r.Context(),
 		queryParametersData.Title,
 		queryParametersData.Author,
+		queryParametersData.GenreID,
 		queryParametersData.Filters,
 	)
 	if err != nil {
@@ -218,7 +373,7 @@ func (a *applicationDependencies) listBooksHandler(w http.ResponseWriter, r *htt
 		"books":     books,
 		"@metadata": metadata,
 	}
-	err = a.writeJSON(w, http.StatusOK, responseData, nil)
+	err = a.writeTaggedJSON(w, r, http.StatusOK, responseData, nil, time.Time{})
 	if err != nil {
 		a.serverErrorResponse(w, r, err)
 	}
@@ -251,7 +406,7 @@ func (a *applicationDependencies) searchBooksHandler(w http.ResponseWriter, r *h
 	}
 
 	// Search books in the database
-	books, metadata, err := a.bookModel.GetAll(queryParams.Title, queryParams.Author, queryParams.Filters)
+	books, metadata, err := a.models.Books.GetAll(r.Context(), queryParams.Title, queryParams.Author, 0, queryParams.Filters)
 	if err != nil {
 		a.serverErrorResponse(w, r, err)
 		return
@@ -289,7 +444,7 @@ func (a *applicationDependencies) listReadingListsHandler(w http.ResponseWriter,
 	}
 
 	// Retrieve reading lists from the database
-	lists, metadata, err := a.readingListModel.GetAll(filters)
+	lists, metadata, err := a.models.ReadingLists.GetAll(r.Context(), filters)
 	if err != nil {
 		a.serverErrorResponse(w, r, err)
 		return
@@ -300,7 +455,7 @@ func (a *applicationDependencies) listReadingListsHandler(w http.ResponseWriter,
 		"reading_lists": lists,
 		"metadata":      metadata,
 	}
-	err = a.writeJSON(w, http.StatusOK, response, nil)
+	err = a.writeTaggedJSON(w, r, http.StatusOK, response, nil, time.Time{})
 	if err != nil {
 		a.serverErrorResponse(w, r, err)
 	}
@@ -308,15 +463,14 @@ func (a *applicationDependencies) listReadingListsHandler(w http.ResponseWriter,
 
 func (a *applicationDependencies) getReadingListHandler(w http.ResponseWriter, r *http.Request) {
 	// Extract the reading list ID from the URL parameters
-	idParam := r.URL.Query().Get("id")
-	id, err := strconv.Atoi(idParam)
-	if err != nil || id < 1 {
+	id, err := a.readIDParam(r)
+	if err != nil {
 		a.notFoundResponse(w, r)
 		return
 	}
 
 	// Fetch the reading list from the database
-	readingList, err := a.readingListModel.Get(id)
+	readingList, err := a.models.ReadingLists.Get(r.Context(), id)
 	if err != nil {
 		if errors.Is(err, data.ErrRecordNotFound) {
 			a.notFoundResponse(w, r)
@@ -327,19 +481,22 @@ func (a *applicationDependencies) getReadingListHandler(w http.ResponseWriter, r
 	}
 
 	// Send the reading list in the response
-	err = a.writeJSON(w, http.StatusOK, envelope{"reading_list": readingList}, nil)
+	err = a.writeTaggedJSON(w, r, http.StatusOK, envelope{"reading_list": readingList}, nil, time.Time{})
 	if err != nil {
 		a.serverErrorResponse(w, r, err)
 	}
 }
 
+// createReadingListInput is the body of POST /v1/lists.
+type createReadingListInput struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Books       []int  `json:"books"` // IDs of books in the list
+	Status      string `json:"status" enum:"currently reading,completed"`
+}
+
 func (a *applicationDependencies) createReadingListHandler(w http.ResponseWriter, r *http.Request) {
-	var input struct {
-		Name        string `json:"name"`
-		Description string `json:"description"`
-		Books       []int  `json:"books"` // IDs of books in the list
-		Status      string `json:"status"`
-	}
+	var input createReadingListInput
 
 	// Decode JSON body
 	err := a.readJSON(w, r, &input)
@@ -364,22 +521,31 @@ func (a *applicationDependencies) createReadingListHandler(w http.ResponseWriter
 	}
 
 	// Insert the reading list into the database
-	err = a.readingListModel.Insert(readingList)
+	err = a.models.ReadingLists.Insert(r.Context(), readingList)
 	if err != nil {
 		a.serverErrorResponse(w, r, err)
 		return
 	}
 
 	headers := make(http.Header)
-	headers.Set("Location", "/api/v1/lists/"+strconv.Itoa(readingList.ID))
+	headers.Set("Location", "/v1/lists/"+strconv.Itoa(readingList.ID))
 
 	// Send the created reading list in the response
-	err = a.writeJSON(w, http.StatusCreated, envelope{"reading_list": readingList}, headers)
+	err = a.writeTaggedJSON(w, r, http.StatusCreated, envelope{"reading_list": readingList}, headers, time.Time{})
 	if err != nil {
 		a.serverErrorResponse(w, r, err)
 	}
 }
 
+// updateReadingListInput is the body of PUT /v1/lists/:id. Omitted fields are
+// left unchanged.
+type updateReadingListInput struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	Books       *[]int  `json:"books"` // IDs of books in the list
+	Status      *string `json:"status" enum:"currently reading,completed"`
+}
+
 func (a *applicationDependencies) updateReadingListHandler(w http.ResponseWriter, r *http.Request) {
 	// Extract the reading list ID from the URL parameters
 	id, err := a.readIDParam(r)
@@ -389,7 +555,7 @@ func (a *applicationDependencies) updateReadingListHandler(w http.ResponseWriter
 	}
 
 	// Fetch the existing reading list from the database
-	readingList, err := a.readingListModel.Get(id)
+	readingList, err := a.models.ReadingLists.Get(r.Context(), id)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -399,14 +565,12 @@ func (a *applicationDependencies) updateReadingListHandler(w http.ResponseWriter
 		}
 		return
 	}
+	if !a.checkIfMatch(w, r, envelope{"reading_list": readingList}) {
+		return
+	}
 
 	// Parse the JSON request body into an input struct
-	var input struct {
-		Name        *string `json:"name"`
-		Description *string `json:"description"`
-		Books       *[]int  `json:"books"` // IDs of books in the list
-		Status      *string `json:"status"`
-	}
+	var input updateReadingListInput
 
 	err = a.readJSON(w, r, &input)
 	if err != nil {
@@ -436,16 +600,34 @@ func (a *applicationDependencies) updateReadingListHandler(w http.ResponseWriter
 		return
 	}
 
-	// Save the updated reading list to the database
-	err = a.readingListModel.Update(readingList)
+	// Save the updated reading list to the database, unless another request
+	// has changed it since it was checked against If-Match
+	err = a.models.WithTx(r.Context(), func(tx data.Models) error {
+		current, err := tx.ReadingLists.Get(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		err = ifMatch(r, envelope{"reading_list": current})
+		if err != nil {
+			return err
+		}
+		return tx.ReadingLists.Update(r.Context(), readingList)
+	})
 	if err != nil {
-		a.serverErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			a.notFoundResponse(w, r)
+		case errors.Is(err, errPreconditionFailed):
+			a.preconditionFailedResponse(w, r)
+		default:
+			a.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
 	// Send the updated reading list in the response
 	data := envelope{"reading_list": readingList}
-	err = a.writeJSON(w, http.StatusOK, data, nil)
+	err = a.writeTaggedJSON(w, r, http.StatusOK, data, nil, time.Time{})
 	if err != nil {
 		a.serverErrorResponse(w, r, err)
 	}
@@ -459,12 +641,38 @@ func (a *applicationDependencies) deleteReadingListHandler(w http.ResponseWriter
 		return
 	}
 
+	readingList, err := a.models.ReadingLists.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			a.notFoundResponse(w, r)
+		default:
+			a.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if !a.checkIfMatch(w, r, envelope{"reading_list": readingList}) {
+		return
+	}
+
 	// Delete the reading list from the database.
-	err = a.readingListModel.Delete(id)
+	err = a.models.WithTx(r.Context(), func(tx data.Models) error {
+		current, err := tx.ReadingLists.Get(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		err = ifMatch(r, envelope{"reading_list": current})
+		if err != nil {
+			return err
+		}
+		return tx.ReadingLists.Delete(r.Context(), id)
+	})
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
 			a.notFoundResponse(w, r)
+		case errors.Is(err, errPreconditionFailed):
+			a.preconditionFailedResponse(w, r)
 		default:
 			a.serverErrorResponse(w, r, err)
 		}
@@ -479,6 +687,11 @@ func (a *applicationDependencies) deleteReadingListHandler(w http.ResponseWriter
 	}
 }
 
+// readingListBookInput is the body of POST and DELETE /v1/lists/:id/books.
+type readingListBookInput struct {
+	BookID int `json:"book_id"`
+}
+
 func (a *applicationDependencies) addBookToReadingListHandler(w http.ResponseWriter, r *http.Request) {
 	// Get the reading list ID from the URL parameters
 	readingListID, err := a.readIDParam(r)
@@ -488,9 +701,7 @@ func (a *applicationDependencies) addBookToReadingListHandler(w http.ResponseWri
 	}
 
 	// Decode the request body to get the book ID
-	var input struct {
-		BookID int `json:"book_id"`
-	}
+	var input readingListBookInput
 	err = a.readJSON(w, r, &input)
 	if err != nil {
 		a.badRequestResponse(w, r, err)
@@ -498,7 +709,7 @@ func (a *applicationDependencies) addBookToReadingListHandler(w http.ResponseWri
 	}
 
 	// Add the book to the reading list
-	err = a.readingListModel.AddBook(readingListID, input.BookID)
+	err = a.models.ReadingLists.AddBook(r.Context(), readingListID, input.BookID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -526,21 +737,45 @@ func (a *applicationDependencies) removeBookFromReadingListHandler(w http.Respon
 	}
 
 	// Decode the request body to get the book ID
-	var input struct {
-		BookID int `json:"book_id"`
-	}
+	var input readingListBookInput
 	err = a.readJSON(w, r, &input)
 	if err != nil {
 		a.badRequestResponse(w, r, err)
 		return
 	}
 
+	readingList, err := a.models.ReadingLists.Get(r.Context(), readingListID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			a.notFoundResponse(w, r)
+		default:
+			a.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if !a.checkIfMatch(w, r, envelope{"reading_list": readingList}) {
+		return
+	}
+
 	// Remove the book from the reading list
-	err = a.readingListModel.RemoveBook(readingListID, input.BookID)
+	err = a.models.WithTx(r.Context(), func(tx data.Models) error {
+		current, err := tx.ReadingLists.Get(r.Context(), readingListID)
+		if err != nil {
+			return err
+		}
+		err = ifMatch(r, envelope{"reading_list": current})
+		if err != nil {
+			return err
+		}
+		return tx.ReadingLists.RemoveBook(r.Context(), readingListID, input.BookID)
+	})
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
 			a.notFoundResponse(w, r)
+		case errors.Is(err, errPreconditionFailed):
+			a.preconditionFailedResponse(w, r)
 		default:
 			a.serverErrorResponse(w, r, err)
 		}
@@ -555,29 +790,133 @@ func (a *applicationDependencies) removeBookFromReadingListHandler(w http.Respon
 	}
 }
 
+// reorderReadingListInput is the body of PUT /v1/lists/:id/books.
+type reorderReadingListInput struct {
+	BookIDs []int `json:"book_ids"`
+}
+
+// reorderReadingListHandler changes the order of the books on a reading list.
+// The new order must name every book on the list exactly once.
+func (a *applicationDependencies) reorderReadingListHandler(w http.ResponseWriter, r *http.Request) {
+	readingListID, err := a.readIDParam(r)
+	if err != nil {
+		a.notFoundResponse(w, r)
+		return
+	}
+
+	var input reorderReadingListInput
+	err = a.readJSON(w, r, &input)
+	if err != nil {
+		a.badRequestResponse(w, r, err)
+		return
+	}
+
+	current, err := a.models.ReadingLists.Get(r.Context(), readingListID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			a.notFoundResponse(w, r)
+		default:
+			a.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if !a.checkIfMatch(w, r, envelope{"reading_list": current}) {
+		return
+	}
+
+	v := validator.New()
+	v.Check(input.BookIDs != nil, "book_ids", "must be provided")
+	if !v.Valid() {
+		a.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Check If-Match again, reorder the books and read the list back in the
+	// same transaction, so the response reflects exactly the order that was
+	// saved
+	var readingList *data.ReadingList
+	err = a.models.WithTx(r.Context(), func(tx data.Models) error {
+		current, err := tx.ReadingLists.Get(r.Context(), readingListID)
+		if err != nil {
+			return err
+		}
+		err = ifMatch(r, envelope{"reading_list": current})
+		if err != nil {
+			return err
+		}
+		err = tx.ReadingLists.Reorder(r.Context(), readingListID, input.BookIDs)
+		if err != nil {
+			return err
+		}
+		readingList, err = tx.ReadingLists.Get(r.Context(), readingListID)
+		return err
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			a.notFoundResponse(w, r)
+		case errors.Is(err, errPreconditionFailed):
+			a.preconditionFailedResponse(w, r)
+		case errors.Is(err, data.ErrInvalidOrder):
+			v.AddError("book_ids", "must list every book on the reading list exactly once")
+			a.failedValidationResponse(w, r, v.Errors)
+		default:
+			a.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = a.writeTaggedJSON(w, r, http.StatusOK, envelope{"reading_list": readingList}, nil, time.Time{})
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+// listReviewsHandler lists the reviews of every edition of the book's work.
 func (a *applicationDependencies) listReviewsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := a.readIDParam(r)
+	if err != nil {
+		a.notFoundResponse(w, r)
+		return
+	}
+
+	_, err = a.models.Books.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			a.notFoundResponse(w, r)
+		default:
+			a.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
 	var queryParams struct {
 		Rating int
 		Author string
 		data.Filters
 	}
 
-	queryParams.Rating = a.getSingleIntegerParameter(r.URL.Query(), "rating", 0, validator.New())
+	v := validator.New()
+	queryParams.Rating = a.getSingleIntegerParameter(r.URL.Query(), "rating", 0, v)
 	queryParams.Author = a.getSingleQueryParameter(r.URL.Query(), "author", "")
 
-	v := validator.New()
 	queryParams.Filters.Page = a.getSingleIntegerParameter(r.URL.Query(), "page", 1, v)
 	queryParams.Filters.PageSize = a.getSingleIntegerParameter(r.URL.Query(), "page_size", 10, v)
 	queryParams.Filters.Sort = a.getSingleQueryParameter(r.URL.Query(), "sort", "id")
 	queryParams.Filters.SortSafelist = []string{"id", "rating", "author", "-id", "-rating", "-author"}
 
+	if r.URL.Query().Has("rating") {
+		v.Check(queryParams.Rating >= 1 && queryParams.Rating <= 5, "rating", "must be between 1 and 5")
+	}
 	data.ValidateFilters(v, &queryParams.Filters)
 	if !v.Valid() {
 		a.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	reviews, metadata, err := a.reviewModel.GetAll(int64(queryParams.Rating), queryParams.Author, queryParams.Filters)
+	reviews, metadata, err := a.models.Reviews.GetAll(r.Context(), int64(id), queryParams.Rating, queryParams.Author, queryParams.Filters)
 	if err != nil {
 		a.serverErrorResponse(w, r, err)
 		return
@@ -587,12 +926,19 @@ func (a *applicationDependencies) listReviewsHandler(w http.ResponseWriter, r *h
 		"reviews":  reviews,
 		"metadata": metadata,
 	}
-	err = a.writeJSON(w, http.StatusOK, response, nil)
+	err = a.writeTaggedJSON(w, r, http.StatusOK, response, nil, time.Time{})
 	if err != nil {
 		a.serverErrorResponse(w, r, err)
 	}
 }
 
+// createReviewInput is the body of POST /v1/books/:id/reviews.
+type createReviewInput struct {
+	Author  string `json:"author"`
+	Content string `json:"content"`
+	Rating  int    `json:"rating"`
+}
+
 // createReviewHandler handles the creation of a new review for a specific book.
 func (a *applicationDependencies) createReviewHandler(w http.ResponseWriter, r *http.Request) {
 	// Parse book ID from the URL
@@ -603,11 +949,7 @@ func (a *applicationDependencies) createReviewHandler(w http.ResponseWriter, r *
 	}
 
 	// Define a structure to hold the expected data from the request body
-	var input struct {
-		Author  string `json:"author"`
-		Content string `json:"content"`
-		Rating  int    `json:"rating"`
-	}
+	var input createReviewInput
 
 	// Parse JSON request body
 	err = a.readJSON(w, r, &input)
@@ -632,22 +974,64 @@ func (a *applicationDependencies) createReviewHandler(w http.ResponseWriter, r *
 		return
 	}
 
-	// Insert the new review into the database
-	err = a.reviewModel.Insert(review)
+	// Insert the new review and recompute the book's rating in one transaction
+	err = a.models.WithTx(r.Context(), func(tx data.Models) error {
+		err := tx.Reviews.Insert(r.Context(), review)
+		if err != nil {
+			return err
+		}
+		return tx.Books.UpdateRating(r.Context(), id)
+	})
 	if err != nil {
-		a.serverErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			a.notFoundResponse(w, r)
+		default:
+			a.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
 	// Send a response with the created review
 	headers := make(http.Header)
-	headers.Set("Location", fmt.Sprintf("/api/v1/books/%d/reviews/%d", id, review.ID))
-	err = a.writeJSON(w, http.StatusCreated, envelope{"review": review}, headers)
+	headers.Set("Location", fmt.Sprintf("/v1/reviews/%d", review.ID))
+	err = a.writeTaggedJSON(w, r, http.StatusCreated, envelope{"review": review}, headers, review.UpdatedAt)
 	if err != nil {
 		a.serverErrorResponse(w, r, err)
 	}
 }
 
+func (a *applicationDependencies) getReviewHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := a.readIDParam(r)
+	if err != nil {
+		a.notFoundResponse(w, r)
+		return
+	}
+
+	review, err := a.models.Reviews.Get(r.Context(), int64(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoRecord):
+			a.notFoundResponse(w, r)
+		default:
+			a.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = a.writeTaggedJSON(w, r, http.StatusOK, envelope{"review": review}, nil, review.UpdatedAt)
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+// updateReviewInput is the body of PUT /v1/reviews/:id. Omitted fields are
+// left unchanged.
+type updateReviewInput struct {
+	Content *string `json:"content"`
+	Rating  *int    `json:"rating"`
+}
+
 func (a *applicationDependencies) updateReviewHandler(w http.ResponseWriter, r *http.Request) {
 	// Parse the review ID from the URL
 	id, err := a.readIDParam(r)
@@ -657,7 +1041,7 @@ func (a *applicationDependencies) updateReviewHandler(w http.ResponseWriter, r *
 	}
 	id64 := int64(id)
 	// Fetch the existing review from the database
-	review, err := a.reviewModel.Get(id64)
+	review, err := a.models.Reviews.Get(r.Context(), id64)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrNoRecord):
@@ -667,12 +1051,12 @@ func (a *applicationDependencies) updateReviewHandler(w http.ResponseWriter, r *
 		}
 		return
 	}
+	if !a.checkIfMatch(w, r, envelope{"review": review}) {
+		return
+	}
 
 	// Define a struct for holding the updated data
-	var input struct {
-		Content *string `json:"content"`
-		Rating  *int    `json:"rating"`
-	}
+	var input updateReviewInput
 
 	// Parse the input from the request body
 	err = a.readJSON(w, r, &input)
@@ -697,154 +1081,270 @@ func (a *applicationDependencies) updateReviewHandler(w http.ResponseWriter, r *
 		return
 	}
 
-	// Save the updated review
-	err = a.reviewModel.Update(review)
+	// Save the updated review, unless another request has changed it since
+	// it was checked against If-Match, and recompute the book's rating
+	err = a.models.WithTx(r.Context(), func(tx data.Models) error {
+		current, err := tx.Reviews.Get(r.Context(), id64)
+		if err != nil {
+			return err
+		}
+		err = ifMatch(r, envelope{"review": current})
+		if err != nil {
+			return err
+		}
+		err = tx.Reviews.Update(r.Context(), review)
+		if err != nil {
+			return err
+		}
+		return tx.Books.UpdateRating(r.Context(), int(review.BookID))
+	})
 	if err != nil {
-		a.serverErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, data.ErrNoRecord):
+			a.notFoundResponse(w, r)
+		case errors.Is(err, errPreconditionFailed):
+			a.preconditionFailedResponse(w, r)
+		default:
+			a.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
 	// Send the updated review in the response
 	response := envelope{"review": review}
-	err = a.writeJSON(w, http.StatusOK, response, nil)
+	err = a.writeTaggedJSON(w, r, http.StatusOK, response, nil, review.UpdatedAt)
 	if err != nil {
 		a.serverErrorResponse(w, r, err)
 	}
 }
 
 func (a *applicationDependencies) deleteReviewHandler(w http.ResponseWriter, r *http.Request) {
-    // Parse the review ID from the URL and convert it to int64
-    id, err := a.readIDParam(r)
-    if err != nil {
-        a.notFoundResponse(w, r)
-        return
-    }
-
-    // Convert the id to int64 if it's not already
-    id64 := int64(id)
-
-    // Delete the review
-    err = a.reviewModel.Delete(id64)
-    if err != nil {
-        switch {
-        case errors.Is(err, data.ErrNoRecord):
-            a.notFoundResponse(w, r)
-        default:
-            a.serverErrorResponse(w, r, err)
-        }
-        return
-    }
-
-    // Respond with a 204 No Content status code
-    w.WriteHeader(http.StatusNoContent)
+	// Parse the review ID from the URL and convert it to int64
+	id, err := a.readIDParam(r)
+	if err != nil {
+		a.notFoundResponse(w, r)
+		return
+	}
+
+	// Convert the id to int64 if it's not already
+	id64 := int64(id)
+
+	review, err := a.models.Reviews.Get(r.Context(), id64)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoRecord):
+			a.notFoundResponse(w, r)
+		default:
+			a.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if !a.checkIfMatch(w, r, envelope{"review": review}) {
+		return
+	}
+
+	// Delete the review and recompute the rating of the book it was about
+	err = a.models.WithTx(r.Context(), func(tx data.Models) error {
+		current, err := tx.Reviews.Get(r.Context(), id64)
+		if err != nil {
+			return err
+		}
+		err = ifMatch(r, envelope{"review": current})
+		if err != nil {
+			return err
+		}
+		err = tx.Reviews.Delete(r.Context(), id64)
+		if err != nil {
+			return err
+		}
+		return tx.Books.UpdateRating(r.Context(), int(review.BookID))
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrNoRecord):
+			a.notFoundResponse(w, r)
+		case errors.Is(err, errPreconditionFailed):
+			a.preconditionFailedResponse(w, r)
+		default:
+			a.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// Respond with a 204 No Content status code
+	w.WriteHeader(http.StatusNoContent)
 }
 
 func (a *applicationDependencies) getUserProfileHandler(w http.ResponseWriter, r *http.Request) {
-    // Extract user ID from the URL parameters
-    id, err := a.readIDParam(r)
-    if err != nil {
-        a.notFoundResponse(w, r)
-        return
-    }
-
-    // Get the user profile from the database using the user model
-    profile, err := a.userModel.Get(id)
-    if err != nil {
-        switch {
-        case errors.Is(err, data.ErrNoRecord):
-            a.notFoundResponse(w, r)
-        default:
-            a.serverErrorResponse(w, r, err)
-        }
-        return
-    }
-
-    // Respond with the user profile data in JSON format
-    err = a.writeJSON(w, http.StatusOK, envelope{"user_profile": profile}, nil)
-    if err != nil {
-        a.serverErrorResponse(w, r, err)
-    }
+	// Extract user ID from the URL parameters
+	id, err := a.readIDParam(r)
+	if err != nil {
+		a.notFoundResponse(w, r)
+		return
+	}
+
+	// Get the user profile from the database using the user model
+	profile, err := a.models.Users.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			a.notFoundResponse(w, r)
+		default:
+			a.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// Respond with the user profile data in JSON format
+	err = a.writeTaggedJSON(w, r, http.StatusOK, envelope{"user_profile": profile}, nil, time.Time{})
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
 }
 
-func (a *applicationDependencies) getUserReadingListsHandler(w http.ResponseWriter, r *http.Request) {
-    // Get the user ID from the URL parameters
-    id, err := a.readIDParam(r)
-    if err != nil {
-        a.notFoundResponse(w, r)
-        return
-    }
-
-    // Initialize the filters for pagination and sorting
-    var filters data.Filters
-    v := validator.New()
-
-    filters.Page = a.getSingleIntegerParameter(r.URL.Query(), "page", 1, v)
-    filters.PageSize = a.getSingleIntegerParameter(r.URL.Query(), "page_size", 10, v)
-    filters.Sort = a.getSingleQueryParameter(r.URL.Query(), "sort", "id")
-    filters.SortSafelist = []string{"id", "name", "-id", "-name"}
-
-    data.ValidateFilters(v, &filters)
-    if !v.Valid() {
-        a.failedValidationResponse(w, r, v.Errors)
-        return
-    }
-
-    // Get the reading lists associated with the user from the model
-    readingLists, metadata, err := a.readingListModel.GetAllByUser(int64(id), filters)
-    if err != nil {
-        a.serverErrorResponse(w, r, err)
-        return
-    }
-
-    // Respond with the reading lists and metadata in JSON format
-    response := envelope{
-        "reading_lists": readingLists,
-        "metadata":      metadata,
-    }
-    err = a.writeJSON(w, http.StatusOK, response, nil)
-    if err != nil {
-        a.serverErrorResponse(w, r, err)
-    }
+// deleteUserHandler deletes a user together with their reviews, reading lists
+// and tags, recomputing the ratings of the books they reviewed.
+func (a *applicationDependencies) deleteUserHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := a.readIDParam(r)
+	if err != nil {
+		a.notFoundResponse(w, r)
+		return
+	}
+
+	profile, err := a.models.Users.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			a.notFoundResponse(w, r)
+		default:
+			a.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if !a.checkIfMatch(w, r, envelope{"user_profile": profile}) {
+		return
+	}
+
+	err = a.models.WithTx(r.Context(), func(tx data.Models) error {
+		current, err := tx.Users.Get(r.Context(), id)
+		if err != nil {
+			return err
+		}
+		err = ifMatch(r, envelope{"user_profile": current})
+		if err != nil {
+			return err
+		}
+
+		bookIDs, err := tx.Reviews.DeleteAllByUser(r.Context(), int64(id))
+		if err != nil {
+			return err
+		}
+
+		for _, bookID := range bookIDs {
+			err = tx.Books.UpdateRating(r.Context(), int(bookID))
+			if err != nil {
+				return err
+			}
+		}
+
+		return tx.Users.Delete(r.Context(), id)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			a.notFoundResponse(w, r)
+		case errors.Is(err, errPreconditionFailed):
+			a.preconditionFailedResponse(w, r)
+		default:
+			a.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = a.writeJSON(w, http.StatusOK, envelope{"message": "user successfully deleted"}, nil)
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
 }
 
+func (a *applicationDependencies) getUserReadingListsHandler(w http.ResponseWriter, r *http.Request) {
+	// Get the user ID from the URL parameters
+	id, err := a.readIDParam(r)
+	if err != nil {
+		a.notFoundResponse(w, r)
+		return
+	}
+
+	// Initialize the filters for pagination and sorting
+	var filters data.Filters
+	v := validator.New()
+
+	filters.Page = a.getSingleIntegerParameter(r.URL.Query(), "page", 1, v)
+	filters.PageSize = a.getSingleIntegerParameter(r.URL.Query(), "page_size", 10, v)
+	filters.Sort = a.getSingleQueryParameter(r.URL.Query(), "sort", "id")
+	filters.SortSafelist = []string{"id", "name", "-id", "-name"}
+
+	data.ValidateFilters(v, &filters)
+	if !v.Valid() {
+		a.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Get the reading lists associated with the user from the model
+	readingLists, metadata, err := a.models.ReadingLists.GetAllByUser(r.Context(), int64(id), filters)
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// Respond with the reading lists and metadata in JSON format
+	response := envelope{
+		"reading_lists": readingLists,
+		"metadata":      metadata,
+	}
+	err = a.writeTaggedJSON(w, r, http.StatusOK, response, nil, time.Time{})
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
 
 func (a *applicationDependencies) getUserReviewsHandler(w http.ResponseWriter, r *http.Request) {
-    // Get the user ID from the URL parameters
-    id, err := a.readIDParam(r)
-    if err != nil {
-        a.notFoundResponse(w, r)
-        return
-    }
-
-    // Initialize the filters for pagination and sorting
-    var filters data.Filters
-    v := validator.New()
-
-    filters.Page = a.getSingleIntegerParameter(r.URL.Query(), "page", 1, v)
-    filters.PageSize = a.getSingleIntegerParameter(r.URL.Query(), "page_size", 10, v)
-    filters.Sort = a.getSingleQueryParameter(r.URL.Query(), "sort", "id")
-    filters.SortSafelist = []string{"id", "rating", "author", "-id", "-rating", "-author"}
-
-    data.ValidateFilters(v, &filters)
-    if !v.Valid() {
-        a.failedValidationResponse(w, r, v.Errors)
-        return
-    }
-
-    // Get the reviews associated with the user from the model
-    reviews, metadata, err := a.reviewModel.GetAllByUser(int64(id), filters)
-    if err != nil {
-        a.serverErrorResponse(w, r, err)
-        return
-    }
-
-    // Respond with the reviews and metadata in JSON format
-    response := envelope{
-        "reviews":  reviews,
-        "metadata": metadata,
-    }
-    err = a.writeJSON(w, http.StatusOK, response, nil)
-    if err != nil {
-        a.serverErrorResponse(w, r, err)
-    }
+	// Get the user ID from the URL parameters
+	id, err := a.readIDParam(r)
+	if err != nil {
+		a.notFoundResponse(w, r)
+		return
+	}
+
+	// Initialize the filters for pagination and sorting
+	var filters data.Filters
+	v := validator.New()
+
+	filters.Page = a.getSingleIntegerParameter(r.URL.Query(), "page", 1, v)
+	filters.PageSize = a.getSingleIntegerParameter(r.URL.Query(), "page_size", 10, v)
+	filters.Sort = a.getSingleQueryParameter(r.URL.Query(), "sort", "id")
+	filters.SortSafelist = []string{"id", "rating", "author", "-id", "-rating", "-author"}
+
+	data.ValidateFilters(v, &filters)
+	if !v.Valid() {
+		a.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// Get the reviews associated with the user from the model
+	reviews, metadata, err := a.models.Reviews.GetAllByUser(r.Context(), int64(id), filters)
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// Respond with the reviews and metadata in JSON format
+	response := envelope{
+		"reviews":  reviews,
+		"metadata": metadata,
+	}
+	err = a.writeTaggedJSON(w, r, http.StatusOK, response, nil, time.Time{})
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
 }
@@ -0,0 +1,657 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/RayMC17/bookclub-api/internal/data"
+)
+
+func insertTestGenre(t *testing.T, store *data.InMemory, name string, parentID *int) *data.Genre {
+	t.Helper()
+	genre := &data.Genre{Name: name, ParentID: parentID}
+	err := store.Genres.Insert(context.Background(), genre, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return genre
+}
+
+func insertTestBook(t *testing.T, store *data.InMemory, title string, author string, genres ...data.Genre) *data.Book {
+	t.Helper()
+	book := &data.Book{
+		Title:        title,
+		Contributors: data.ContributorsFromAuthors([]string{author}, nil),
+		ISBN:         "9780441172719",
+		Format:       "paperback",
+		Language:     "en",
+		Genres:       genres,
+	}
+	err := store.Books.Insert(context.Background(), book)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return book
+}
+
+func insertTestUser(t *testing.T, store *data.InMemory, username string) *data.User {
+	t.Helper()
+	user := &data.User{Username: username, Email: username + "@example.com"}
+	err := store.Users.Insert(context.Background(), user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func insertTestReadingList(t *testing.T, store *data.InMemory, name string, userID int) *data.ReadingList {
+	t.Helper()
+	list := &data.ReadingList{Name: name, CreatedBy: userID, Status: "currently reading"}
+	err := store.ReadingLists.Insert(context.Background(), list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return list
+}
+
+func insertTestReview(t *testing.T, store *data.InMemory, bookID int, author string, rating int) *data.Review {
+	t.Helper()
+	review := &data.Review{BookID: int64(bookID), Author: author, Rating: rating, Content: "Worth reading."}
+	err := store.Reviews.Insert(context.Background(), review)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return review
+}
+
+// failingBooks makes every lookup fail with err, to exercise the error paths.
+type failingBooks struct {
+	data.Books
+	err error
+}
+
+func (b failingBooks) Get(ctx context.Context, id int) (*data.Book, error) {
+	return nil, b.err
+}
+
+func TestCreateBookHandler(t *testing.T) {
+	app, store := newTestApplication(t)
+	fiction := insertTestGenre(t, store, "Science Fiction", nil)
+
+	valid := fmt.Sprintf(`{"title": "Dune", "authors": ["Frank Herbert"], "isbn": "9780441172719", "genre_ids": [%d]}`, fiction.ID)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantError  string
+	}{
+		{"valid", valid, http.StatusCreated, ""},
+		{"legacy genre", `{"title": "Dune", "authors": ["Frank Herbert"], "isbn": "9780441172719", "genre": "science fiction"}`, http.StatusCreated, ""},
+		{"empty body", ``, http.StatusBadRequest, ""},
+		{"unknown field", `{"title": "Dune", "rating": 5}`, http.StatusBadRequest, ""},
+		{"missing title", fmt.Sprintf(`{"authors": ["Frank Herbert"], "isbn": "9780441172719", "genre_ids": [%d]}`, fiction.ID), http.StatusUnprocessableEntity, "title"},
+		{"missing authors", fmt.Sprintf(`{"title": "Dune", "isbn": "9780441172719", "genre_ids": [%d]}`, fiction.ID), http.StatusUnprocessableEntity, "authors"},
+		{"short isbn", fmt.Sprintf(`{"title": "Dune", "authors": ["Frank Herbert"], "isbn": "123", "genre_ids": [%d]}`, fiction.ID), http.StatusUnprocessableEntity, "isbn"},
+		{"no genres", `{"title": "Dune", "authors": ["Frank Herbert"], "isbn": "9780441172719"}`, http.StatusUnprocessableEntity, "genres"},
+		{"unknown legacy genre", `{"title": "Dune", "authors": ["Frank Herbert"], "isbn": "9780441172719", "genre": "nope"}`, http.StatusUnprocessableEntity, "genre"},
+		{"unknown genre id", `{"title": "Dune", "authors": ["Frank Herbert"], "isbn": "9780441172719", "genre_ids": [999]}`, http.StatusUnprocessableEntity, "genre_ids"},
+		{"unknown work", fmt.Sprintf(`{"title": "Dune", "authors": ["Frank Herbert"], "isbn": "9780441172719", "genre_ids": [%d], "work_id": 999}`, fiction.ID), http.StatusUnprocessableEntity, "work_id"},
+		{"unknown author", fmt.Sprintf(`{"title": "Dune", "contributors": [{"author_id": 999, "role": "author"}], "isbn": "9780441172719", "genre_ids": [%d]}`, fiction.ID), http.StatusUnprocessableEntity, "contributors"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := do(t, app, http.MethodPost, "/v1/books", tt.body)
+			assertStatus(t, res, tt.wantStatus)
+
+			if tt.wantError != "" && res.field("error", tt.wantError) == nil {
+				t.Errorf("want a validation error for %q, got %v", tt.wantError, res.body)
+			}
+			if tt.wantStatus == http.StatusCreated {
+				id := res.field("book", "id")
+				if want := fmt.Sprintf("/v1/books/%v", id); res.header.Get("Location") != want {
+					t.Errorf("got Location %q, want %q", res.header.Get("Location"), want)
+				}
+				if got := res.field("book", "format"); got != "unknown" {
+					t.Errorf("got format %v, want the default \"unknown\"", got)
+				}
+			}
+		})
+	}
+}
+
+func TestGetBookHandler(t *testing.T) {
+	app, store := newTestApplication(t)
+	book := insertTestBook(t, store, "Dune", "Frank Herbert")
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+	}{
+		{"existing", fmt.Sprintf("/v1/books/%d", book.ID), http.StatusOK},
+		{"missing", "/v1/books/999", http.StatusNotFound},
+		{"invalid id", "/v1/books/abc", http.StatusNotFound},
+		{"zero id", "/v1/books/0", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := do(t, app, http.MethodGet, tt.path, "")
+			assertStatus(t, res, tt.wantStatus)
+			if tt.wantStatus == http.StatusOK && res.field("book", "title") != "Dune" {
+				t.Errorf("got book %v", res.field("book"))
+			}
+		})
+	}
+
+	t.Run("store error", func(t *testing.T) {
+		app.models.Books = failingBooks{store.Books, errors.New("connection refused")}
+		res := do(t, app, http.MethodGet, fmt.Sprintf("/v1/books/%d", book.ID), "")
+		assertStatus(t, res, http.StatusInternalServerError)
+	})
+
+	t.Run("store timeout", func(t *testing.T) {
+		app.models.Books = failingBooks{store.Books, fmt.Errorf("get book: %w", context.DeadlineExceeded)}
+		res := do(t, app, http.MethodGet, fmt.Sprintf("/v1/books/%d", book.ID), "")
+		assertStatus(t, res, http.StatusServiceUnavailable)
+		if res.header.Get("Retry-After") == "" {
+			t.Error("missing Retry-After header")
+		}
+	})
+}
+
+func TestUpdateBookHandler(t *testing.T) {
+	app, store := newTestApplication(t)
+	fiction := insertTestGenre(t, store, "Fiction", nil)
+	book := insertTestBook(t, store, "Dune", "Frank Herbert", *fiction)
+	path := fmt.Sprintf("/v1/books/%d", book.ID)
+
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		wantStatus int
+		wantError  string
+	}{
+		{"partial update", path, `{"title": "Dune Messiah", "page_count": 256}`, http.StatusOK, ""},
+		{"replace authors", path, `{"authors": ["Frank Herbert", "Brian Herbert"]}`, http.StatusOK, ""},
+		{"missing", "/v1/books/999", `{"title": "Dune"}`, http.StatusNotFound, ""},
+		{"invalid id", "/v1/books/abc", `{"title": "Dune"}`, http.StatusNotFound, ""},
+		{"bad json", path, `{"title": `, http.StatusBadRequest, ""},
+		{"empty title", path, `{"title": ""}`, http.StatusUnprocessableEntity, "title"},
+		{"bad format", path, `{"format": "scroll"}`, http.StatusUnprocessableEntity, "format"},
+		{"unknown work", path, `{"work_id": 999}`, http.StatusUnprocessableEntity, "work_id"},
+		{"unknown genre", path, `{"genre_ids": [999]}`, http.StatusUnprocessableEntity, "genre_ids"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := doIfMatch(t, app, http.MethodPut, tt.path, tt.body, tt.path)
+			assertStatus(t, res, tt.wantStatus)
+			if tt.wantError != "" && res.field("error", tt.wantError) == nil {
+				t.Errorf("want a validation error for %q, got %v", tt.wantError, res.body)
+			}
+		})
+	}
+
+	stored, err := store.Books.Get(context.Background(), book.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Title != "Dune Messiah" || stored.PageCount != 256 || len(stored.Authors) != 2 {
+		t.Errorf("successful updates weren't saved: %+v", stored)
+	}
+	if stored.Format != "paperback" {
+		t.Errorf("a failed update was saved: format is %q", stored.Format)
+	}
+}
+
+func TestDeleteBookHandler(t *testing.T) {
+	app, store := newTestApplication(t)
+	book := insertTestBook(t, store, "Dune", "Frank Herbert")
+	review := insertTestReview(t, store, book.ID, "alice", 5)
+	path := fmt.Sprintf("/v1/books/%d", book.ID)
+
+	res := doIfMatch(t, app, http.MethodDelete, path, "", path)
+	assertStatus(t, res, http.StatusOK)
+
+	if _, err := store.Reviews.Get(context.Background(), review.ID); !errors.Is(err, data.ErrNoRecord) {
+		t.Errorf("the book's review wasn't deleted: %v", err)
+	}
+
+	res = doIfMatch(t, app, http.MethodDelete, path, "", path)
+	assertStatus(t, res, http.StatusNotFound)
+
+	res = do(t, app, http.MethodDelete, "/v1/books/abc", "")
+	assertStatus(t, res, http.StatusNotFound)
+}
+
+func TestListBooksHandler(t *testing.T) {
+	app, store := newTestApplication(t)
+	fiction := insertTestGenre(t, store, "Fiction", nil)
+	fantasy := insertTestGenre(t, store, "Fantasy", &fiction.ID)
+	history := insertTestGenre(t, store, "History", nil)
+	insertTestBook(t, store, "The Hobbit", "J.R.R. Tolkien", *fantasy)
+	insertTestBook(t, store, "Dune", "Frank Herbert", *fiction)
+	insertTestBook(t, store, "SPQR", "Mary Beard", *history)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantTitles []string
+	}{
+		{"all", "", http.StatusOK, []string{"The Hobbit", "Dune", "SPQR"}},
+		{"title filter", "?title=dun", http.StatusOK, []string{"Dune"}},
+		{"author filter", "?author=beard", http.StatusOK, []string{"SPQR"}},
+		{"genre includes descendants", "?genre=fiction", http.StatusOK, []string{"The Hobbit", "Dune"}},
+		{"sort by title", "?sort=title", http.StatusOK, []string{"Dune", "SPQR", "The Hobbit"}},
+		{"sort descending", "?sort=-id", http.StatusOK, []string{"SPQR", "Dune", "The Hobbit"}},
+		{"second page", "?page=2&page_size=2", http.StatusOK, []string{"SPQR"}},
+		{"unknown genre", "?genre=poetry", http.StatusUnprocessableEntity, nil},
+		{"bad sort", "?sort=isbn", http.StatusUnprocessableEntity, nil},
+		{"bad page", "?page=abc", http.StatusUnprocessableEntity, nil},
+		{"page size too large", "?page_size=101", http.StatusUnprocessableEntity, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := do(t, app, http.MethodGet, "/v1/books"+tt.query, "")
+			assertStatus(t, res, tt.wantStatus)
+			if tt.wantTitles == nil {
+				return
+			}
+
+			books, _ := res.field("books").([]any)
+			var titles []string
+			for _, book := range books {
+				titles = append(titles, book.(map[string]any)["title"].(string))
+			}
+			if fmt.Sprint(titles) != fmt.Sprint(tt.wantTitles) {
+				t.Errorf("got titles %v, want %v", titles, tt.wantTitles)
+			}
+		})
+	}
+
+	res := do(t, app, http.MethodGet, "/v1/books?page_size=2", "")
+	if got := res.field("@metadata", "total_pages"); got != float64(2) {
+		t.Errorf("got total_pages %v, want 2", got)
+	}
+}
+
+func TestSearchBooksHandler(t *testing.T) {
+	app, store := newTestApplication(t)
+	insertTestBook(t, store, "Dune", "Frank Herbert")
+	insertTestBook(t, store, "Neuromancer", "William Gibson")
+
+	handler := http.HandlerFunc(app.searchBooksHandler)
+
+	res := serve(t, handler, http.MethodGet, "/v1/books/search?author=gibson", "")
+	assertStatus(t, res, http.StatusOK)
+	if books, _ := res.field("books").([]any); len(books) != 1 {
+		t.Errorf("got %d books, want 1", len(books))
+	}
+
+	res = serve(t, handler, http.MethodGet, "/v1/books/search?sort=nope", "")
+	assertStatus(t, res, http.StatusUnprocessableEntity)
+}
+
+func TestReadingListHandlers(t *testing.T) {
+	app, store := newTestApplication(t)
+	user := insertTestUser(t, store, "alice")
+	book := insertTestBook(t, store, "Dune", "Frank Herbert")
+	list := insertTestReadingList(t, store, "Summer", user.ID)
+	listPath := fmt.Sprintf("/v1/lists/%d", list.ID)
+	booksPath := listPath + "/books"
+	bookBody := fmt.Sprintf(`{"book_id": %d}`, book.ID)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{"list", http.MethodGet, "/v1/lists?sort=-name", "", http.StatusOK},
+		{"list bad sort", http.MethodGet, "/v1/lists?sort=status", "", http.StatusUnprocessableEntity},
+		{"get", http.MethodGet, listPath, "", http.StatusOK},
+		{"get missing", http.MethodGet, "/v1/lists/999", "", http.StatusNotFound},
+		{"get invalid id", http.MethodGet, "/v1/lists/abc", "", http.StatusNotFound},
+		{"create", http.MethodPost, "/v1/lists", `{"name": "Winter", "status": "completed"}`, http.StatusCreated},
+		{"create bad json", http.MethodPost, "/v1/lists", `[]`, http.StatusBadRequest},
+		{"create invalid", http.MethodPost, "/v1/lists", `{"name": "", "status": "draft"}`, http.StatusUnprocessableEntity},
+		{"update", http.MethodPut, listPath, `{"description": "Beach reads"}`, http.StatusOK},
+		{"update missing", http.MethodPut, "/v1/lists/999", `{"name": "x"}`, http.StatusNotFound},
+		{"update bad json", http.MethodPut, listPath, `{`, http.StatusBadRequest},
+		{"update invalid", http.MethodPut, listPath, `{"status": "draft"}`, http.StatusUnprocessableEntity},
+		{"update status", http.MethodPut, listPath, `{"status": "completed"}`, http.StatusOK},
+		{"add book", http.MethodPost, booksPath, bookBody, http.StatusOK},
+		{"add book twice", http.MethodPost, booksPath, bookBody, http.StatusOK},
+		{"add missing book", http.MethodPost, booksPath, `{"book_id": 999}`, http.StatusNotFound},
+		{"add to missing list", http.MethodPost, "/v1/lists/999/books", bookBody, http.StatusNotFound},
+		{"add bad json", http.MethodPost, booksPath, `{"book_id": "one"}`, http.StatusBadRequest},
+		{"remove book", http.MethodDelete, booksPath, bookBody, http.StatusOK},
+		{"remove book again", http.MethodDelete, booksPath, bookBody, http.StatusNotFound},
+		{"remove bad json", http.MethodDelete, booksPath, ``, http.StatusBadRequest},
+		{"delete", http.MethodDelete, listPath, "", http.StatusOK},
+		{"delete again", http.MethodDelete, listPath, "", http.StatusNotFound},
+		{"delete invalid id", http.MethodDelete, "/v1/lists/0", "", http.StatusNotFound},
+	}
+
+	// The cases run in order: later ones depend on the earlier ones.
+	for _, tt := range tests {
+		res := doIfMatch(t, app, tt.method, tt.path, tt.body, strings.TrimSuffix(tt.path, "/books"))
+		if res.status != tt.wantStatus {
+			t.Fatalf("%s: got status %d, want %d (body: %v)", tt.name, res.status, tt.wantStatus, res.body)
+		}
+	}
+}
+
+func TestCreateReadingListHandlerLocation(t *testing.T) {
+	app, _ := newTestApplication(t)
+
+	res := do(t, app, http.MethodPost, "/v1/lists", `{"name": "Winter", "status": "completed"}`)
+	assertStatus(t, res, http.StatusCreated)
+	if want := fmt.Sprintf("/v1/lists/%v", res.field("reading_list", "id")); res.header.Get("Location") != want {
+		t.Errorf("got Location %q, want %q", res.header.Get("Location"), want)
+	}
+}
+
+func TestReviewHandlers(t *testing.T) {
+	app, store := newTestApplication(t)
+	book := insertTestBook(t, store, "Dune", "Frank Herbert")
+	paperback := insertTestBook(t, store, "Dune", "Frank Herbert")
+	other := insertTestBook(t, store, "Neuromancer", "William Gibson")
+
+	// Make the second book another edition of the first one's work.
+	paperback.WorkID = book.WorkID
+	err := store.Books.Update(context.Background(), paperback)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	insertTestReview(t, store, book.ID, "alice", 5)
+	insertTestReview(t, store, paperback.ID, "bob", 3)
+	insertTestReview(t, store, other.ID, "carol", 4)
+
+	t.Run("list aggregates editions", func(t *testing.T) {
+		res := do(t, app, http.MethodGet, fmt.Sprintf("/v1/books/%d/reviews?sort=-rating", book.ID), "")
+		assertStatus(t, res, http.StatusOK)
+		reviews, _ := res.field("reviews").([]any)
+		if len(reviews) != 2 || reviews[0].(map[string]any)["author"] != "alice" {
+			t.Errorf("got reviews %v", reviews)
+		}
+	})
+
+	listTests := []struct {
+		name       string
+		path       string
+		wantStatus int
+	}{
+		{"rating filter", fmt.Sprintf("/v1/books/%d/reviews?rating=3", book.ID), http.StatusOK},
+		{"rating out of range", fmt.Sprintf("/v1/books/%d/reviews?rating=6", book.ID), http.StatusUnprocessableEntity},
+		{"rating zero", fmt.Sprintf("/v1/books/%d/reviews?rating=0", book.ID), http.StatusUnprocessableEntity},
+		{"bad sort", fmt.Sprintf("/v1/books/%d/reviews?sort=content", book.ID), http.StatusUnprocessableEntity},
+		{"missing book", "/v1/books/999/reviews", http.StatusNotFound},
+		{"invalid id", "/v1/books/abc/reviews", http.StatusNotFound},
+	}
+	for _, tt := range listTests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := do(t, app, http.MethodGet, tt.path, "")
+			assertStatus(t, res, tt.wantStatus)
+		})
+	}
+
+	t.Run("create", func(t *testing.T) {
+		res := do(t, app, http.MethodPost, fmt.Sprintf("/v1/books/%d/reviews", book.ID), `{"author": "dave", "content": "Great.", "rating": 4}`)
+		assertStatus(t, res, http.StatusCreated)
+		if want := fmt.Sprintf("/v1/reviews/%v", res.field("review", "id")); res.header.Get("Location") != want {
+			t.Errorf("got Location %q, want %q", res.header.Get("Location"), want)
+		}
+	})
+
+	createTests := []struct {
+		name       string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{"create invalid rating", fmt.Sprintf("/v1/books/%d/reviews", book.ID), `{"author": "dave", "content": "Meh.", "rating": 0}`, http.StatusUnprocessableEntity},
+		{"create bad json", fmt.Sprintf("/v1/books/%d/reviews", book.ID), `{"rating": "five"}`, http.StatusBadRequest},
+		{"create invalid id", "/v1/books/abc/reviews", `{}`, http.StatusNotFound},
+	}
+	for _, tt := range createTests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := do(t, app, http.MethodPost, tt.path, tt.body)
+			assertStatus(t, res, tt.wantStatus)
+		})
+	}
+
+	review := insertTestReview(t, store, other.ID, "erin", 2)
+	reviewPath := fmt.Sprintf("/v1/reviews/%d", review.ID)
+
+	updateTests := []struct {
+		name       string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{"update", reviewPath, `{"rating": 3}`, http.StatusOK},
+		{"update invalid", reviewPath, `{"content": ""}`, http.StatusUnprocessableEntity},
+		{"update bad json", reviewPath, `{"rating": 3`, http.StatusBadRequest},
+		{"update missing", "/v1/reviews/999", `{"rating": 3}`, http.StatusNotFound},
+		{"update invalid id", "/v1/reviews/abc", `{"rating": 3}`, http.StatusNotFound},
+	}
+	for _, tt := range updateTests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := doIfMatch(t, app, http.MethodPut, tt.path, tt.body, tt.path)
+			assertStatus(t, res, tt.wantStatus)
+		})
+	}
+
+	stored, err := store.Reviews.Get(context.Background(), review.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Rating != 3 || stored.Content != "Worth reading." {
+		t.Errorf("got review %+v after updates", stored)
+	}
+
+	t.Run("delete", func(t *testing.T) {
+		res := doIfMatch(t, app, http.MethodDelete, reviewPath, "", reviewPath)
+		assertStatus(t, res, http.StatusNoContent)
+
+		res = doIfMatch(t, app, http.MethodDelete, reviewPath, "", reviewPath)
+		assertStatus(t, res, http.StatusNotFound)
+
+		res = do(t, app, http.MethodDelete, "/v1/reviews/abc", "")
+		assertStatus(t, res, http.StatusNotFound)
+	})
+}
+
+func TestUserHandlers(t *testing.T) {
+	app, store := newTestApplication(t)
+	alice := insertTestUser(t, store, "alice")
+	bob := insertTestUser(t, store, "bob")
+	book := insertTestBook(t, store, "Dune", "Frank Herbert")
+	insertTestReadingList(t, store, "Summer", alice.ID)
+	insertTestReadingList(t, store, "Winter", alice.ID)
+	insertTestReadingList(t, store, "Bob's", bob.ID)
+	insertTestReview(t, store, book.ID, "alice", 5)
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantKey    string
+		wantCount  int
+	}{
+		{"profile", fmt.Sprintf("/v1/users/%d", alice.ID), http.StatusOK, "", 0},
+		{"missing profile", "/v1/users/999", http.StatusNotFound, "", 0},
+		{"invalid id", "/v1/users/abc", http.StatusNotFound, "", 0},
+		{"reading lists", fmt.Sprintf("/v1/users/%d/lists", alice.ID), http.StatusOK, "reading_lists", 2},
+		{"reading lists paged", fmt.Sprintf("/v1/users/%d/lists?page_size=1&sort=-name", alice.ID), http.StatusOK, "reading_lists", 1},
+		{"reading lists bad sort", fmt.Sprintf("/v1/users/%d/lists?sort=status", alice.ID), http.StatusUnprocessableEntity, "", 0},
+		{"reading lists invalid id", "/v1/users/abc/lists", http.StatusNotFound, "", 0},
+		{"reviews", fmt.Sprintf("/v1/users/%d/reviews", alice.ID), http.StatusOK, "reviews", 1},
+		{"reviews none", fmt.Sprintf("/v1/users/%d/reviews", bob.ID), http.StatusOK, "reviews", 0},
+		{"reviews bad page", fmt.Sprintf("/v1/users/%d/reviews?page=0", alice.ID), http.StatusUnprocessableEntity, "", 0},
+		{"reviews invalid id", "/v1/users/abc/reviews", http.StatusNotFound, "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := do(t, app, http.MethodGet, tt.path, "")
+			assertStatus(t, res, tt.wantStatus)
+			if tt.wantKey != "" {
+				items, _ := res.field(tt.wantKey).([]any)
+				if len(items) != tt.wantCount {
+					t.Errorf("got %d %s, want %d", len(items), tt.wantKey, tt.wantCount)
+				}
+			}
+		})
+	}
+
+	res := do(t, app, http.MethodGet, fmt.Sprintf("/v1/users/%d", alice.ID), "")
+	if res.field("user_profile", "username") != "alice" {
+		t.Errorf("got profile %v", res.field("user_profile"))
+	}
+}
+
+func TestReorderReadingListHandler(t *testing.T) {
+	app, store := newTestApplication(t)
+	user := insertTestUser(t, store, "alice")
+	list := insertTestReadingList(t, store, "Summer", user.ID)
+	booksPath := fmt.Sprintf("/v1/lists/%d/books", list.ID)
+
+	var ids []int
+	for _, title := range []string{"Dune", "Emma", "Ulysses"} {
+		book := insertTestBook(t, store, title, "Someone")
+		err := store.ReadingLists.AddBook(context.Background(), list.ID, book.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, book.ID)
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{"missing book", booksPath, fmt.Sprintf(`{"book_ids": [%d, %d]}`, ids[2], ids[0]), http.StatusUnprocessableEntity},
+		{"duplicate book", booksPath, fmt.Sprintf(`{"book_ids": [%d, %d, %d]}`, ids[2], ids[0], ids[0]), http.StatusUnprocessableEntity},
+		{"no book ids", booksPath, `{}`, http.StatusUnprocessableEntity},
+		{"bad json", booksPath, `{"book_ids": "1,2"}`, http.StatusBadRequest},
+		{"missing list", "/v1/lists/999/books", `{"book_ids": []}`, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := doIfMatch(t, app, http.MethodPut, tt.path, tt.body, strings.TrimSuffix(tt.path, "/books"))
+			assertStatus(t, res, tt.wantStatus)
+		})
+	}
+
+	res := doIfMatch(t, app, http.MethodPut, booksPath, fmt.Sprintf(`{"book_ids": [%d, %d, %d]}`, ids[2], ids[0], ids[1]), strings.TrimSuffix(booksPath, "/books"))
+	assertStatus(t, res, http.StatusOK)
+	want := fmt.Sprint([]any{float64(ids[2]), float64(ids[0]), float64(ids[1])})
+	if got := fmt.Sprint(res.field("reading_list", "books")); got != want {
+		t.Errorf("got books %s, want %s", got, want)
+	}
+
+	res = do(t, app, http.MethodGet, fmt.Sprintf("/v1/lists/%d", list.ID), "")
+	if got := fmt.Sprint(res.field("reading_list", "books")); got != want {
+		t.Errorf("got books %s after reordering, want %s", got, want)
+	}
+}
+
+func TestReviewsUpdateRating(t *testing.T) {
+	app, store := newTestApplication(t)
+	book := insertTestBook(t, store, "Dune", "Frank Herbert")
+	paperback := insertTestBook(t, store, "Dune", "Frank Herbert")
+	paperback.WorkID = book.WorkID
+	err := store.Books.Update(context.Background(), paperback)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertRating := func(t *testing.T, want float64) {
+		t.Helper()
+		for _, id := range []int{book.ID, paperback.ID} {
+			stored, err := store.Books.Get(context.Background(), id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.AverageRating != want {
+				t.Errorf("book %d: got average rating %v, want %v", id, stored.AverageRating, want)
+			}
+		}
+	}
+
+	res := do(t, app, http.MethodPost, fmt.Sprintf("/v1/books/%d/reviews", book.ID), `{"author": "alice", "content": "Great.", "rating": 5}`)
+	assertStatus(t, res, http.StatusCreated)
+	res = do(t, app, http.MethodPost, fmt.Sprintf("/v1/books/%d/reviews", paperback.ID), `{"author": "bob", "content": "Fine.", "rating": 2}`)
+	assertStatus(t, res, http.StatusCreated)
+	assertRating(t, 3.5)
+
+	reviewPath := fmt.Sprintf("/v1/reviews/%v", res.field("review", "id"))
+	res = doIfMatch(t, app, http.MethodPut, reviewPath, `{"rating": 4}`, reviewPath)
+	assertStatus(t, res, http.StatusOK)
+	assertRating(t, 4.5)
+
+	res = doIfMatch(t, app, http.MethodDelete, reviewPath, "", reviewPath)
+	assertStatus(t, res, http.StatusNoContent)
+	assertRating(t, 5)
+
+	res = do(t, app, http.MethodPost, "/v1/books/999/reviews", `{"author": "alice", "content": "Great.", "rating": 5}`)
+	assertStatus(t, res, http.StatusNotFound)
+}
+
+func TestDeleteUserHandler(t *testing.T) {
+	app, store := newTestApplication(t)
+	alice := insertTestUser(t, store, "alice")
+	bob := insertTestUser(t, store, "bob")
+	book := insertTestBook(t, store, "Dune", "Frank Herbert")
+	insertTestReadingList(t, store, "Summer", alice.ID)
+	insertTestReview(t, store, book.ID, "alice", 1)
+	insertTestReview(t, store, book.ID, "bob", 5)
+	err := store.Books.UpdateRating(context.Background(), book.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := fmt.Sprintf("/v1/users/%d", alice.ID)
+	res := doIfMatch(t, app, http.MethodDelete, path, "", path)
+	assertStatus(t, res, http.StatusOK)
+
+	res = do(t, app, http.MethodGet, fmt.Sprintf("/v1/users/%d", alice.ID), "")
+	assertStatus(t, res, http.StatusNotFound)
+
+	res = do(t, app, http.MethodGet, fmt.Sprintf("/v1/users/%d/lists", alice.ID), "")
+	if lists, _ := res.field("reading_lists").([]any); len(lists) != 0 {
+		t.Errorf("got reading lists %v after deleting their owner", lists)
+	}
+
+	stored, err := store.Books.Get(context.Background(), book.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.AverageRating != 5 {
+		t.Errorf("got average rating %v, want 5", stored.AverageRating)
+	}
+
+	res = do(t, app, http.MethodDelete, fmt.Sprintf("/v1/users/%d", alice.ID), "")
+	assertStatus(t, res, http.StatusNotFound)
+	res = do(t, app, http.MethodDelete, "/v1/users/abc", "")
+	assertStatus(t, res, http.StatusNotFound)
+
+	res = do(t, app, http.MethodGet, fmt.Sprintf("/v1/users/%d", bob.ID), "")
+	assertStatus(t, res, http.StatusOK)
+}
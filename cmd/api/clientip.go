@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// parseTrustedProxies parses the -trusted-proxies values, each a
+// comma-separated list of CIDR ranges or single addresses.
+func parseTrustedProxies(values []string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, value := range values {
+		for _, field := range strings.Split(value, ",") {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+
+			if strings.Contains(field, "/") {
+				prefix, err := netip.ParsePrefix(field)
+				if err != nil {
+					return nil, fmt.Errorf("invalid trusted proxy %q: %w", field, err)
+				}
+				prefixes = append(prefixes, prefix.Masked())
+				continue
+			}
+
+			addr, err := netip.ParseAddr(field)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", field, err)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+		}
+	}
+	return prefixes, nil
+}
+
+// Middleware: Client IP
+//
+// resolveClientIP stores the IP address of the client in the request context.
+// It is the address of the peer unless the peer is a trusted proxy, in which
+// case the Forwarded header, or X-Forwarded-For without one, is read from the
+// right, skipping the trusted proxies, until the first address that isn't
+// trusted. Headers sent through untrusted peers are ignored, as the client
+// could have forged them.
+func (a *applicationDependencies) resolveClientIP(next http.Handler) http.Handler {
+	// The proxies were checked when the configuration was loaded
+	trusted, _ := parseTrustedProxies(a.config.trustedProxies)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, withClientIP(r, clientIP(r, trusted)))
+	})
+}
+
+// clientIP resolves the IP address of the client that sent r.
+func clientIP(r *http.Request, trusted []netip.Prefix) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	peer, err := netip.ParseAddr(host)
+	if err != nil || !isTrusted(peer, trusted) {
+		return host
+	}
+
+	var hops []string
+	if values := r.Header.Values("Forwarded"); len(values) > 0 {
+		hops = forwardedFor(values)
+	} else {
+		for _, value := range r.Header.Values("X-Forwarded-For") {
+			hops = append(hops, strings.Split(value, ",")...)
+		}
+	}
+
+	client := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, ok := parseHop(hops[i])
+		if !ok {
+			// An unknown or obfuscated hop hides the addresses before it
+			break
+		}
+		client = addr
+		if !isTrusted(addr, trusted) {
+			break
+		}
+	}
+	return client.String()
+}
+
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+	addr = addr.Unmap()
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedFor returns the for= parameters of the elements of the Forwarded
+// header values (RFC 7239), in order.
+func forwardedFor(values []string) []string {
+	var hops []string
+	for _, value := range values {
+		for _, element := range splitQuoted(value, ',') {
+			for _, pair := range splitQuoted(element, ';') {
+				name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && strings.EqualFold(name, "for") {
+					hops = append(hops, value)
+				}
+			}
+		}
+	}
+	return hops
+}
+
+// splitQuoted splits s at each sep that isn't inside a quoted string.
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	quoted := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quoted:
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// parseHop parses an address of X-Forwarded-For, or a Forwarded for=
+// parameter, which may be quoted, carry a port, and put an IPv6 address in
+// brackets.
+func parseHop(hop string) (netip.Addr, bool) {
+	hop = strings.Trim(strings.TrimSpace(hop), `"`)
+	if host, _, err := net.SplitHostPort(hop); err == nil {
+		hop = host
+	}
+	hop = strings.TrimSuffix(strings.TrimPrefix(hop, "["), "]")
+
+	addr, err := netip.ParseAddr(hop)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
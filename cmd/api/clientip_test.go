@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted, err := parseTrustedProxies([]string{"10.0.0.0/8, 2001:db8::/32", "192.0.2.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		header     http.Header
+		want       string
+	}{
+		{"untrusted peer", "203.0.113.9:1234", http.Header{"X-Forwarded-For": {"198.51.100.7"}}, "203.0.113.9"},
+		{"no header", "10.0.0.1:1234", nil, "10.0.0.1"},
+		{"x-forwarded-for", "10.0.0.1:1234", http.Header{"X-Forwarded-For": {"198.51.100.7"}}, "198.51.100.7"},
+		{"proxy chain", "10.0.0.1:1234", http.Header{"X-Forwarded-For": {"203.0.113.66, 198.51.100.7, 10.1.2.3"}}, "198.51.100.7"},
+		{"repeated headers", "10.0.0.1:1234", http.Header{"X-Forwarded-For": {"198.51.100.7", "10.1.2.3"}}, "198.51.100.7"},
+		{"only proxies", "10.0.0.1:1234", http.Header{"X-Forwarded-For": {"10.4.4.4, 10.1.2.3"}}, "10.4.4.4"},
+		{"garbage", "10.0.0.1:1234", http.Header{"X-Forwarded-For": {"nonsense, 10.1.2.3"}}, "10.1.2.3"},
+		{"forwarded", "10.0.0.1:1234", http.Header{"Forwarded": {`for=198.51.100.7;proto=https, for=10.1.2.3`}}, "198.51.100.7"},
+		{"forwarded ipv6", "[2001:db8::1]:1234", http.Header{"Forwarded": {`For="[2001:db8:cafe::17]:4711"`}}, "2001:db8:cafe::17"},
+		{"forwarded over x-forwarded-for", "10.0.0.1:1234", http.Header{"Forwarded": {"for=198.51.100.7"}, "X-Forwarded-For": {"203.0.113.66"}}, "198.51.100.7"},
+		{"forwarded unknown", "10.0.0.1:1234", http.Header{"Forwarded": {"for=unknown"}}, "10.0.0.1"},
+		{"single address", "192.0.2.1:1234", http.Header{"X-Forwarded-For": {"198.51.100.7"}}, "198.51.100.7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := newRecordedRequest(http.MethodGet, "/v1/books", "")
+			r.RemoteAddr = tt.remoteAddr
+			for name, values := range tt.header {
+				r.Header[name] = values
+			}
+			if got := clientIP(r, trusted); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := parseTrustedProxies([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("parseTrustedProxies accepted an invalid range")
+	}
+}
+
+func TestRateLimitBehindProxy(t *testing.T) {
+	app := newLimitedTestApplication(t)
+	app.config.trustedProxies = []string{"192.0.2.0/24"}
+	handler := app.routes()
+
+	// Each client gets its own bucket although they share the proxy
+	for _, client := range []string{"198.51.100.7", "198.51.100.8"} {
+		r, w := newRecordedRequest(http.MethodGet, "/v1/genres", "")
+		r.Header.Set("X-Forwarded-For", client)
+		handler.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Errorf("client %s: got status %d, want %d", client, w.Code, http.StatusOK)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// entityTag returns the strong entity tag of a JSON body, a hash of its
+// bytes.
+func entityTag(js []byte) string {
+	sum := sha256.Sum256(js)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// errPreconditionFailed is returned by ifMatch when the resource has changed.
+var errPreconditionFailed = errors.New("precondition failed")
+
+// writeTaggedJSON writes data like writeJSON, with its entity tag in the ETag
+// header and modified, unless it is zero, in Last-Modified. GET and HEAD
+// requests are answered 304 Not Modified instead when the client's copy is
+// current according to If-None-Match.
+//
+// Last-Modified is informational only. The updated_at columns it comes from
+// aren't bumped when an author, genre, series or tag of the resource changes,
+// and only count whole seconds, so If-Modified-Since is ignored and caches
+// are told to revalidate with the entity tag instead.
+func (a *applicationDependencies) writeTaggedJSON(w http.ResponseWriter, r *http.Request, status int, data any, headers http.Header, modified time.Time) error {
+	js, err := encodeJSON(data)
+	if err != nil {
+		return err
+	}
+
+	etag := entityTag(js)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if !modified.IsZero() {
+		w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+
+	if status == http.StatusOK && (r.Method == http.MethodGet || r.Method == http.MethodHead) && notModified(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+	return writeEncodedJSON(w, status, js, headers)
+}
+
+// notModified reports whether the client's copy of the representation with
+// etag is current.
+func notModified(r *http.Request, etag string) bool {
+	// If-None-Match uses the weak comparison
+	return matchesETag(r.Header.Values("If-None-Match"), etag, false)
+}
+
+// matchesETag reports whether one of the entity tags listed in the header
+// values matches etag. The strong comparison doesn't match weak tags.
+func matchesETag(values []string, etag string, strong bool) bool {
+	for _, value := range values {
+		for _, candidate := range strings.Split(value, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" {
+				return true
+			}
+			if weak, ok := strings.CutPrefix(candidate, "W/"); ok {
+				if strong {
+					continue
+				}
+				candidate = weak
+			}
+			if candidate == etag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkIfMatch reports whether the request may change the resource whose
+// current representation is current. Requests without an If-Match header are
+// answered 428 Precondition Required, so that clients can't overwrite
+// changes they haven't seen, and those whose tag doesn't match 412
+// Precondition Failed.
+//
+// The check only lets a handler answer early. Another request may change the
+// resource before this one does, so the change itself is made with WithTx,
+// re-reading the resource and checking it with ifMatch in the same
+// transaction.
+func (a *applicationDependencies) checkIfMatch(w http.ResponseWriter, r *http.Request, current any) bool {
+	if len(r.Header.Values("If-Match")) == 0 {
+		a.preconditionRequiredResponse(w, r)
+		return false
+	}
+
+	err := ifMatch(r, current)
+	switch {
+	case errors.Is(err, errPreconditionFailed):
+		a.preconditionFailedResponse(w, r)
+		return false
+	case err != nil:
+		a.serverErrorResponse(w, r, err)
+		return false
+	}
+	return true
+}
+
+// ifMatch returns errPreconditionFailed unless an entity tag of the request's
+// If-Match header matches current, the representation of the resource read
+// in the transaction changing it. Serializable transactions make the check
+// and the change atomic: of two requests changing the resource with the same
+// tag, one is retried and then fails the check.
+func ifMatch(r *http.Request, current any) error {
+	js, err := encodeJSON(current)
+	if err != nil {
+		return err
+	}
+	if !matchesETag(r.Header.Values("If-Match"), entityTag(js), true) {
+		return errPreconditionFailed
+	}
+	return nil
+}
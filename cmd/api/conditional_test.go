@@ -0,0 +1,278 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/RayMC17/bookclub-api/internal/data"
+)
+
+func TestMatchesETag(t *testing.T) {
+	tests := []struct {
+		header string
+		strong bool
+		want   bool
+	}{
+		{`"abc"`, true, true},
+		{`"xyz", "abc"`, true, true},
+		{`"xyz"`, true, false},
+		{`*`, true, true},
+		{`W/"abc"`, true, false},
+		{`W/"abc"`, false, true},
+		{`"ab"`, false, false},
+	}
+	for _, tt := range tests {
+		if got := matchesETag([]string{tt.header}, `"abc"`, tt.strong); got != tt.want {
+			t.Errorf("matchesETag(%s, strong %t) = %t, want %t", tt.header, tt.strong, got, tt.want)
+		}
+	}
+}
+
+// doWithHeader sends a request through the application's routes with one
+// extra header.
+func doWithHeader(t *testing.T, app *applicationDependencies, method, path, body, key, value string) testResponse {
+	t.Helper()
+
+	r, w := newRecordedRequest(method, path, body)
+	r.Header.Set(key, value)
+	app.routes().ServeHTTP(w, r)
+	return decodeResponse(t, w)
+}
+
+func TestGetBookConditional(t *testing.T) {
+	app, store := newTestApplication(t)
+	fiction := insertTestGenre(t, store, "Fiction", nil)
+	book := insertTestBook(t, store, "Dune", "Frank Herbert", *fiction)
+	path := fmt.Sprintf("/v1/books/%d", book.ID)
+
+	res := do(t, app, http.MethodGet, path, "")
+	assertStatus(t, res, http.StatusOK)
+	etag := res.header.Get("ETag")
+	if etag == "" {
+		t.Fatal("missing ETag header")
+	}
+	lastModified, err := http.ParseTime(res.header.Get("Last-Modified"))
+	if err != nil {
+		t.Fatalf("got Last-Modified %q: %v", res.header.Get("Last-Modified"), err)
+	}
+	if !lastModified.Equal(book.UpdatedAt.Truncate(time.Second)) {
+		t.Errorf("got Last-Modified %v, want %v", lastModified, book.UpdatedAt)
+	}
+
+	res = doWithHeader(t, app, http.MethodGet, path, "", "If-None-Match", etag)
+	assertStatus(t, res, http.StatusNotModified)
+	if res.body != nil {
+		t.Errorf("got body %v with 304", res.body)
+	}
+	if res.header.Get("ETag") != etag {
+		t.Errorf("got ETag %q with 304, want %q", res.header.Get("ETag"), etag)
+	}
+
+	res = doWithHeader(t, app, http.MethodGet, path, "", "If-None-Match", "W/"+etag)
+	assertStatus(t, res, http.StatusNotModified)
+
+	// updated_at misses changes to the book's authors, genres, series and
+	// tags, so only the entity tag decides whether the client's copy is
+	// current
+	if got := res.header.Get("Cache-Control"); got != "no-cache" {
+		t.Errorf("got Cache-Control %q, want %q", got, "no-cache")
+	}
+	res = doWithHeader(t, app, http.MethodGet, path, "", "If-Modified-Since", lastModified.Add(time.Hour).Format(http.TimeFormat))
+	assertStatus(t, res, http.StatusOK)
+
+	res = doWithHeader(t, app, http.MethodPut, path, `{"title": "Dune Messiah"}`, "If-Match", etag)
+	assertStatus(t, res, http.StatusOK)
+	if res.header.Get("ETag") == etag {
+		t.Error("the ETag didn't change with the book")
+	}
+
+	res = doWithHeader(t, app, http.MethodGet, path, "", "If-None-Match", etag)
+	assertStatus(t, res, http.StatusOK)
+}
+
+func TestCollectionConditional(t *testing.T) {
+	app, store := newTestApplication(t)
+	insertTestBook(t, store, "Dune", "Frank Herbert")
+
+	for _, path := range []string{"/v1/books", "/v1/genres"} {
+		res := do(t, app, http.MethodGet, path, "")
+		assertStatus(t, res, http.StatusOK)
+		etag := res.header.Get("ETag")
+		if etag == "" {
+			t.Fatalf("%s: missing ETag header", path)
+		}
+
+		res = doWithHeader(t, app, http.MethodGet, path, "", "If-None-Match", etag)
+		if res.status != http.StatusNotModified {
+			t.Errorf("%s: got status %d, want %d", path, res.status, http.StatusNotModified)
+		}
+	}
+
+	res := do(t, app, http.MethodGet, "/v1/books", "")
+	etag := res.header.Get("ETag")
+	insertTestBook(t, store, "Emma", "Jane Austen")
+	res = doWithHeader(t, app, http.MethodGet, "/v1/books", "", "If-None-Match", etag)
+	assertStatus(t, res, http.StatusOK)
+}
+
+func TestIfMatchRequired(t *testing.T) {
+	app, store := newTestApplication(t)
+	user := insertTestUser(t, store, "alice")
+	fiction := insertTestGenre(t, store, "Fiction", nil)
+	book := insertTestBook(t, store, "Dune", "Frank Herbert", *fiction)
+	review := insertTestReview(t, store, book.ID, "alice", 4)
+	list := insertTestReadingList(t, store, "Summer", user.ID)
+	author := insertTestAuthor(t, store, "Brian Herbert")
+	series := insertTestSeries(t, store, "Dune Chronicles", book.WorkID)
+	err := store.Tags.Add(context.Background(), user.ID, book.ID, "classic")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bookPath := fmt.Sprintf("/v1/books/%d", book.ID)
+	reviewPath := fmt.Sprintf("/v1/reviews/%d", review.ID)
+	listPath := fmt.Sprintf("/v1/lists/%d", list.ID)
+	authorPath := fmt.Sprintf("/v1/authors/%d", author.ID)
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodPut, bookPath, `{"title": "Dune Messiah"}`},
+		{http.MethodDelete, bookPath, ""},
+		{http.MethodPut, reviewPath, `{"rating": 5}`},
+		{http.MethodDelete, reviewPath, ""},
+		{http.MethodPut, listPath, `{"name": "Winter"}`},
+		{http.MethodDelete, listPath, ""},
+		{http.MethodPut, listPath + "/books", `{"book_ids": []}`},
+		{http.MethodDelete, listPath + "/books", fmt.Sprintf(`{"book_id": %d}`, book.ID)},
+		{http.MethodPut, authorPath, `{"bio": "Wrote the prequels."}`},
+		{http.MethodDelete, authorPath, ""},
+		{http.MethodDelete, fmt.Sprintf("/v1/users/%d", user.ID), ""},
+		{http.MethodDelete, fmt.Sprintf("/v1/series/%d/entries", series.ID), fmt.Sprintf(`{"work_id": %d}`, book.WorkID)},
+		{http.MethodDelete, bookPath + "/tags", fmt.Sprintf(`{"user_id": %d, "tag": "classic"}`, user.ID)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			res := do(t, app, tt.method, tt.path, tt.body)
+			assertStatus(t, res, http.StatusPreconditionRequired)
+			if res.field("error") == nil {
+				t.Errorf("got body %v", res.body)
+			}
+
+			res = doWithHeader(t, app, tt.method, tt.path, tt.body, "If-Match", `"stale"`)
+			assertStatus(t, res, http.StatusPreconditionFailed)
+		})
+	}
+
+	// A weak tag never matches If-Match
+	etag := do(t, app, http.MethodGet, bookPath, "").header.Get("ETag")
+	res := doWithHeader(t, app, http.MethodPut, bookPath, `{"title": "Dune Messiah"}`, "If-Match", "W/"+etag)
+	assertStatus(t, res, http.StatusPreconditionFailed)
+
+	res = doWithHeader(t, app, http.MethodPut, bookPath, `{"title": "Dune Messiah"}`, "If-Match", "*")
+	assertStatus(t, res, http.StatusOK)
+
+	// The review's tag comes with it from GET /v1/reviews/:id
+	res = do(t, app, http.MethodGet, reviewPath, "")
+	assertStatus(t, res, http.StatusOK)
+	if res.header.Get("Last-Modified") == "" {
+		t.Error("missing Last-Modified header on the review")
+	}
+	res = doWithHeader(t, app, http.MethodPut, reviewPath, `{"rating": 5}`, "If-Match", res.header.Get("ETag"))
+	assertStatus(t, res, http.StatusOK)
+}
+
+// racingBooks changes the book it is asked for through store the second
+// time, as if another request had saved it between the handler's check of
+// If-Match and its transaction.
+type racingBooks struct {
+	data.Books
+	store *data.InMemory
+	gets  int
+}
+
+func (b *racingBooks) Get(ctx context.Context, id int) (*data.Book, error) {
+	b.gets++
+	if b.gets == 2 {
+		book, err := b.Books.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		book.Title = "Children of Dune"
+		err = b.store.Models().Books.Update(ctx, book)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return b.Books.Get(ctx, id)
+}
+
+// racingAuthors renames the author it is asked for through store the second
+// time, like racingBooks.
+type racingAuthors struct {
+	data.Authors
+	store *data.InMemory
+	gets  int
+}
+
+func (a *racingAuthors) Get(ctx context.Context, id int) (*data.Author, error) {
+	a.gets++
+	if a.gets == 2 {
+		author, err := a.Authors.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		author.Name = "Brian Patrick Herbert"
+		err = a.store.Models().Authors.Update(ctx, author)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return a.Authors.Get(ctx, id)
+}
+
+func TestIfMatchConcurrentChange(t *testing.T) {
+	app, store := newTestApplication(t)
+	fiction := insertTestGenre(t, store, "Fiction", nil)
+	book := insertTestBook(t, store, "Dune", "Frank Herbert", *fiction)
+	path := fmt.Sprintf("/v1/books/%d", book.ID)
+
+	etag := do(t, app, http.MethodGet, path, "").header.Get("ETag")
+	app.models.Books = &racingBooks{Books: app.models.Books, store: store}
+
+	res := doWithHeader(t, app, http.MethodPut, path, `{"title": "Dune Messiah"}`, "If-Match", etag)
+	assertStatus(t, res, http.StatusPreconditionFailed)
+
+	saved, err := store.Models().Books.Get(context.Background(), book.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Title != "Children of Dune" {
+		t.Errorf("got title %q, want the concurrent change kept", saved.Title)
+	}
+}
+
+func TestIfMatchConcurrentAuthorChange(t *testing.T) {
+	app, store := newTestApplication(t)
+	author := insertTestAuthor(t, store, "Brian Herbert")
+	path := fmt.Sprintf("/v1/authors/%d", author.ID)
+
+	etag := do(t, app, http.MethodGet, path, "").header.Get("ETag")
+	app.models.Authors = &racingAuthors{Authors: app.models.Authors, store: store}
+
+	res := doWithHeader(t, app, http.MethodDelete, path, "", "If-Match", etag)
+	assertStatus(t, res, http.StatusPreconditionFailed)
+
+	saved, err := store.Models().Authors.Get(context.Background(), author.ID)
+	if err != nil {
+		t.Fatalf("got %v, want the renamed author kept", err)
+	}
+	if saved.Name != "Brian Patrick Herbert" {
+		t.Errorf("got name %q, want the concurrent change kept", saved.Name)
+	}
+}
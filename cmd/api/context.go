@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+)
+
+// contextKey is the type of the keys this package stores in request contexts.
+type contextKey string
+
+const (
+	routeContextKey     = contextKey("route")
+	requestIDContextKey = contextKey("request_id")
+	userIDContextKey    = contextKey("user_id")
+	clientIPContextKey  = contextKey("client_ip")
+	versionContextKey   = contextKey("api_version")
+)
+
+// routeInfo is filled in by patternRouter with the route a request matched.
+type routeInfo struct {
+	pattern string
+}
+
+// withRouteInfo returns a copy of r whose context carries route.
+func withRouteInfo(r *http.Request, route *routeInfo) *http.Request {
+	ctx := context.WithValue(r.Context(), routeContextKey, route)
+	return r.WithContext(ctx)
+}
+
+// routeInfoFromContext returns the routeInfo of the request, or nil if the
+// request didn't go through recordMetrics.
+func routeInfoFromContext(r *http.Request) *routeInfo {
+	route, _ := r.Context().Value(routeContextKey).(*routeInfo)
+	return route
+}
+
+// withRequestID returns a copy of r whose context carries the request ID.
+func withRequestID(r *http.Request, id string) *http.Request {
+	ctx := context.WithValue(r.Context(), requestIDContextKey, id)
+	return r.WithContext(ctx)
+}
+
+// requestIDFromContext returns the ID assigned to the request by the
+// requestID middleware, or "" if there is none.
+func requestIDFromContext(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDContextKey).(string)
+	return id
+}
+
+// withUserID returns a copy of r whose context carries the ID of the
+// authenticated user.
+func withUserID(r *http.Request, id int64) *http.Request {
+	ctx := context.WithValue(r.Context(), userIDContextKey, id)
+	return r.WithContext(ctx)
+}
+
+// userIDFromContext returns the ID of the user the request was authenticated
+// as, if any.
+func userIDFromContext(r *http.Request) (int64, bool) {
+	id, ok := r.Context().Value(userIDContextKey).(int64)
+	return id, ok
+}
+
+// withClientIP returns a copy of r whose context carries the IP address of
+// the client.
+func withClientIP(r *http.Request, ip string) *http.Request {
+	ctx := context.WithValue(r.Context(), clientIPContextKey, ip)
+	return r.WithContext(ctx)
+}
+
+// clientIPFromContext returns the IP address of the client resolved by the
+// resolveClientIP middleware, or the address of the peer if the request
+// didn't go through it.
+func clientIPFromContext(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPContextKey).(string); ok {
+		return ip
+	}
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
+
+// withAPIVersion returns a copy of r whose context carries the API version
+// the request was routed to.
+func withAPIVersion(r *http.Request, version string) *http.Request {
+	ctx := context.WithValue(r.Context(), versionContextKey, version)
+	return r.WithContext(ctx)
+}
+
+// apiVersionFromContext returns the API version the request was routed to,
+// such as "v1", or "" if the route isn't versioned.
+func apiVersionFromContext(r *http.Request) string {
+	version, _ := r.Context().Value(versionContextKey).(string)
+	return version
+}
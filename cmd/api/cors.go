@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// corsAllowedHeaders are the request headers browsers may send cross-origin.
+var corsAllowedHeaders = []string{"Authorization", "Content-Type", "If-Match", "If-None-Match"}
+
+// corsExposedHeaders are the response headers, besides those every origin may
+// read, that the scripts of trusted origins may read.
+var corsExposedHeaders = []string{"ETag", "Location"}
+
+// validOrigin reports whether origin is a serialized origin, such as
+// "https://app.example.com", as browsers send in the Origin header.
+func validOrigin(origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" &&
+		u.User == nil && u.Path == "" && u.RawQuery == "" && u.Fragment == ""
+}
+
+// Middleware: CORS
+//
+// enableCORS lets the browsers on the -cors-trusted-origins read the
+// responses of the API. The preflight requests are answered by
+// preflightHandler, which the router calls with the methods of the path.
+func (a *applicationDependencies) enableCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Origin")
+
+		origin := r.Header.Get("Origin")
+		if origin != "" && slices.Contains(a.config.cors.trustedOrigins, origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Expose-Headers", strings.Join(corsExposedHeaders, ", "))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// preflightHandler answers the automatic OPTIONS requests of the router,
+// which sets the Allow header to the methods of the path beforehand. The
+// preflight requests of trusted origins are told the same methods.
+func (a *applicationDependencies) preflightHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Vary", "Access-Control-Request-Method")
+
+	if w.Header().Get("Access-Control-Allow-Origin") == "" || r.Header.Get("Access-Control-Request-Method") == "" {
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Methods", w.Header().Get("Allow"))
+	w.Header().Set("Access-Control-Allow-Headers", strings.Join(corsAllowedHeaders, ", "))
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestCORS(t *testing.T) {
+	app, _ := newTestApplication(t)
+	app.config.cors.trustedOrigins = []string{"https://app.example.com"}
+	handler := app.routes()
+
+	t.Run("trusted origin", func(t *testing.T) {
+		r, w := newRecordedRequest(http.MethodGet, "/v1/books", "")
+		r.Header.Set("Origin", "https://app.example.com")
+		handler.ServeHTTP(w, r)
+
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+			t.Errorf("got Access-Control-Allow-Origin %q, want the origin", got)
+		}
+		if got := w.Header().Get("Access-Control-Expose-Headers"); got != "ETag, Location" {
+			t.Errorf("got Access-Control-Expose-Headers %q", got)
+		}
+		if got := w.Header().Values("Vary"); len(got) == 0 || got[0] != "Origin" {
+			t.Errorf("got Vary %q, want Origin", got)
+		}
+	})
+
+	t.Run("untrusted origin", func(t *testing.T) {
+		r, w := newRecordedRequest(http.MethodGet, "/v1/books", "")
+		r.Header.Set("Origin", "https://evil.example.com")
+		handler.ServeHTTP(w, r)
+
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+			t.Errorf("got Access-Control-Allow-Origin %q for an untrusted origin", got)
+		}
+		if got := w.Header().Get("Vary"); got != "Origin" {
+			t.Errorf("got Vary %q, want Origin", got)
+		}
+	})
+
+	t.Run("preflight", func(t *testing.T) {
+		r, w := newRecordedRequest(http.MethodOptions, "/v1/books/7", "")
+		r.Header.Set("Origin", "https://app.example.com")
+		r.Header.Set("Access-Control-Request-Method", http.MethodPut)
+		handler.ServeHTTP(w, r)
+
+		if w.Code != http.StatusNoContent {
+			t.Errorf("got status %d, want %d", w.Code, http.StatusNoContent)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Methods"); got != "DELETE, GET, OPTIONS, PUT" {
+			t.Errorf("got Access-Control-Allow-Methods %q, want the methods of /v1/books/:id", got)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Headers"); got != "Authorization, Content-Type, If-Match, If-None-Match" {
+			t.Errorf("got Access-Control-Allow-Headers %q", got)
+		}
+	})
+
+	t.Run("preflight from untrusted origin", func(t *testing.T) {
+		r, w := newRecordedRequest(http.MethodOptions, "/v1/books/7", "")
+		r.Header.Set("Origin", "https://evil.example.com")
+		r.Header.Set("Access-Control-Request-Method", http.MethodPut)
+		handler.ServeHTTP(w, r)
+
+		if got := w.Header().Get("Access-Control-Allow-Methods"); got != "" {
+			t.Errorf("got Access-Control-Allow-Methods %q for an untrusted origin", got)
+		}
+	})
+}
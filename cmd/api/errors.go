@@ -1,64 +1,188 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/RayMC17/bookclub-api/internal/data"
+	"github.com/RayMC17/bookclub-api/internal/trace"
 )
 
 var (
 	ErrNoRecord = errors.New("record not found")
 )
 
+// requestLogger returns the logger with the request and trace IDs of r
+// attached, so the log lines of one request can be correlated.
+func (a *applicationDependencies) requestLogger(r *http.Request) *slog.Logger {
+	logger := a.logger
+	if id := requestIDFromContext(r); id != "" {
+		logger = logger.With("request_id", id)
+	}
+	if span := trace.SpanFromContext(r.Context()); span.IsRecording() {
+		logger = logger.With("trace_id", span.SpanContext().TraceID.String())
+	}
+	return logger
+}
+
 // logError is a helper function for logging errors.
 func (a *applicationDependencies) logError(r *http.Request, err error) {
-	a.logger.Error(err.Error(), "method", r.Method, "url", r.URL.String())
+	a.requestLogger(r).Error(err.Error(), "method", r.Method, "url", r.URL.String())
+}
+
+// errorEnvelope and problem are the two forms of error responses, chosen
+// by errorResponseJSON from the Accept header.
+type errorEnvelope struct {
+	// Error is a message, or the messages of the invalid fields.
+	Error any `json:"error"`
+}
+
+type problem struct {
+	Type      string            `json:"type"`
+	Title     string            `json:"title"`
+	Status    int               `json:"status"`
+	Detail    string            `json:"detail,omitempty"`
+	Instance  string            `json:"instance"`
+	Code      string            `json:"code"`
+	Errors    map[string]string `json:"errors,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
+}
+
+// problemJSON is the media type of RFC 7807 problem details.
+const problemJSON = "application/problem+json"
+
+// wantsProblem reports whether the client accepts problem details, rather
+// than the {"error": ...} envelope older clients expect.
+func wantsProblem(r *http.Request) bool {
+	for _, accept := range r.Header.Values("Accept") {
+		for _, mediaRange := range strings.Split(accept, ",") {
+			mediaType, params, _ := strings.Cut(mediaRange, ";")
+			if !strings.EqualFold(strings.TrimSpace(mediaType), problemJSON) {
+				continue
+			}
+			for _, param := range strings.Split(params, ";") {
+				name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if strings.EqualFold(name, "q") {
+					if q, err := strconv.ParseFloat(value, 64); err == nil && q == 0 {
+						return false
+					}
+				}
+			}
+			return true
+		}
+	}
+	return false
 }
 
-// errorResponseJSON sends a JSON-formatted error message with the specified status code.
-func (a *applicationDependencies) errorResponseJSON(w http.ResponseWriter, r *http.Request, status int, message interface{}) {
-	// Create an envelope containing the error message.
-	errorData := envelope{"error": message}
+// errorResponseJSON sends an error with the specified status code and
+// machine-readable code, as problem details (RFC 7807) when the client
+// accepts them and in the {"error": message} envelope otherwise.
+func (a *applicationDependencies) errorResponseJSON(w http.ResponseWriter, r *http.Request, status int, code string, message interface{}) {
+	w.Header().Add("Vary", "Accept")
 
-	// Write the JSON response.
-	err := a.writeJSON(w, status, errorData, nil)
+	var err error
+	if wantsProblem(r) {
+		problem := problem{
+			Type:      "about:blank",
+			Title:     http.StatusText(status),
+			Status:    status,
+			Instance:  r.URL.Path,
+			Code:      code,
+			RequestID: requestIDFromContext(r),
+		}
+		switch message := message.(type) {
+		case string:
+			problem.Detail = message
+		case map[string]string:
+			problem.Detail = "the request contains invalid fields"
+			problem.Errors = message
+		}
+		err = a.writeJSON(w, status, problem, http.Header{"Content-Type": {problemJSON}})
+	} else {
+		err = a.writeJSON(w, status, errorEnvelope{Error: message}, nil)
+	}
 	if err != nil {
 		a.logError(r, err)
 		w.WriteHeader(http.StatusInternalServerError)
 	}
 }
 
-// serverErrorResponse sends a 500 Internal Server Error response.
+// serverErrorResponse sends a 500 Internal Server Error response. Database
+// operations that were cancelled are reported as timeouts instead, or not at
+// all when the client has already gone away.
 func (a *applicationDependencies) serverErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	if data.IsCanceled(err) {
+		if errors.Is(r.Context().Err(), context.Canceled) {
+			a.requestLogger(r).Info("client disconnected", "method", r.Method, "url", r.URL.String())
+			return
+		}
+		a.timeoutResponse(w, r, err)
+		return
+	}
+
 	a.logError(r, err)
 	message := "the server encountered a problem and could not process your request"
-	a.errorResponseJSON(w, r, http.StatusInternalServerError, message)
+	a.errorResponseJSON(w, r, http.StatusInternalServerError, "server_error", message)
+}
+
+// timeoutResponse sends a 503 Service Unavailable response when a database
+// operation ran past its deadline.
+func (a *applicationDependencies) timeoutResponse(w http.ResponseWriter, r *http.Request, err error) {
+	a.logError(r, err)
+	w.Header().Set("Retry-After", "1")
+	message := "the server took too long to process your request, please try again later"
+	a.errorResponseJSON(w, r, http.StatusServiceUnavailable, "timeout", message)
 }
 
 // notFoundResponse sends a 404 Not Found response.
 func (a *applicationDependencies) notFoundResponse(w http.ResponseWriter, r *http.Request) {
 	message := "the requested resource could not be found"
-	a.errorResponseJSON(w, r, http.StatusNotFound, message)
+	a.errorResponseJSON(w, r, http.StatusNotFound, "not_found", message)
 }
 
 // methodNotAllowedResponse sends a 405 Method Not Allowed response.
 func (a *applicationDependencies) methodNotAllowedResponse(w http.ResponseWriter, r *http.Request) {
 	message := fmt.Sprintf("the %s method is not supported for this resource", r.Method)
-	a.errorResponseJSON(w, r, http.StatusMethodNotAllowed, message)
+	a.errorResponseJSON(w, r, http.StatusMethodNotAllowed, "method_not_allowed", message)
 }
 
 // badRequestResponse sends a 400 Bad Request response.
 func (a *applicationDependencies) badRequestResponse(w http.ResponseWriter, r *http.Request, err error) {
-	a.errorResponseJSON(w, r, http.StatusBadRequest, err.Error())
+	a.errorResponseJSON(w, r, http.StatusBadRequest, "bad_request", err.Error())
 }
 
 // failedValidationResponse sends a 422 Unprocessable Entity response with validation errors.
 func (a *applicationDependencies) failedValidationResponse(w http.ResponseWriter, r *http.Request, errors map[string]string) {
-	a.errorResponseJSON(w, r, http.StatusUnprocessableEntity, errors)
+	a.errorResponseJSON(w, r, http.StatusUnprocessableEntity, "failed_validation", errors)
+}
+
+// conflictResponse sends a 409 Conflict response when the request conflicts
+// with the current state of the resource.
+func (a *applicationDependencies) conflictResponse(w http.ResponseWriter, r *http.Request, message string) {
+	a.errorResponseJSON(w, r, http.StatusConflict, "conflict", message)
 }
 
 // rateLimitExceededResponse sends a 429 Too Many Requests response when rate limit is exceeded.
 func (a *applicationDependencies) rateLimitExceededResponse(w http.ResponseWriter, r *http.Request) {
 	message := "rate limit exceeded"
-	a.errorResponseJSON(w, r, http.StatusTooManyRequests, message)
+	a.errorResponseJSON(w, r, http.StatusTooManyRequests, "rate_limit_exceeded", message)
+}
+
+// preconditionFailedResponse sends a 412 Precondition Failed response when
+// the resource changed since the client read it.
+func (a *applicationDependencies) preconditionFailedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the resource has changed since you last read it, please fetch it again"
+	a.errorResponseJSON(w, r, http.StatusPreconditionFailed, "precondition_failed", message)
+}
+
+// preconditionRequiredResponse sends a 428 Precondition Required response
+// when a change is requested without an If-Match header.
+func (a *applicationDependencies) preconditionRequiredResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the If-Match header must be set to the ETag of the resource"
+	a.errorResponseJSON(w, r, http.StatusPreconditionRequired, "precondition_required", message)
 }
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestErrorResponses(t *testing.T) {
+	app, _ := newTestApplication(t)
+	handler := app.routes()
+
+	t.Run("not found", func(t *testing.T) {
+		res := serve(t, handler, http.MethodGet, "/nowhere", "")
+		assertStatus(t, res, http.StatusNotFound)
+		if got := res.header.Get("Content-Type"); got != "application/json" {
+			t.Errorf("got Content-Type %q, want application/json", got)
+		}
+		if res.field("error") != "the requested resource could not be found" {
+			t.Errorf("got body %v, want the error envelope", res.body)
+		}
+	})
+
+	t.Run("method not allowed", func(t *testing.T) {
+		res := serve(t, handler, http.MethodPatch, "/v1/books", "")
+		assertStatus(t, res, http.StatusMethodNotAllowed)
+		if got := res.header.Get("Allow"); got != "GET, OPTIONS, POST" {
+			t.Errorf("got Allow %q, want GET, OPTIONS, POST", got)
+		}
+		if res.field("error") != "the PATCH method is not supported for this resource" {
+			t.Errorf("got body %v, want the error envelope", res.body)
+		}
+	})
+
+	t.Run("problem details", func(t *testing.T) {
+		r, w := newRecordedRequest(http.MethodGet, "/nowhere", "")
+		r.Header.Set("Accept", "application/problem+json, application/json;q=0.9")
+		handler.ServeHTTP(w, r)
+		res := decodeResponse(t, w)
+
+		assertStatus(t, res, http.StatusNotFound)
+		if got := res.header.Get("Content-Type"); got != problemJSON {
+			t.Errorf("got Content-Type %q, want %s", got, problemJSON)
+		}
+		want := map[string]any{
+			"type":     "about:blank",
+			"title":    "Not Found",
+			"status":   float64(http.StatusNotFound),
+			"detail":   "the requested resource could not be found",
+			"instance": "/nowhere",
+			"code":     "not_found",
+		}
+		for key, value := range want {
+			if got := res.field(key); got != value {
+				t.Errorf("got %s %v, want %v", key, got, value)
+			}
+		}
+		if res.field("request_id") == nil {
+			t.Error("got no request_id")
+		}
+	})
+
+	t.Run("validation problem", func(t *testing.T) {
+		r, w := newRecordedRequest(http.MethodPost, "/v1/books", `{}`)
+		r.Header.Set("Accept", problemJSON)
+		handler.ServeHTTP(w, r)
+		res := decodeResponse(t, w)
+
+		assertStatus(t, res, http.StatusUnprocessableEntity)
+		if res.field("code") != "failed_validation" || res.field("errors", "title") == nil {
+			t.Errorf("got body %v, want the invalid fields", res.body)
+		}
+	})
+}
+
+func TestWantsProblem(t *testing.T) {
+	tests := map[string]bool{
+		"":                                    false,
+		"application/json":                    false,
+		"*/*":                                 false,
+		"application/problem+json":            true,
+		"text/html, Application/Problem+JSON": true,
+		"application/problem+json; q=0.5":     true,
+		"application/problem+json;q=0, */*;q=0.1": false,
+	}
+	for accept, want := range tests {
+		r, _ := newRecordedRequest(http.MethodGet, "/", "")
+		r.Header.Set("Accept", accept)
+		if got := wantsProblem(r); got != want {
+			t.Errorf("wantsProblem(%q) = %v, want %v", accept, got, want)
+		}
+	}
+}
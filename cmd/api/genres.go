@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/RayMC17/bookclub-api/internal/data"
+	"github.com/RayMC17/bookclub-api/internal/validator"
+)
+
+// listGenresHandler returns the whole genre taxonomy as a tree.
+func (a *applicationDependencies) listGenresHandler(w http.ResponseWriter, r *http.Request) {
+	genres, err := a.models.Genres.GetAll(r.Context())
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = a.writeTaggedJSON(w, r, http.StatusOK, envelope{"genres": data.BuildGenreTree(genres)}, nil, time.Time{})
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+// createGenreInput is the body of POST /v1/genres.
+type createGenreInput struct {
+	Name     string   `json:"name"`
+	ParentID *int     `json:"parent_id"`
+	Aliases  []string `json:"aliases"`
+}
+
+func (a *applicationDependencies) createGenreHandler(w http.ResponseWriter, r *http.Request) {
+	var input createGenreInput
+	err := a.readJSON(w, r, &input)
+	if err != nil {
+		a.badRequestResponse(w, r, err)
+		return
+	}
+
+	genre := &data.Genre{
+		Name:     input.Name,
+		ParentID: input.ParentID,
+	}
+
+	v := validator.New()
+	data.ValidateGenre(v, genre, input.Aliases)
+	if !v.Valid() {
+		a.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = a.models.Genres.Insert(r.Context(), genre, input.Aliases)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateGenre):
+			v.AddError("name", "a genre with this name or alias already exists")
+			a.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrUnknownGenre):
+			v.AddError("parent_id", "must reference an existing genre")
+			a.failedValidationResponse(w, r, v.Errors)
+		default:
+			a.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = a.writeJSON(w, http.StatusCreated, envelope{"genre": genre}, nil)
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+// resolveGenres turns the genre IDs and the legacy free-text genre of a book
+// request into genres. Unknown legacy genres are reported on v.
+func (a *applicationDependencies) resolveGenres(ctx context.Context, v *validator.Validator, legacyGenre string, genreIDs []int) ([]data.Genre, error) {
+	genres := []data.Genre{}
+	for _, id := range genreIDs {
+		v.Check(id > 0, "genre_ids", "must contain valid IDs")
+		genres = append(genres, data.Genre{ID: id})
+	}
+
+	if legacyGenre != "" {
+		genre, err := a.models.Genres.Resolve(ctx, legacyGenre)
+		switch {
+		case errors.Is(err, data.ErrUnknownGenre):
+			v.AddError("genre", "must be a known genre or genre alias")
+		case err != nil:
+			return nil, err
+		default:
+			genres = append(genres, *genre)
+		}
+	}
+
+	return genres, nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestCreateGenreHandler(t *testing.T) {
+	app, store := newTestApplication(t)
+	fiction := insertTestGenre(t, store, "Fiction", nil)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantError  string
+	}{
+		{"valid", fmt.Sprintf(`{"name": "Science Fiction", "parent_id": %d, "aliases": ["sci-fi", "SF"]}`, fiction.ID), http.StatusCreated, ""},
+		{"missing name", `{"aliases": ["nameless"]}`, http.StatusUnprocessableEntity, "name"},
+		{"duplicate name", `{"name": "fiction"}`, http.StatusUnprocessableEntity, "name"},
+		{"duplicate alias", `{"name": "Speculative Fiction", "aliases": ["Sci-Fi"]}`, http.StatusUnprocessableEntity, "name"},
+		{"unknown parent", `{"name": "Fantasy", "parent_id": 999}`, http.StatusUnprocessableEntity, "parent_id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := do(t, app, http.MethodPost, "/v1/genres", tt.body)
+			assertStatus(t, res, tt.wantStatus)
+
+			if tt.wantError != "" && res.field("error", tt.wantError) == nil {
+				t.Errorf("want a validation error for %q, got %v", tt.wantError, res.body)
+			}
+		})
+	}
+
+	// Aliases resolve the legacy free-text genre of a book
+	body := `{"title": "Dune", "authors": ["Frank Herbert"], "isbn": "9780441172719", "genre": "sci-fi"}`
+	res := do(t, app, http.MethodPost, "/v1/books", body)
+	assertStatus(t, res, http.StatusCreated)
+	if got := fmt.Sprint(res.field("book", "genres")); got == "[]" {
+		t.Errorf("got no genres, want Science Fiction")
+	}
+}
+
+func TestListGenresHandler(t *testing.T) {
+	app, store := newTestApplication(t)
+	fiction := insertTestGenre(t, store, "Fiction", nil)
+	insertTestGenre(t, store, "Science Fiction", &fiction.ID)
+	insertTestGenre(t, store, "History", nil)
+
+	res := do(t, app, http.MethodGet, "/v1/genres", "")
+	assertStatus(t, res, http.StatusOK)
+
+	roots, _ := res.field("genres").([]any)
+	if len(roots) != 2 {
+		t.Fatalf("got %d top-level genres, want 2", len(roots))
+	}
+	first := roots[0].(map[string]any)
+	if first["name"] != "Fiction" {
+		t.Errorf("got %v first, want Fiction", first["name"])
+	}
+	if children, _ := first["children"].([]any); len(children) != 1 {
+		t.Errorf("got %d children of Fiction, want 1", len(children))
+	}
+}
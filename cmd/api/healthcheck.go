@@ -1,9 +1,210 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
 	"net/http"
+	"sync"
+	"time"
+
+	"github.com/RayMC17/bookclub-api/internal/data"
 )
 
+// readinessTimeout bounds the time taken by all the readiness checks together.
+const readinessTimeout = 2 * time.Second
+
+// healthCheck is one of the checks run by the readiness probe. The details
+// are reported whether or not the check passes.
+type healthCheck struct {
+	name string
+	run  func(ctx context.Context) (details map[string]any, err error)
+}
+
+// checkResult is the outcome of a health check, as reported by the probe.
+type checkResult struct {
+	Status  string         `json:"status"`
+	Error   string         `json:"error,omitempty"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// databaseChecks returns the readiness checks of the database behind db.
+func databaseChecks(db *sql.DB) ([]healthCheck, error) {
+	migrator, err := newMigrator(db)
+	if err != nil {
+		return nil, err
+	}
+
+	ping := healthCheck{
+		name: "database",
+		run: func(ctx context.Context) (map[string]any, error) {
+			start := time.Now()
+			err := db.PingContext(ctx)
+			return map[string]any{"latency": time.Since(start).String()}, err
+		},
+	}
+
+	migrations := healthCheck{
+		name: "migrations",
+		run: func(ctx context.Context) (map[string]any, error) {
+			details := map[string]any{"expected": migrator.Latest()}
+			version, err := migrator.Version(ctx)
+			if err != nil {
+				return details, err
+			}
+			details["current"] = version
+			if version != migrator.Latest() {
+				return details, fmt.Errorf("schema is at version %d, expected %d", version, migrator.Latest())
+			}
+			return details, nil
+		},
+	}
+
+	// The pool only reports its usage: failing readiness when it is busy
+	// would pull every instance out of the load balancer at once under load
+	pool := healthCheck{
+		name: "pool",
+		run: func(ctx context.Context) (map[string]any, error) {
+			return poolDetails(db.Stats()), nil
+		},
+	}
+
+	return []healthCheck{ping, migrations, pool}, nil
+}
+
+// replicaCheckInterval is how often the health of the replicas is checked.
+const replicaCheckInterval = 10 * time.Second
+
+// replicaCheck reports the health of the read replicas. It doesn't fail, as
+// reads fall back to the primary when no replica is available.
+func replicaCheck(replicas *data.ReplicaSet) healthCheck {
+	return healthCheck{
+		name: "replicas",
+		run: func(ctx context.Context) (map[string]any, error) {
+			statuses := replicas.Status()
+			healthy := 0
+			for _, status := range statuses {
+				if status.Healthy {
+					healthy++
+				}
+			}
+			return map[string]any{"healthy": healthy, "replicas": statuses}, nil
+		},
+	}
+}
+
+// monitorReplicas pings the replicas periodically until ctx is canceled, so
+// that the ones failing are skipped and the ones recovering are used again.
+func (a *applicationDependencies) monitorReplicas(ctx context.Context) {
+	ticker := time.NewTicker(replicaCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		checkCtx, cancel := context.WithTimeout(ctx, readinessTimeout)
+		a.replicas.CheckHealth(checkCtx)
+		cancel()
+	}
+}
+
+// poolDetails reports the usage of the connection pool. Its saturation is the
+// share of the connections it may open that are in use; at 1, new queries
+// have to wait for one to be released.
+func poolDetails(stats sql.DBStats) map[string]any {
+	details := map[string]any{
+		"open":          stats.OpenConnections,
+		"in_use":        stats.InUse,
+		"idle":          stats.Idle,
+		"max_open":      stats.MaxOpenConnections,
+		"wait_count":    stats.WaitCount,
+		"wait_duration": stats.WaitDuration.String(),
+
+		"max_idle_closed":      stats.MaxIdleClosed,
+		"max_idle_time_closed": stats.MaxIdleTimeClosed,
+		"max_lifetime_closed":  stats.MaxLifetimeClosed,
+	}
+	if stats.MaxOpenConnections <= 0 {
+		// The pool is unbounded, so it can't be saturated.
+		return details
+	}
+
+	details["saturation"] = float64(stats.InUse) / float64(stats.MaxOpenConnections)
+	return details
+}
+
+// liveHandler reports that the process is up. It doesn't look at any
+// dependency, so that a database outage doesn't get the process restarted.
+func (a *applicationDependencies) liveHandler(w http.ResponseWriter, r *http.Request) {
+	data := envelope{
+		"status": "alive",
+		"system_info": map[string]string{
+			"environment": a.config.environment,
+			"version":     appVersion,
+		},
+	}
+
+	err := a.writeJSON(w, http.StatusOK, data, nil)
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+// readyHandler reports whether the application can serve traffic, running
+// the readiness checks concurrently. It responds 503 when any check fails and
+// once the server has started shutting down.
+func (a *applicationDependencies) readyHandler(w http.ResponseWriter, r *http.Request) {
+	if a.shuttingDown.Load() {
+		err := a.writeJSON(w, http.StatusServiceUnavailable, envelope{"status": "shutting down"}, nil)
+		if err != nil {
+			a.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	results := make(map[string]checkResult, len(a.readinessChecks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range a.readinessChecks {
+		wg.Add(1)
+		go func(check healthCheck) {
+			defer wg.Done()
+
+			details, err := check.run(ctx)
+			result := checkResult{Status: "pass", Details: details}
+			if err != nil {
+				result.Status = "fail"
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			results[check.name] = result
+			mu.Unlock()
+		}(check)
+	}
+	wg.Wait()
+
+	status, code := "ready", http.StatusOK
+	for _, result := range results {
+		if result.Status == "fail" {
+			status, code = "not ready", http.StatusServiceUnavailable
+			break
+		}
+	}
+
+	err := a.writeJSON(w, code, envelope{"status": status, "checks": results}, nil)
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
 // healthCheckHandler responds with basic information about the application status
 func (a *applicationDependencies) healthCheckHandler(w http.ResponseWriter, r *http.Request) {
 	// Define the data to be returned in the response
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestLiveHandler(t *testing.T) {
+	app, _ := newTestApplication(t)
+	app.readinessChecks = []healthCheck{failingCheck("database")}
+
+	res := do(t, app, http.MethodGet, "/v1/healthcheck/live", "")
+	assertStatus(t, res, http.StatusOK)
+	if got := res.field("status"); got != "alive" {
+		t.Errorf("got status %v, want alive", got)
+	}
+}
+
+func TestReadyHandler(t *testing.T) {
+	passing := healthCheck{
+		name: "database",
+		run: func(ctx context.Context) (map[string]any, error) {
+			return map[string]any{"latency": "1ms"}, nil
+		},
+	}
+
+	t.Run("ready", func(t *testing.T) {
+		app, _ := newTestApplication(t)
+		app.readinessChecks = []healthCheck{passing}
+
+		res := do(t, app, http.MethodGet, "/v1/healthcheck/ready", "")
+		assertStatus(t, res, http.StatusOK)
+		if got := res.field("status"); got != "ready" {
+			t.Errorf("got status %v, want ready", got)
+		}
+		if got := res.field("checks", "database", "details", "latency"); got != "1ms" {
+			t.Errorf("got database latency %v, want 1ms", got)
+		}
+	})
+
+	t.Run("failing check", func(t *testing.T) {
+		app, _ := newTestApplication(t)
+		app.readinessChecks = []healthCheck{passing, failingCheck("migrations")}
+
+		res := do(t, app, http.MethodGet, "/v1/healthcheck/ready", "")
+		assertStatus(t, res, http.StatusServiceUnavailable)
+		if got := res.field("checks", "database", "status"); got != "pass" {
+			t.Errorf("got database status %v, want pass", got)
+		}
+		if got := res.field("checks", "migrations", "status"); got != "fail" {
+			t.Errorf("got migrations status %v, want fail", got)
+		}
+		if got := res.field("checks", "migrations", "error"); got != "check failed" {
+			t.Errorf("got migrations error %v, want %q", got, "check failed")
+		}
+	})
+
+	t.Run("shutting down", func(t *testing.T) {
+		app, _ := newTestApplication(t)
+		app.readinessChecks = []healthCheck{passing}
+		app.shuttingDown.Store(true)
+
+		res := do(t, app, http.MethodGet, "/v1/healthcheck/ready", "")
+		assertStatus(t, res, http.StatusServiceUnavailable)
+
+		res = do(t, app, http.MethodGet, "/v1/healthcheck/live", "")
+		assertStatus(t, res, http.StatusOK)
+	})
+}
+
+func TestPoolDetails(t *testing.T) {
+	details := poolDetails(sql.DBStats{MaxOpenConnections: 4, OpenConnections: 3, InUse: 1, Idle: 2})
+	if got := details["saturation"]; got != 0.25 {
+		t.Errorf("got saturation %v, want 0.25", got)
+	}
+
+	details = poolDetails(sql.DBStats{MaxOpenConnections: 4, OpenConnections: 4, InUse: 4})
+	if got := details["saturation"]; got != 1.0 {
+		t.Errorf("got saturation %v for a saturated pool, want 1", got)
+	}
+
+	details = poolDetails(sql.DBStats{OpenConnections: 10, InUse: 10})
+	if details["saturation"] != nil {
+		t.Errorf("got saturation %v for an unbounded pool", details["saturation"])
+	}
+}
+
+func failingCheck(name string) healthCheck {
+	return healthCheck{
+		name: name,
+		run: func(ctx context.Context) (map[string]any, error) {
+			return nil, errors.New("check failed")
+		},
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"database/sql"
+	"expvar"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/RayMC17/bookclub-api/internal/metrics"
+	"github.com/julienschmidt/httprouter"
+)
+
+// unmatchedRoute labels the requests that didn't match any route, so that
+// probing random URLs can't create an unbounded number of series.
+const unmatchedRoute = "unmatched"
+
+// appMetrics holds the metrics recorded by the middleware.
+type appMetrics struct {
+	registry          *metrics.Registry
+	requests          *metrics.CounterVec
+	requestDuration   *metrics.HistogramVec
+	limiterRejections *metrics.CounterVec
+	panics            *metrics.CounterVec
+}
+
+// newAppMetrics registers the application's metrics. The connection pool
+// gauges are left out when db is nil.
+func newAppMetrics(db *sql.DB) *appMetrics {
+	registry := metrics.NewRegistry()
+	m := &appMetrics{
+		registry: registry,
+		requests: registry.NewCounterVec("bookclub_http_requests_total",
+			"Number of HTTP requests handled, by method, route pattern and status.", "method", "route", "status"),
+		requestDuration: registry.NewHistogramVec("bookclub_http_request_duration_seconds",
+			"Time taken to handle HTTP requests, by method, route pattern and status.", metrics.DefaultBuckets, "method", "route", "status"),
+		limiterRejections: registry.NewCounterVec("bookclub_rate_limit_rejections_total",
+			"Number of requests rejected by the rate limiter."),
+		panics: registry.NewCounterVec("bookclub_panics_total",
+			"Number of panics recovered while handling requests."),
+	}
+
+	if db != nil {
+		registerDBMetrics(registry, db)
+	}
+	return m
+}
+
+// registerDBMetrics exposes the statistics of the connection pool.
+func registerDBMetrics(registry *metrics.Registry, db *sql.DB) {
+	gauge := func(name, help string, value func(sql.DBStats) float64) {
+		registry.NewGaugeFunc(name, help, func() float64 { return value(db.Stats()) })
+	}
+	counter := func(name, help string, value func(sql.DBStats) float64) {
+		registry.NewCounterFunc(name, help, func() float64 { return value(db.Stats()) })
+	}
+
+	gauge("bookclub_db_max_open_connections", "Maximum number of open connections to the database.",
+		func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) })
+	gauge("bookclub_db_open_connections", "Number of established connections, both in use and idle.",
+		func(s sql.DBStats) float64 { return float64(s.OpenConnections) })
+	gauge("bookclub_db_in_use_connections", "Number of connections currently in use.",
+		func(s sql.DBStats) float64 { return float64(s.InUse) })
+	gauge("bookclub_db_idle_connections", "Number of idle connections.",
+		func(s sql.DBStats) float64 { return float64(s.Idle) })
+	counter("bookclub_db_wait_count_total", "Number of connections waited for.",
+		func(s sql.DBStats) float64 { return float64(s.WaitCount) })
+	counter("bookclub_db_wait_duration_seconds_total", "Time spent waiting for new connections.",
+		func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() })
+	counter("bookclub_db_max_idle_closed_total", "Number of connections closed due to the idle connection limit.",
+		func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) })
+	counter("bookclub_db_max_idle_time_closed_total", "Number of connections closed due to the maximum idle time.",
+		func(s sql.DBStats) float64 { return float64(s.MaxIdleTimeClosed) })
+	counter("bookclub_db_max_lifetime_closed_total", "Number of connections closed due to the maximum lifetime.",
+		func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) })
+}
+
+// Middleware: Metrics
+func (a *applicationDependencies) recordMetrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		route := &routeInfo{pattern: unmatchedRoute}
+		rw := newResponseWriter(w)
+
+		next.ServeHTTP(rw, withRouteInfo(r, route))
+
+		status := strconv.Itoa(rw.status)
+		a.metrics.requests.Inc(r.Method, route.pattern, status)
+		a.metrics.requestDuration.Observe(time.Since(start).Seconds(), r.Method, route.pattern, status)
+	})
+}
+
+// patternRouter registers handlers on an httprouter.Router so that each
+// request records the pattern of the route it matched, for use as a metric
+// label, and runs in a span named after it. The middleware needing the route,
+// such as the rate limiter, is applied by wrap.
+type patternRouter struct {
+	*httprouter.Router
+	wrap func(method, pattern string, handler http.HandlerFunc) http.HandlerFunc
+	// version is the API version the routes are registered for, under the
+	// prefix "/" + version.
+	version string
+	// routes lists the routes registered, as "METHOD pattern", when not nil.
+	routes *[]string
+}
+
+// apiVersion returns a router registering its routes under the prefix of
+// version, such as "v1", and telling their handlers the version in the
+// request context.
+func (p patternRouter) apiVersion(version string) patternRouter {
+	p.version = version
+	return p
+}
+
+func (p patternRouter) HandlerFunc(method, pattern string, handler http.HandlerFunc) {
+	if p.version != "" {
+		pattern = "/" + p.version + pattern
+		handler = withVersionHandler(p.version, handler)
+	}
+	if p.routes != nil {
+		*p.routes = append(*p.routes, method+" "+pattern)
+	}
+	handler = traceHandler(method, pattern, handler)
+	if p.wrap != nil {
+		handler = p.wrap(method, pattern, handler)
+	}
+
+	p.Router.Handler(method, pattern, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if route := routeInfoFromContext(r); route != nil {
+			route.pattern = pattern
+		}
+		handler(w, r)
+	}))
+}
+
+// dbStatsVar is the expvar variable publishing the connection pool
+// statistics.
+const dbStatsVar = "database"
+
+// adminRoutes returns the handler of the admin server, which is kept off the
+// public port. Besides the metrics it serves the connection pool statistics.
+func (a *applicationDependencies) adminRoutes() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", a.metrics.registry.Handler())
+	mux.Handle("/debug/vars", expvarHandler(dbStatsVar))
+	return mux
+}
+
+// expvarHandler serves the named expvar variables in the format of
+// expvar.Handler. Unlike it, it leaves out the default cmdline and memstats
+// variables: the command line may hold the database password.
+func expvarHandler(names ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		fmt.Fprint(w, "{\n")
+		first := true
+		for _, name := range names {
+			v := expvar.Get(name)
+			if v == nil {
+				continue
+			}
+			if !first {
+				fmt.Fprint(w, ",\n")
+			}
+			first = false
+			fmt.Fprintf(w, "%q: %s", name, v.String())
+		}
+		fmt.Fprint(w, "\n}\n")
+	})
+}
+
+// adminServer returns the server of the admin routes.
+func (a *applicationDependencies) adminServer() *http.Server {
+	return &http.Server{
+		Addr:         a.config.adminAddr,
+		Handler:      a.adminRoutes(),
+		IdleTimeout:  time.Minute,
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 10 * time.Second,
+		ErrorLog:     slog.NewLogLogger(a.logger.Handler(), slog.LevelError),
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	app, store := newTestApplication(t)
+	book := insertTestBook(t, store, "Dune", "Frank Herbert")
+
+	do(t, app, http.MethodGet, fmt.Sprintf("/v1/books/%d", book.ID), "")
+	do(t, app, http.MethodGet, fmt.Sprintf("/v1/books/%d", book.ID), "")
+	do(t, app, http.MethodGet, "/v1/books/999", "")
+	app.routes().ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/no/such/route", nil))
+
+	// A panicking handler behind the same middleware as the routes.
+	panicking := app.recordMetrics(app.recoverPanic(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("boom")
+	})))
+	serve(t, panicking, http.MethodGet, "/", "")
+
+	w := httptest.NewRecorder()
+	app.adminRoutes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", w.Code, http.StatusOK)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("got Content-Type %q", ct)
+	}
+
+	body := w.Body.String()
+	for _, want := range []string{
+		"# TYPE bookclub_http_requests_total counter\n",
+		`bookclub_http_requests_total{method="GET",route="/v1/books/:id",status="200"} 2` + "\n",
+		`bookclub_http_requests_total{method="GET",route="/v1/books/:id",status="404"} 1` + "\n",
+		`bookclub_http_requests_total{method="GET",route="unmatched",status="404"} 1` + "\n",
+		`bookclub_http_requests_total{method="GET",route="unmatched",status="500"} 1` + "\n",
+		"# TYPE bookclub_http_request_duration_seconds histogram\n",
+		`bookclub_http_request_duration_seconds_bucket{method="GET",route="/v1/books/:id",status="200",le="+Inf"} 2` + "\n",
+		`bookclub_http_request_duration_seconds_count{method="GET",route="/v1/books/:id",status="200"} 2` + "\n",
+		"bookclub_panics_total 1\n",
+		"bookclub_rate_limit_rejections_total 0\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics missing %q\n%s", want, body)
+		}
+	}
+}
+
+func TestDebugVars(t *testing.T) {
+	app, _ := newTestApplication(t)
+	expvar.Publish(dbStatsVar, expvar.Func(func() any { return sql.DBStats{MaxOpenConnections: 25} }))
+
+	w := httptest.NewRecorder()
+	app.adminRoutes().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d", w.Code, http.StatusOK)
+	}
+
+	var vars map[string]json.RawMessage
+	if err := json.Unmarshal(w.Body.Bytes(), &vars); err != nil {
+		t.Fatalf("decoding %q: %v", w.Body.String(), err)
+	}
+	if _, ok := vars[dbStatsVar]; !ok {
+		t.Errorf("missing %q in %s", dbStatsVar, w.Body.String())
+	}
+	// The command line may hold the database password
+	for _, name := range []string{"cmdline", "memstats"} {
+		if _, ok := vars[name]; ok {
+			t.Errorf("got %q in %s", name, w.Body.String())
+		}
+	}
+}
@@ -1,19 +1,92 @@
 package main
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
-	"net"
+	"math"
 	"net/http"
 	"strings"
-	"sync"
 	"time"
 
-	"golang.org/x/time/rate"
+	"github.com/RayMC17/bookclub-api/internal/data"
 )
 
-type client struct {
-	limiter  *rate.Limiter
-	lastSeen time.Time
+// responseWriter wraps an http.ResponseWriter to record the status code and
+// the number of body bytes sent to the client.
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+// newResponseWriter wraps w, reusing it if an outer middleware already did.
+func newResponseWriter(w http.ResponseWriter) *responseWriter {
+	if rw, ok := w.(*responseWriter); ok {
+		return rw
+	}
+	return &responseWriter{ResponseWriter: w, status: http.StatusOK}
+}
+
+func (rw *responseWriter) WriteHeader(status int) {
+	if !rw.wroteHeader {
+		rw.status = status
+		rw.wroteHeader = true
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	rw.wroteHeader = true
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying ResponseWriter.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// maxRequestIDLength bounds the X-Request-ID values accepted from clients.
+const maxRequestIDLength = 128
+
+// Middleware: Request ID
+//
+// requestID propagates the X-Request-ID header of the request, or assigns a
+// new ID when it is missing or malformed, and echoes it in the response.
+func (a *applicationDependencies) requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		w.Header().Set("X-Request-ID", id)
+		next.ServeHTTP(w, withRequestID(r, id))
+	})
+}
+
+// validRequestID reports whether id is short and made of printable ASCII, so
+// that it is safe to log and echo back.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID returns a random 128-bit ID in hex.
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
 }
 
 // Middleware: Panic Recovery
@@ -21,6 +94,7 @@ func (a *applicationDependencies) recoverPanic(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
+				a.metrics.panics.Inc()
 				w.Header().Set("Connection", "close")
 				a.serverErrorResponse(w, r, fmt.Errorf("%s", err))
 			}
@@ -29,51 +103,51 @@ func (a *applicationDependencies) recoverPanic(next http.Handler) http.Handler {
 	})
 }
 
-// Middleware: Rate Limiting
-func (a *applicationDependencies) rateLimit(next http.Handler) http.Handler {
-	clients := make(map[string]*client)
-	var mu sync.Mutex
-
-	go func() {
-		for {
-			time.Sleep(time.Minute)
-			mu.Lock()
-			for ip, c := range clients {
-				if time.Since(c.lastSeen) > 3*time.Minute {
-					delete(clients, ip)
-				}
-			}
-			mu.Unlock()
-		}
-	}()
+// Clients ask for their reads to go to the primary with this header, and are
+// sent there for a while after a write by this cookie.
+const (
+	readPrimaryHeader = "X-Read-Primary"
+	readPrimaryCookie = "read_primary"
+)
 
+// Middleware: Read-your-writes
+//
+// readYourWrites sends the queries of a request to the primary, rather than
+// to a replica, when the request writes, when the client asks for it, or when
+// the client has written recently. The client then reads its writes before
+// they reach the replicas.
+func (a *applicationDependencies) readYourWrites(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !a.config.limiter.enabled {
+		if a.replicas == nil {
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		ip, _, err := net.SplitHostPort(r.RemoteAddr)
-		if err != nil {
-			a.serverErrorResponse(w, r, err)
-			return
+		primary := r.Header.Get(readPrimaryHeader) == "true"
+		if _, err := r.Cookie(readPrimaryCookie); err == nil {
+			primary = true
 		}
 
-		mu.Lock()
-		if _, found := clients[ip]; !found {
-			clients[ip] = &client{
-				limiter: rate.NewLimiter(rate.Limit(a.config.limiter.rps), a.config.limiter.burst),
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+		default:
+			primary = true
+			maxAge := int(math.Ceil(a.config.db.replicaMaxLag.Seconds()))
+			if maxAge > 0 {
+				http.SetCookie(w, &http.Cookie{
+					Name:     readPrimaryCookie,
+					Value:    "1",
+					Path:     "/",
+					MaxAge:   maxAge,
+					HttpOnly: true,
+					SameSite: http.SameSiteLaxMode,
+				})
 			}
 		}
 
-		clients[ip].lastSeen = time.Now()
-		if !clients[ip].limiter.Allow() {
-			mu.Unlock()
-			a.rateLimitExceededResponse(w, r)
-			return
+		if primary {
+			r = r.WithContext(data.WithPrimary(r.Context()))
 		}
-		mu.Unlock()
-
 		next.ServeHTTP(w, r)
 	})
 }
@@ -82,9 +156,20 @@ func (a *applicationDependencies) rateLimit(next http.Handler) http.Handler {
 func (a *applicationDependencies) logRequest(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
-		a.logger.Info("started request", "method", r.Method, "url", r.URL.String())
-		next.ServeHTTP(w, r)
-		a.logger.Info("completed request", "method", r.Method, "url", r.URL.String(), "duration", time.Since(start))
+		logger := a.requestLogger(r)
+		logger.Debug("started request", "method", r.Method, "url", r.URL.String())
+
+		rw := newResponseWriter(w)
+		next.ServeHTTP(rw, r)
+
+		logger.Info("completed request",
+			"method", r.Method,
+			"url", r.URL.String(),
+			"status", rw.status,
+			"bytes", rw.bytes,
+			"client_ip", clientIPFromContext(r),
+			"duration", time.Since(start),
+		)
 	})
 }
 
@@ -96,23 +181,13 @@ func (a *applicationDependencies) authenticate(next http.Handler) http.Handler {
 			a.unauthorizedResponse(w, r)
 			return
 		}
-		// Extract token and validate it
-		//token := strings.TrimPrefix(authHeader, "Bearer ")
-		// Here, add logic to validate token
-		// If invalid:
-		//authHeader := r.Header.get("Authorization")
-		//if authHeather == "" || !strings.HasPrefix(authHeader, "Bearer ") {
+		// Bearer tokens aren't issued yet, so none of them is valid
 		a.unauthorizedResponse(w, r)
-		 return
-		
-
-		// If token is valid, proceed
-		next.ServeHTTP(w, r)
 	})
 }
 
 // Unauthorized Response Helper
 func (a *applicationDependencies) unauthorizedResponse(w http.ResponseWriter, r *http.Request) {
 	message := "You are not authorized to access this resource"
-	a.errorResponseJSON(w, r, http.StatusUnauthorized, message)
+	a.errorResponseJSON(w, r, http.StatusUnauthorized, "unauthorized", message)
 }
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	app, _ := newTestApplication(t)
+	handler := app.requestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		app.writeJSON(w, http.StatusOK, envelope{"request_id": requestIDFromContext(r)}, nil)
+	}))
+
+	tests := []struct {
+		name   string
+		header string
+		keep   bool
+	}{
+		{"propagated", "abc-123", true},
+		{"missing", "", false},
+		{"too long", strings.Repeat("a", maxRequestIDLength+1), false},
+		{"control characters", "abc\x01", false},
+		{"spaces", "abc 123", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, w := newRecordedRequest(http.MethodGet, "/", "")
+			if tt.header != "" {
+				r.Header.Set("X-Request-ID", tt.header)
+			}
+			handler.ServeHTTP(w, r)
+
+			id := w.Header().Get("X-Request-ID")
+			if tt.keep && id != tt.header {
+				t.Errorf("got request ID %q, want %q", id, tt.header)
+			}
+			if !tt.keep && (len(id) != 32 || id == tt.header) {
+				t.Errorf("got request ID %q, want a new 32 character ID", id)
+			}
+			if !strings.Contains(w.Body.String(), fmt.Sprintf("%q", id)) {
+				t.Errorf("request context has a different ID than the response header %q: %s", id, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestServerErrorLogsRequestID(t *testing.T) {
+	app, store := newTestApplication(t)
+	book := insertTestBook(t, store, "Dune", "Frank Herbert")
+
+	var logs bytes.Buffer
+	app.logger = slog.New(slog.NewJSONHandler(&logs, nil))
+	app.models.Books = failingBooks{store.Books, errors.New("connection refused")}
+
+	r, w := newRecordedRequest(http.MethodGet, fmt.Sprintf("/v1/books/%d", book.ID), "")
+	r.Header.Set("X-Request-ID", "trace-me")
+	app.routes().ServeHTTP(w, r)
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("got status %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+
+	var errorLogged, completionLogged bool
+	for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+		var record map[string]any
+		err := json.Unmarshal([]byte(line), &record)
+		if err != nil {
+			t.Fatalf("decoding log line %q: %v", line, err)
+		}
+		if record["request_id"] != "trace-me" {
+			t.Errorf("log line without the request ID: %s", line)
+		}
+		switch record["msg"] {
+		case "connection refused":
+			errorLogged = true
+		case "completed request":
+			completionLogged = true
+			if record["status"] != float64(http.StatusInternalServerError) || record["bytes"] != float64(w.Body.Len()) {
+				t.Errorf("got completion log %s, want status 500 and %d bytes", line, w.Body.Len())
+			}
+		}
+	}
+	if !errorLogged || !completionLogged {
+		t.Errorf("missing log lines:\n%s", logs.String())
+	}
+}
+
+func TestNewLogger(t *testing.T) {
+	tests := []struct {
+		format, level string
+		wantErr       bool
+	}{
+		{"json", "debug", false},
+		{"text", "WARN", false},
+		{"text", "error", false},
+		{"xml", "info", true},
+		{"json", "loud", true},
+	}
+
+	for _, tt := range tests {
+		_, err := newLogger(&bytes.Buffer{}, tt.format, tt.level)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("newLogger(%q, %q): got error %v, want error %t", tt.format, tt.level, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/RayMC17/bookclub-api/internal/migrate"
+	"github.com/RayMC17/bookclub-api/migrations"
+)
+
+const migrateUsage = "usage: api [flags] migrate up|down [N]|status|goto N"
+
+// newMigrator returns a migrator for the migrations embedded in the binary.
+func newMigrator(db *sql.DB) (*migrate.Migrator, error) {
+	all, err := migrate.Load(migrations.Files)
+	if err != nil {
+		return nil, err
+	}
+	return &migrate.Migrator{DB: db, Migrations: all}, nil
+}
+
+// runMigrateCommand implements the "migrate" subcommand.
+func (a *applicationDependencies) runMigrateCommand(db *sql.DB, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	migrator, err := newMigrator(db)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		if len(args) != 1 {
+			return errors.New(migrateUsage)
+		}
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		a.logger.Info("applied migrations", "versions", applied)
+
+	case "down":
+		steps := 1
+		if len(args) == 2 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return errors.New("migrate down: N must be a positive integer")
+			}
+		} else if len(args) != 1 {
+			return errors.New(migrateUsage)
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		a.logger.Info("reverted migrations", "versions", reverted)
+
+	case "goto":
+		if len(args) != 2 {
+			return errors.New(migrateUsage)
+		}
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil || version < 0 {
+			return errors.New("migrate goto: N must be a migration version or 0")
+		}
+		changed, err := migrator.Goto(ctx, version)
+		if err != nil {
+			return err
+		}
+		a.logger.Info("migrated", "target", version, "versions", changed)
+
+	case "status":
+		if len(args) != 1 {
+			return errors.New(migrateUsage)
+		}
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, s := range statuses {
+			status, appliedAt := "pending", ""
+			if s.Applied {
+				status = "applied"
+			}
+			// Migrations applied by golang-migrate have no time
+			if !s.AppliedAt.IsZero() {
+				appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05 MST")
+			}
+			fmt.Fprintf(tw, "%06d\t%s\t%s\t%s\n", s.Version, s.Name, status, appliedAt)
+		}
+		return tw.Flush()
+
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	return nil
+}
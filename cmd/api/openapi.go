@@ -0,0 +1,202 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/RayMC17/bookclub-api/internal/data"
+	"github.com/RayMC17/bookclub-api/internal/openapi"
+)
+
+// apiOperation describes a route in the OpenAPI document. The types of the
+// request and response bodies are given as values, like the envelopes the
+// handlers write.
+type apiOperation struct {
+	summary string
+	tag     string
+	query   []openapi.Parameter
+	// paged operations take the page, page_size and sort parameters.
+	paged bool
+	// tagged operations send an ETag, and answer GETs with a matching
+	// If-None-Match 304 Not Modified.
+	tagged bool
+	// ifMatch operations require an If-Match header.
+	ifMatch  bool
+	body     any
+	status   int
+	response envelope
+}
+
+// queryParam describes a query parameter of type typ.
+func queryParam(name, typ, description string) openapi.Parameter {
+	return openapi.Parameter{Name: name, In: "query", Description: description, Schema: openapi.Schema{"type": typ}}
+}
+
+var pageParams = []openapi.Parameter{
+	queryParam("page", "integer", "Page number, from 1"),
+	queryParam("page_size", "integer", "Number of items per page, up to 100"),
+	queryParam("sort", "string", "Field to sort by, descending when prefixed with -"),
+}
+
+// messageResponse is the response of the operations that only confirm they
+// were done.
+var messageResponse = envelope{"message": ""}
+
+// apiOperations describes the routes of routes(), keyed by "METHOD pattern".
+// TestOpenAPICoversRoutes fails when a route is missing.
+var apiOperations = map[string]apiOperation{
+	"GET /v1/openapi.json": {summary: "Get this OpenAPI document", tag: "meta", status: http.StatusOK, response: envelope{}},
+
+	"GET /v1/healthcheck":       {summary: "Report the status of the API", tag: "health", status: http.StatusOK, response: envelope{"status": "", "system_info": map[string]string{}}},
+	"GET /v1/healthcheck/live":  {summary: "Liveness probe", tag: "health", status: http.StatusOK, response: envelope{"status": "", "system_info": map[string]string{}}},
+	"GET /v1/healthcheck/ready": {summary: "Readiness probe, checking the dependencies of the API", tag: "health", status: http.StatusOK, response: envelope{"status": "", "checks": map[string]checkResult{}}},
+
+	"GET /v1/books": {summary: "List books", tag: "books", tagged: true, paged: true, status: http.StatusOK,
+		query: []openapi.Parameter{
+			queryParam("title", "string", "Words in the title"),
+			queryParam("author", "string", "Name of an author"),
+			queryParam("genre", "string", "Genre or genre alias, including its descendant genres"),
+		},
+		response: envelope{"books": []*data.Book{}, "@metadata": data.Metadata{}}},
+	"GET /v1/books/:id":    {summary: "Get a book", tag: "books", tagged: true, status: http.StatusOK, response: envelope{"book": data.Book{}}},
+	"POST /v1/books":       {summary: "Create a book", tag: "books", tagged: true, body: createBookInput{}, status: http.StatusCreated, response: envelope{"book": data.Book{}}},
+	"PUT /v1/books/:id":    {summary: "Update a book", tag: "books", tagged: true, ifMatch: true, body: updateBookInput{}, status: http.StatusOK, response: envelope{"book": data.Book{}}},
+	"DELETE /v1/books/:id": {summary: "Delete a book", tag: "books", ifMatch: true, status: http.StatusOK, response: messageResponse},
+
+	"GET /v1/lists":                 {summary: "List reading lists", tag: "reading lists", tagged: true, paged: true, status: http.StatusOK, response: envelope{"reading_lists": []*data.ReadingList{}, "metadata": data.Metadata{}}},
+	"GET /v1/lists/:id":             {summary: "Get a reading list", tag: "reading lists", tagged: true, status: http.StatusOK, response: envelope{"reading_list": data.ReadingList{}}},
+	"POST /v1/lists":                {summary: "Create a reading list", tag: "reading lists", tagged: true, body: createReadingListInput{}, status: http.StatusCreated, response: envelope{"reading_list": data.ReadingList{}}},
+	"PUT /v1/lists/:id":             {summary: "Update a reading list", tag: "reading lists", tagged: true, ifMatch: true, body: updateReadingListInput{}, status: http.StatusOK, response: envelope{"reading_list": data.ReadingList{}}},
+	"DELETE /v1/lists/:id":          {summary: "Delete a reading list", tag: "reading lists", ifMatch: true, status: http.StatusOK, response: messageResponse},
+	"POST /v1/lists/:id/books":      {summary: "Add a book to a reading list", tag: "reading lists", body: readingListBookInput{}, status: http.StatusOK, response: messageResponse},
+	"DELETE /v1/lists/:id/books":    {summary: "Remove a book from a reading list", tag: "reading lists", ifMatch: true, body: readingListBookInput{}, status: http.StatusOK, response: messageResponse},
+	"PUT /v1/lists/:id/books":       {summary: "Reorder the books of a reading list", tag: "reading lists", tagged: true, ifMatch: true, body: reorderReadingListInput{}, status: http.StatusOK, response: envelope{"reading_list": data.ReadingList{}}},
+	"GET /v1/books/:id/reviews":     {summary: "List the reviews of a book", tag: "reviews", tagged: true, paged: true, status: http.StatusOK, query: []openapi.Parameter{queryParam("rating", "integer", "Rating, from 1 to 5"), queryParam("author", "string", "Author of the review")}, response: envelope{"reviews": []*data.Review{}, "metadata": data.Metadata{}}},
+	"POST /v1/books/:id/reviews":    {summary: "Review a book", tag: "reviews", tagged: true, body: createReviewInput{}, status: http.StatusCreated, response: envelope{"review": data.Review{}}},
+	"GET /v1/reviews/:id":           {summary: "Get a review", tag: "reviews", tagged: true, status: http.StatusOK, response: envelope{"review": data.Review{}}},
+	"PUT /v1/reviews/:id":           {summary: "Update a review", tag: "reviews", tagged: true, ifMatch: true, body: updateReviewInput{}, status: http.StatusOK, response: envelope{"review": data.Review{}}},
+	"DELETE /v1/reviews/:id":        {summary: "Delete a review", tag: "reviews", ifMatch: true, status: http.StatusOK, response: messageResponse},
+	"GET /v1/works/:id":             {summary: "Get a work with its editions", tag: "works", status: http.StatusOK, response: envelope{"work": data.Work{}}},
+	"GET /v1/books/:id/tags":        {summary: "List the tags of a book, with the number of users applying each", tag: "tags", tagged: true, status: http.StatusOK, response: envelope{"tags": []*data.TagCount{}}},
+	"POST /v1/books/:id/tags":       {summary: "Tag a book", tag: "tags", body: bookTagInput{}, status: http.StatusOK, response: envelope{"message": "", "tag": ""}},
+	"DELETE /v1/books/:id/tags":     {summary: "Remove a tag from a book", tag: "tags", ifMatch: true, body: bookTagInput{}, status: http.StatusOK, response: messageResponse},
+	"GET /v1/tags/:name/books":      {summary: "List the books with a tag", tag: "tags", tagged: true, paged: true, status: http.StatusOK, response: envelope{"tag": "", "books": []*data.TaggedBook{}, "metadata": data.Metadata{}}},
+	"GET /v1/genres":                {summary: "Get the genre tree", tag: "genres", tagged: true, status: http.StatusOK, response: envelope{"genres": []*data.Genre{}}},
+	"POST /v1/genres":               {summary: "Create a genre", tag: "genres", body: createGenreInput{}, status: http.StatusCreated, response: envelope{"genre": data.Genre{}}},
+	"GET /v1/series":                {summary: "List series", tag: "series", tagged: true, paged: true, status: http.StatusOK, query: []openapi.Parameter{queryParam("name", "string", "Words in the name")}, response: envelope{"series": []*data.Series{}, "metadata": data.Metadata{}}},
+	"POST /v1/series":               {summary: "Create a series", tag: "series", body: createSeriesInput{}, status: http.StatusCreated, response: envelope{"series": data.Series{}}},
+	"GET /v1/series/:id":            {summary: "Get a series with its entries", tag: "series", tagged: true, status: http.StatusOK, response: envelope{"series": data.Series{}}},
+	"POST /v1/series/:id/entries":   {summary: "Place a work in a series", tag: "series", body: seriesEntryInput{}, status: http.StatusOK, response: envelope{"series": data.Series{}}},
+	"DELETE /v1/series/:id/entries": {summary: "Remove a work from a series", tag: "series", ifMatch: true, body: removeSeriesEntryInput{}, status: http.StatusOK, response: messageResponse},
+
+	"GET /v1/authors":            {summary: "List authors", tag: "authors", tagged: true, paged: true, status: http.StatusOK, query: []openapi.Parameter{queryParam("name", "string", "Words in the name")}, response: envelope{"authors": []*data.Author{}, "metadata": data.Metadata{}}},
+	"POST /v1/authors":           {summary: "Create an author", tag: "authors", body: createAuthorInput{}, status: http.StatusCreated, response: envelope{"author": data.Author{}}},
+	"GET /v1/authors/:id":        {summary: "Get an author", tag: "authors", tagged: true, status: http.StatusOK, response: envelope{"author": data.Author{}}},
+	"PUT /v1/authors/:id":        {summary: "Update an author", tag: "authors", tagged: true, ifMatch: true, body: updateAuthorInput{}, status: http.StatusOK, response: envelope{"author": data.Author{}}},
+	"DELETE /v1/authors/:id":     {summary: "Delete an author who isn't credited on any book", tag: "authors", ifMatch: true, status: http.StatusOK, response: messageResponse},
+	"GET /v1/authors/:id/books":  {summary: "List the books of an author", tag: "authors", tagged: true, paged: true, status: http.StatusOK, response: envelope{"books": []*data.Book{}, "metadata": data.Metadata{}}},
+	"POST /v1/authors/:id/merge": {summary: "Merge duplicate authors into an author", tag: "authors", body: mergeAuthorsInput{}, status: http.StatusOK, response: envelope{"author": data.Author{}}},
+
+	"GET /v1/users/:id":             {summary: "Get the profile of a user", tag: "users", tagged: true, status: http.StatusOK, response: envelope{"user_profile": data.User{}}},
+	"DELETE /v1/users/:id":          {summary: "Delete a user", tag: "users", ifMatch: true, status: http.StatusOK, response: messageResponse},
+	"GET /v1/users/:id/lists":       {summary: "List the reading lists of a user", tag: "users", tagged: true, paged: true, status: http.StatusOK, response: envelope{"reading_lists": []*data.ReadingList{}, "metadata": data.Metadata{}}},
+	"GET /v1/users/:id/reviews":     {summary: "List the reviews of a user", tag: "users", tagged: true, paged: true, status: http.StatusOK, response: envelope{"reviews": []*data.Review{}, "metadata": data.Metadata{}}},
+	"GET /v1/users/:id/series/next": {summary: "List the next works of the series a user is reading", tag: "users", status: http.StatusOK, response: envelope{"next_in_series": []*data.NextInSeries{}}},
+}
+
+// newOpenAPIDocument describes the operations of apiOperations.
+func newOpenAPIDocument() *openapi.Document {
+	doc := openapi.New("Book Club API", appVersion)
+	doc.Info.Description = "Books, reading lists and reviews of the book club."
+
+	errorContent := map[string]openapi.MediaType{
+		"application/json": {Schema: doc.SchemaOf(errorEnvelope{})},
+		problemJSON:        {Schema: doc.SchemaOf(problem{})},
+	}
+	doc.Components.Responses["Error"] = openapi.Response{
+		Description: "Error, as problem details when the client accepts application/problem+json",
+		Content:     errorContent,
+	}
+
+	for route, spec := range apiOperations {
+		method, pattern, _ := strings.Cut(route, " ")
+
+		success := openapi.Response{
+			Description: http.StatusText(spec.status),
+			Content:     map[string]openapi.MediaType{"application/json": {Schema: envelopeSchema(doc, spec.response)}},
+		}
+		if spec.tagged {
+			success.Headers = map[string]openapi.Header{
+				"ETag": {Description: "Entity tag of the response body", Schema: openapi.Schema{"type": "string"}},
+			}
+		}
+		op := &openapi.Operation{
+			OperationID: operationID(method, pattern),
+			Summary:     spec.summary,
+			Tags:        []string{spec.tag},
+			Responses: map[string]openapi.Response{
+				strconv.Itoa(spec.status): success,
+				"default":                 {Ref: "#/components/responses/Error"},
+			},
+		}
+		for _, segment := range strings.Split(pattern, "/") {
+			if name, ok := strings.CutPrefix(segment, ":"); ok {
+				typ := "string"
+				if name == "id" {
+					typ = "integer"
+				}
+				op.Parameters = append(op.Parameters, openapi.Parameter{Name: name, In: "path", Required: true, Schema: openapi.Schema{"type": typ}})
+			}
+		}
+		op.Parameters = append(op.Parameters, spec.query...)
+		if spec.paged {
+			op.Parameters = append(op.Parameters, pageParams...)
+		}
+		if spec.tagged && method == http.MethodGet {
+			op.Parameters = append(op.Parameters, openapi.Parameter{Name: "If-None-Match", In: "header", Description: "Entity tags of the copies the client has", Schema: openapi.Schema{"type": "string"}})
+			op.Responses["304"] = openapi.Response{Description: http.StatusText(http.StatusNotModified)}
+		}
+		if spec.ifMatch {
+			op.Parameters = append(op.Parameters, openapi.Parameter{Name: "If-Match", In: "header", Required: true, Description: "Entity tag of the representation being changed", Schema: openapi.Schema{"type": "string"}})
+		}
+		if spec.body != nil {
+			op.RequestBody = &openapi.RequestBody{
+				Required: true,
+				Content:  map[string]openapi.MediaType{"application/json": {Schema: doc.SchemaOf(spec.body)}},
+			}
+		}
+
+		doc.Add(method, pattern, op)
+	}
+	return doc
+}
+
+// envelopeSchema returns the schema of an envelope holding values of the
+// types of the members of response.
+func envelopeSchema(doc *openapi.Document, response envelope) openapi.Schema {
+	properties := make(map[string]openapi.Schema, len(response))
+	for name, value := range response {
+		properties[name] = doc.SchemaOf(value)
+	}
+	return openapi.Schema{"type": "object", "properties": properties}
+}
+
+// operationID names an operation after its method and path, such as
+// "get_v1_books_id".
+func operationID(method, pattern string) string {
+	replacer := strings.NewReplacer("/", "_", ":", "", ".", "_")
+	return strings.ToLower(method) + replacer.Replace(pattern)
+}
+
+// openAPIDocument is built once, on the first request for it.
+var openAPIDocument = sync.OnceValue(newOpenAPIDocument)
+
+// openAPIHandler serves the OpenAPI document of the API.
+func (a *applicationDependencies) openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	err := a.writeJSON(w, http.StatusOK, openAPIDocument(), nil)
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/RayMC17/bookclub-api/internal/data"
+)
+
+func TestOpenAPICoversRoutes(t *testing.T) {
+	app, _ := newTestApplication(t)
+	router := app.router()
+
+	registered := make(map[string]bool)
+	for _, route := range *router.routes {
+		registered[route] = true
+		// The legacy paths only redirect to the documented ones
+		if strings.Contains(route, " "+legacyPrefix+"/") {
+			continue
+		}
+		if _, ok := apiOperations[route]; !ok {
+			t.Errorf("route %s has no entry in apiOperations", route)
+		}
+	}
+	for route := range apiOperations {
+		if !registered[route] {
+			t.Errorf("apiOperations describes %s, which isn't routed", route)
+		}
+	}
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	app, _ := newTestApplication(t)
+	res := do(t, app, http.MethodGet, "/v1/openapi.json", "")
+	assertStatus(t, res, http.StatusOK)
+
+	if got := res.field("openapi"); got != "3.1.0" {
+		t.Errorf("got openapi %v, want 3.1.0", got)
+	}
+	if res.field("paths", "/v1/books/{id}", "get") == nil {
+		t.Error("got no operation for GET /v1/books/{id}")
+	}
+	if res.field("paths", "/v1/books", "post", "requestBody") == nil {
+		t.Error("got no request body for POST /v1/books")
+	}
+	for _, name := range []string{"Book", "Review", "ReadingList", "User", "Metadata", "CreateBookInput", "ErrorEnvelope", "Problem"} {
+		if res.field("components", "schemas", name) == nil {
+			t.Errorf("got no %s schema", name)
+		}
+	}
+	if res.field("components", "schemas", "User", "properties", "password") != nil {
+		t.Error("the User schema has the password")
+	}
+
+	// Every reference resolves
+	var check func(value any)
+	check = func(value any) {
+		switch value := value.(type) {
+		case map[string]any:
+			if ref, ok := value["$ref"].(string); ok {
+				keys := strings.Split(strings.TrimPrefix(ref, "#/"), "/")
+				if res.field(keys...) == nil {
+					t.Errorf("reference %s doesn't resolve", ref)
+				}
+			}
+			for _, member := range value {
+				check(member)
+			}
+		case []any:
+			for _, item := range value {
+				check(item)
+			}
+		}
+	}
+	check(res.body)
+}
+
+func TestOpenAPIReadingListStatuses(t *testing.T) {
+	app, _ := newTestApplication(t)
+	res := do(t, app, http.MethodGet, "/v1/openapi.json", "")
+	assertStatus(t, res, http.StatusOK)
+
+	want := fmt.Sprint(data.ReadingListStatuses)
+	for _, name := range []string{"ReadingList", "CreateReadingListInput"} {
+		if got := fmt.Sprint(res.field("components", "schemas", name, "properties", "status", "enum")); got != want {
+			t.Errorf("got %s status enum %s, want %s", name, got, want)
+		}
+	}
+
+	// The update may leave the status out
+	want = strings.TrimSuffix(want, "]") + " <nil>]"
+	if got := fmt.Sprint(res.field("components", "schemas", "UpdateReadingListInput", "properties", "status", "enum")); got != want {
+		t.Errorf("got UpdateReadingListInput status enum %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RayMC17/bookclub-api/internal/ratelimit"
+	"github.com/RayMC17/bookclub-api/internal/trace"
+)
+
+// Buckets of the in-process store that are idle for longer than
+// limiterIdleTime are forgotten every limiterSweepInterval.
+const (
+	limiterSweepInterval = time.Minute
+	limiterIdleTime      = 3 * time.Minute
+)
+
+// routePolicyRX matches a route policy such as
+// "POST /v1/books/:id/reviews=0.1:3".
+var routePolicyRX = regexp.MustCompile(`^([A-Z]+) (/\S*)=(\S+)$`)
+
+// parseRoutePolicy parses a -limiter-route policy into the route it applies
+// to, as "METHOD pattern", and the policy.
+func parseRoutePolicy(text string) (string, ratelimit.Policy, error) {
+	match := routePolicyRX.FindStringSubmatch(text)
+	if match == nil {
+		return "", ratelimit.Policy{}, fmt.Errorf("invalid route policy %q: expected \"METHOD /pattern=rate:burst\"", text)
+	}
+	route := match[1] + " " + match[2]
+	policy, err := ratelimit.ParsePolicy(route, match[3])
+	return route, policy, err
+}
+
+// rateLimiter holds the policies applied by the rateLimit middleware.
+type rateLimiter struct {
+	store ratelimit.Store
+	// global applies to the routes without a policy of their own.
+	global ratelimit.Policy
+	// routes are keyed by "METHOD pattern".
+	routes map[string]ratelimit.Policy
+	// exempt holds the SHA-256 sums of the tokens that aren't limited.
+	exempt [][sha256.Size]byte
+}
+
+// newRateLimiter returns the limiter configured by the -limiter-* flags. Its
+// buckets are kept in a.limiterStore, or in process when that is nil, in
+// which case a background task sweeps them until shutdown. It is called once,
+// when the dependencies are set up.
+func (a *applicationDependencies) newRateLimiter() *rateLimiter {
+	limiter := &rateLimiter{
+		store:  a.limiterStore,
+		global: ratelimit.Policy{Name: "global", Rate: a.config.limiter.rps, Burst: a.config.limiter.burst},
+		routes: make(map[string]ratelimit.Policy),
+	}
+
+	if limiter.store == nil && a.config.limiter.enabled {
+		store := ratelimit.NewMemoryStore()
+		limiter.store = store
+
+		// Forget the clients that haven't been seen for a while, until the
+		// server shuts down
+		a.background(func(ctx context.Context) {
+			ticker := time.NewTicker(limiterSweepInterval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					store.Sweep(limiterIdleTime)
+				}
+			}
+		})
+	}
+
+	// The policies were checked when the configuration was loaded
+	for _, text := range a.config.limiter.routes {
+		route, policy, err := parseRoutePolicy(text)
+		if err == nil {
+			limiter.routes[route] = policy
+		}
+	}
+
+	for _, token := range a.config.limiter.exemptTokens {
+		limiter.exempt = append(limiter.exempt, sha256.Sum256([]byte(token)))
+	}
+
+	return limiter
+}
+
+// policy returns the policy of the route.
+func (l *rateLimiter) policy(method, pattern string) ratelimit.Policy {
+	if policy, ok := l.routes[method+" "+pattern]; ok {
+		return policy
+	}
+	return l.global
+}
+
+// isExempt reports whether the request carries one of the exempt tokens as
+// a bearer token.
+func (l *rateLimiter) isExempt(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return false
+	}
+	sum := sha256.Sum256([]byte(token))
+	exempt := 0
+	for _, candidate := range l.exempt {
+		exempt |= subtle.ConstantTimeCompare(sum[:], candidate[:])
+	}
+	return exempt == 1
+}
+
+// clientKey identifies the client the buckets are kept for: the
+// authenticated user if there is one, so that users behind a shared address
+// don't use up each other's requests, and the client's IP address otherwise.
+func clientKey(r *http.Request) string {
+	if userID, ok := userIDFromContext(r); ok {
+		return "user:" + strconv.FormatInt(userID, 10)
+	}
+	return "ip:" + clientIPFromContext(r)
+}
+
+// Middleware: Rate Limiting
+//
+// rateLimit returns the route middleware taking a token from the client's
+// bucket for the route's policy, and responding 429 when it is empty. The
+// RateLimit-Limit and RateLimit-Remaining headers report the state of the
+// bucket, and Retry-After when to try again.
+func (a *applicationDependencies) rateLimit(limiter *rateLimiter) func(method, pattern string, next http.HandlerFunc) http.HandlerFunc {
+	return func(method, pattern string, next http.HandlerFunc) http.HandlerFunc {
+		policy := limiter.policy(method, pattern)
+
+		return func(w http.ResponseWriter, r *http.Request) {
+			if !a.config.limiter.enabled || limiter.isExempt(r) {
+				next(w, r)
+				return
+			}
+
+			ctx, span := trace.Start(r.Context(), "rateLimit", trace.KindInternal)
+			result, err := limiter.store.Allow(ctx, clientKey(r), policy)
+			span.SetAttributes("ratelimit.policy", policy.Name, "ratelimit.allowed", result.Allowed)
+			span.RecordError(err)
+			span.End()
+
+			if err != nil {
+				// Let the request through rather than fail it because the
+				// store is unavailable.
+				a.logError(r, fmt.Errorf("rate limiter store: %w", err))
+				next(w, r)
+				return
+			}
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			if !result.Allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds()))))
+				a.metrics.limiterRejections.Inc()
+				a.rateLimitExceededResponse(w, r)
+				return
+			}
+
+			next(w, r)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RayMC17/bookclub-api/internal/data"
+)
+
+// queryLog records the queries run on the fake databases, by database name.
+type queryLog struct {
+	mu      sync.Mutex
+	queries []string
+}
+
+func (l *queryLog) add(name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.queries = append(l.queries, name)
+}
+
+func (l *queryLog) take() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	queries := l.queries
+	l.queries = nil
+	return queries
+}
+
+// errHang makes the queries of a fake database wait for their context to be
+// done.
+var errHang = errors.New("hang")
+
+// fakeDB returns a pool whose queries return no rows, or fail with err, and
+// are logged under name.
+func fakeDB(t *testing.T, name string, log *queryLog, err *error) *sql.DB {
+	t.Helper()
+	db := sql.OpenDB(fakeDBConnector{name: name, log: log, err: err})
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+type fakeDBConnector struct {
+	name string
+	log  *queryLog
+	err  *error
+}
+
+func (c fakeDBConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return fakeDBConn{c}, nil
+}
+
+func (c fakeDBConnector) Driver() driver.Driver { return nil }
+
+type fakeDBConn struct {
+	fakeDBConnector
+}
+
+func (c fakeDBConn) Prepare(query string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c fakeDBConn) Close() error                              { return nil }
+func (c fakeDBConn) Begin() (driver.Tx, error)                 { return nil, driver.ErrSkip }
+
+func (c fakeDBConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.log.add(c.name)
+	if *c.err == errHang {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if *c.err != nil {
+		return nil, *c.err
+	}
+	return emptyRows{}, nil
+}
+
+func (c fakeDBConn) Ping(ctx context.Context) error {
+	return *c.err
+}
+
+type emptyRows struct{}
+
+func (emptyRows) Columns() []string              { return []string{"id", "name", "slug", "parent_id"} }
+func (emptyRows) Close() error                   { return nil }
+func (emptyRows) Next(dest []driver.Value) error { return io.EOF }
+
+func TestReadReplicas(t *testing.T) {
+	var log queryLog
+	var primaryErr, replicaErr error
+	primary := fakeDB(t, "primary", &log, &primaryErr)
+	replicaA := fakeDB(t, "replica-a", &log, &replicaErr)
+	replicaB := fakeDB(t, "replica-b", &log, &replicaErr)
+
+	replicas := data.NewReplicaSet(primary)
+	replicas.AddReplica("a", replicaA)
+	replicas.AddReplica("b", replicaB)
+
+	app, _ := newTestApplication(t)
+	app.config.db.replicaMaxLag = 5 * time.Second
+	app.replicas = replicas
+	app.models = data.NewReplicatedModels(replicas, data.Timeouts{})
+
+	t.Run("reads round-robin", func(t *testing.T) {
+		for i := 0; i < 4; i++ {
+			assertStatus(t, do(t, app, http.MethodGet, "/v1/genres", ""), http.StatusOK)
+		}
+		got := log.take()
+		if len(got) != 4 || got[0] == got[1] || got[0] != got[2] || got[1] != got[3] || got[0] == "primary" || got[1] == "primary" {
+			t.Fatalf("got queries on %q, want them alternating between the replicas", got)
+		}
+	})
+
+	t.Run("header", func(t *testing.T) {
+		r, w := newRecordedRequest(http.MethodGet, "/v1/genres", "")
+		r.Header.Set(readPrimaryHeader, "true")
+		app.routes().ServeHTTP(w, r)
+
+		if got := log.take(); len(got) != 1 || got[0] != "primary" {
+			t.Fatalf("got queries on %q, want the primary", got)
+		}
+	})
+
+	t.Run("cookie after write", func(t *testing.T) {
+		r, w := newRecordedRequest(http.MethodPost, "/v1/genres", `{}`)
+		app.routes().ServeHTTP(w, r)
+		cookies := w.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].Name != readPrimaryCookie || cookies[0].MaxAge != 5 {
+			t.Fatalf("got cookies %v after a write, want %s with a max age of 5s", cookies, readPrimaryCookie)
+		}
+		log.take()
+
+		r, w = newRecordedRequest(http.MethodGet, "/v1/genres", "")
+		r.AddCookie(cookies[0])
+		app.routes().ServeHTTP(w, r)
+		if got := log.take(); len(got) != 1 || got[0] != "primary" {
+			t.Fatalf("got queries on %q, want the primary", got)
+		}
+	})
+
+	t.Run("slow replicas", func(t *testing.T) {
+		replicaErr = errHang
+		app.models = data.NewReplicatedModels(replicas, data.Timeouts{Read: 10 * time.Millisecond})
+		defer func() {
+			replicaErr = nil
+			app.models = data.NewReplicatedModels(replicas, data.Timeouts{})
+		}()
+
+		res := do(t, app, http.MethodGet, "/v1/genres", "")
+		if res.status == http.StatusOK {
+			t.Error("got status 200 for a query that timed out")
+		}
+		if got := log.take(); len(got) != 1 || got[0] == "primary" {
+			t.Fatalf("got queries on %q, want one on a replica and no retry on the primary", got)
+		}
+		for _, status := range replicas.Status() {
+			if !status.Healthy {
+				t.Errorf("replica %s was marked down after a timeout", status.Name)
+			}
+		}
+	})
+
+	t.Run("failing replicas", func(t *testing.T) {
+		replicaErr = driver.ErrBadConn
+		defer func() { replicaErr = nil }()
+
+		assertStatus(t, do(t, app, http.MethodGet, "/v1/genres", ""), http.StatusOK)
+		assertStatus(t, do(t, app, http.MethodGet, "/v1/genres", ""), http.StatusOK)
+		log.take()
+
+		for _, status := range replicas.Status() {
+			if status.Healthy {
+				t.Errorf("replica %s is still healthy", status.Name)
+			}
+		}
+		assertStatus(t, do(t, app, http.MethodGet, "/v1/genres", ""), http.StatusOK)
+		if got := log.take(); len(got) != 1 || got[0] != "primary" {
+			t.Fatalf("got queries on %q, want the primary", got)
+		}
+
+		replicaErr = nil
+		replicas.CheckHealth(context.Background())
+		assertStatus(t, do(t, app, http.MethodGet, "/v1/genres", ""), http.StatusOK)
+		if got := log.take(); len(got) != 1 || got[0] == "primary" {
+			t.Fatalf("got queries on %q, want a recovered replica", got)
+		}
+	})
+}
@@ -7,40 +7,102 @@ import (
 )
 
 func (a *applicationDependencies) routes() http.Handler {
-	router := httprouter.New()
+	router := a.router()
 
-	// Health check route
-	router.HandlerFunc(http.MethodGet, "/api/v1/healthcheck", a.healthCheckHandler)
+	// Wrap the entire router with global middleware
+	return a.requestID(a.resolveClientIP(a.enableCORS(a.recordMetrics(a.startTrace(a.logRequest(a.readYourWrites(a.recoverPanic(router))))))))
+}
 
-	// Books routes
-	router.HandlerFunc(http.MethodGet, "/v1/books", a.listBooksHandler)
-	router.HandlerFunc(http.MethodGet, "/api/v1/books/:id", a.getBookHandler)
-	router.HandlerFunc(http.MethodPost, "/v1/books", a.createBookHandler)
-	router.HandlerFunc(http.MethodPut, "/v1/books/:id", a.updateBookHandler)
-	router.HandlerFunc(http.MethodDelete, "/v1/books/:id", a.deleteBookHandler)
+// router registers the routes of the API.
+func (a *applicationDependencies) router() patternRouter {
+	limit := a.rateLimit(a.limiter)
+	router := patternRouter{Router: httprouter.New(), wrap: limit, routes: new([]string)}
+
+	// Requests not matching any route are limited by the global policy
+	router.NotFound = limit("", unmatchedRoute, a.notFoundResponse)
+	router.MethodNotAllowed = limit("", unmatchedRoute, a.methodNotAllowedResponse)
+
+	// Preflight requests are answered with the methods of the path
+	router.GlobalOPTIONS = http.HandlerFunc(a.preflightHandler)
+
+	// Paths of the first releases redirect to their /v1 equivalents
+	a.legacyRoutes(router)
+
+	a.v1Routes(router.apiVersion("v1"))
+
+	return router
+}
+
+// v1Routes registers the routes of version 1 of the API. A later version
+// gets a function of its own, registering the handlers it shares with v1
+// next to its new ones; the handlers check apiVersionFromContext where the
+// shape of their responses differs.
+func (a *applicationDependencies) v1Routes(v1 patternRouter) {
+	// Health check routes
+	v1.HandlerFunc(http.MethodGet, "/healthcheck", a.healthCheckHandler)
+	v1.HandlerFunc(http.MethodGet, "/healthcheck/live", a.liveHandler)
+	v1.HandlerFunc(http.MethodGet, "/healthcheck/ready", a.readyHandler)
 
-	router.HandlerFunc(http.MethodGet, "/api/v1/books", a.listBooksHandler)
+	// OpenAPI document, describing the routes below
+	v1.HandlerFunc(http.MethodGet, "/openapi.json", a.openAPIHandler)
+
+	// Books routes
+	v1.HandlerFunc(http.MethodGet, "/books", a.listBooksHandler)
+	v1.HandlerFunc(http.MethodGet, "/books/:id", a.getBookHandler)
+	v1.HandlerFunc(http.MethodPost, "/books", a.createBookHandler)
+	v1.HandlerFunc(http.MethodPut, "/books/:id", a.updateBookHandler)
+	v1.HandlerFunc(http.MethodDelete, "/books/:id", a.deleteBookHandler)
 
 	// Reading Lists routes
-	router.HandlerFunc(http.MethodGet, "/v1/lists", a.listReadingListsHandler)//change to search
-	router.HandlerFunc(http.MethodGet, "/v1/lists/:id", a.getReadingListHandler)
-	router.HandlerFunc(http.MethodPost, "/v1/lists", a.createReadingListHandler)
-	router.HandlerFunc(http.MethodPut, "/v1/lists/:id", a.updateReadingListHandler)
-	router.HandlerFunc(http.MethodDelete, "/v1/lists/:id", a.deleteReadingListHandler)
-	router.HandlerFunc(http.MethodPost, "/v1/lists/:id/books", a.addBookToReadingListHandler)
-	router.HandlerFunc(http.MethodDelete, "/v1/lists/:id/books", a.removeBookFromReadingListHandler)
+	v1.HandlerFunc(http.MethodGet, "/lists", a.listReadingListsHandler) //change to search
+	v1.HandlerFunc(http.MethodGet, "/lists/:id", a.getReadingListHandler)
+	v1.HandlerFunc(http.MethodPost, "/lists", a.createReadingListHandler)
+	v1.HandlerFunc(http.MethodPut, "/lists/:id", a.updateReadingListHandler)
+	v1.HandlerFunc(http.MethodDelete, "/lists/:id", a.deleteReadingListHandler)
+	v1.HandlerFunc(http.MethodPost, "/lists/:id/books", a.addBookToReadingListHandler)
+	v1.HandlerFunc(http.MethodDelete, "/lists/:id/books", a.removeBookFromReadingListHandler)
+	v1.HandlerFunc(http.MethodPut, "/lists/:id/books", a.reorderReadingListHandler)
 
 	// Reviews routes
-	router.HandlerFunc(http.MethodGet, "/v1/books/:id/reviews", a.listReviewsHandler)
-	router.HandlerFunc(http.MethodPost, "/v1/books/:id/reviews", a.createReviewHandler)
-	router.HandlerFunc(http.MethodPut, "/v1/reviews/:id", a.updateReviewHandler)
-	router.HandlerFunc(http.MethodDelete, "/v1/reviews/:id", a.deleteReviewHandler)
+	v1.HandlerFunc(http.MethodGet, "/books/:id/reviews", a.listReviewsHandler)
+	v1.HandlerFunc(http.MethodPost, "/books/:id/reviews", a.createReviewHandler)
+	v1.HandlerFunc(http.MethodGet, "/reviews/:id", a.getReviewHandler)
+	v1.HandlerFunc(http.MethodPut, "/reviews/:id", a.updateReviewHandler)
+	v1.HandlerFunc(http.MethodDelete, "/reviews/:id", a.deleteReviewHandler)
 
-	// Users routes
-	router.HandlerFunc(http.MethodGet, "/v1/users/:id", a.getUserProfileHandler)
-	router.HandlerFunc(http.MethodGet, "/v1/users/:id/lists", a.getUserReadingListsHandler)
-	router.HandlerFunc(http.MethodGet, "/v1/users/:id/reviews", a.getUserReviewsHandler)
+	// Works routes
+	v1.HandlerFunc(http.MethodGet, "/works/:id", a.getWorkHandler)
 
-	// Wrap the entire router with global middleware
-	return a.logRequest(a.rateLimit(a.recoverPanic(router)))
+	// Tags routes
+	v1.HandlerFunc(http.MethodGet, "/books/:id/tags", a.listBookTagsHandler)
+	v1.HandlerFunc(http.MethodPost, "/books/:id/tags", a.tagBookHandler)
+	v1.HandlerFunc(http.MethodDelete, "/books/:id/tags", a.untagBookHandler)
+	v1.HandlerFunc(http.MethodGet, "/tags/:name/books", a.listTagBooksHandler)
+
+	// Genres routes
+	v1.HandlerFunc(http.MethodGet, "/genres", a.listGenresHandler)
+	v1.HandlerFunc(http.MethodPost, "/genres", a.createGenreHandler)
+
+	// Series routes
+	v1.HandlerFunc(http.MethodGet, "/series", a.listSeriesHandler)
+	v1.HandlerFunc(http.MethodPost, "/series", a.createSeriesHandler)
+	v1.HandlerFunc(http.MethodGet, "/series/:id", a.getSeriesHandler)
+	v1.HandlerFunc(http.MethodPost, "/series/:id/entries", a.setSeriesEntryHandler)
+	v1.HandlerFunc(http.MethodDelete, "/series/:id/entries", a.removeSeriesEntryHandler)
+
+	// Authors routes
+	v1.HandlerFunc(http.MethodGet, "/authors", a.listAuthorsHandler)
+	v1.HandlerFunc(http.MethodPost, "/authors", a.createAuthorHandler)
+	v1.HandlerFunc(http.MethodGet, "/authors/:id", a.getAuthorHandler)
+	v1.HandlerFunc(http.MethodPut, "/authors/:id", a.updateAuthorHandler)
+	v1.HandlerFunc(http.MethodDelete, "/authors/:id", a.deleteAuthorHandler)
+	v1.HandlerFunc(http.MethodGet, "/authors/:id/books", a.listAuthorBooksHandler)
+	v1.HandlerFunc(http.MethodPost, "/authors/:id/merge", a.mergeAuthorsHandler)
+
+	// Users routes
+	v1.HandlerFunc(http.MethodGet, "/users/:id", a.getUserProfileHandler)
+	v1.HandlerFunc(http.MethodDelete, "/users/:id", a.deleteUserHandler)
+	v1.HandlerFunc(http.MethodGet, "/users/:id/lists", a.getUserReadingListsHandler)
+	v1.HandlerFunc(http.MethodGet, "/users/:id/reviews", a.getUserReviewsHandler)
+	v1.HandlerFunc(http.MethodGet, "/users/:id/series/next", a.getUserNextInSeriesHandler)
 }
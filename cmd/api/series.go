@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/RayMC17/bookclub-api/internal/data"
+	"github.com/RayMC17/bookclub-api/internal/validator"
+)
+
+// createSeriesInput is the body of POST /v1/series.
+type createSeriesInput struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func (a *applicationDependencies) createSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	var input createSeriesInput
+	err := a.readJSON(w, r, &input)
+	if err != nil {
+		a.badRequestResponse(w, r, err)
+		return
+	}
+
+	series := &data.Series{
+		Name:        input.Name,
+		Description: input.Description,
+	}
+
+	v := validator.New()
+	data.ValidateSeries(v, series)
+	if !v.Valid() {
+		a.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = a.models.Series.Insert(r.Context(), series)
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/series/%d", series.ID))
+	err = a.writeJSON(w, http.StatusCreated, envelope{"series": series}, headers)
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+func (a *applicationDependencies) listSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	var queryParams struct {
+		Name string
+		data.Filters
+	}
+
+	queryParams.Name = a.getSingleQueryParameter(r.URL.Query(), "name", "")
+
+	v := validator.New()
+	queryParams.Filters.Page = a.getSingleIntegerParameter(r.URL.Query(), "page", 1, v)
+	queryParams.Filters.PageSize = a.getSingleIntegerParameter(r.URL.Query(), "page_size", 10, v)
+	queryParams.Filters.Sort = a.getSingleQueryParameter(r.URL.Query(), "sort", "name")
+	queryParams.Filters.SortSafelist = []string{"id", "name", "-id", "-name"}
+
+	data.ValidateFilters(v, &queryParams.Filters)
+	if !v.Valid() {
+		a.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	series, metadata, err := a.models.Series.GetAll(r.Context(), queryParams.Name, queryParams.Filters)
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+		return
+	}
+
+	response := envelope{
+		"series":   series,
+		"metadata": metadata,
+	}
+	err = a.writeTaggedJSON(w, r, http.StatusOK, response, nil, time.Time{})
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+// getSeriesHandler returns a series with its entries in reading order, each
+// entry listing the editions of its work.
+func (a *applicationDependencies) getSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := a.readIDParam(r)
+	if err != nil {
+		a.notFoundResponse(w, r)
+		return
+	}
+
+	series, err := getSeriesWithEditions(r.Context(), a.models, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			a.notFoundResponse(w, r)
+		default:
+			a.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = a.writeTaggedJSON(w, r, http.StatusOK, envelope{"series": series}, nil, time.Time{})
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+// getSeriesWithEditions reads a series as GET /v1/series/:id shows it, each
+// entry listing the editions of its work.
+func getSeriesWithEditions(ctx context.Context, models data.Models, id int) (*data.Series, error) {
+	series, err := models.Series.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	workIDs := make([]int, len(series.Entries))
+	for i, entry := range series.Entries {
+		workIDs[i] = entry.WorkID
+	}
+	editions, err := models.Books.GetAllByWorks(ctx, workIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range series.Entries {
+		entry.Books = editions[entry.WorkID]
+	}
+
+	return series, nil
+}
+
+// seriesEntryInput is the body of POST /v1/series/:id/entries.
+type seriesEntryInput struct {
+	WorkID   int     `json:"work_id"`
+	Position float64 `json:"position"`
+}
+
+// setSeriesEntryHandler adds a work to a series or moves it to a new position.
+func (a *applicationDependencies) setSeriesEntryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := a.readIDParam(r)
+	if err != nil {
+		a.notFoundResponse(w, r)
+		return
+	}
+
+	_, err = a.models.Series.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			a.notFoundResponse(w, r)
+		default:
+			a.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input seriesEntryInput
+	err = a.readJSON(w, r, &input)
+	if err != nil {
+		a.badRequestResponse(w, r, err)
+		return
+	}
+
+	entry := &data.SeriesEntry{
+		WorkID:   input.WorkID,
+		Position: input.Position,
+	}
+
+	v := validator.New()
+	data.ValidateSeriesEntry(v, entry)
+	if !v.Valid() {
+		a.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = a.models.Series.SetEntry(r.Context(), id, entry)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrUnknownWork):
+			v.AddError("work_id", "must reference an existing work")
+			a.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrDuplicatePosition):
+			v.AddError("position", "is already taken by another entry in this series")
+			a.failedValidationResponse(w, r, v.Errors)
+		default:
+			a.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	series, err := a.models.Series.Get(r.Context(), id)
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = a.writeJSON(w, http.StatusOK, envelope{"series": series}, nil)
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+// removeSeriesEntryInput is the body of DELETE /v1/series/:id/entries.
+type removeSeriesEntryInput struct {
+	WorkID int `json:"work_id"`
+}
+
+func (a *applicationDependencies) removeSeriesEntryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := a.readIDParam(r)
+	if err != nil {
+		a.notFoundResponse(w, r)
+		return
+	}
+
+	series, err := getSeriesWithEditions(r.Context(), a.models, id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			a.notFoundResponse(w, r)
+		default:
+			a.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	if !a.checkIfMatch(w, r, envelope{"series": series}) {
+		return
+	}
+
+	var input removeSeriesEntryInput
+	err = a.readJSON(w, r, &input)
+	if err != nil {
+		a.badRequestResponse(w, r, err)
+		return
+	}
+
+	err = a.models.WithTx(r.Context(), func(tx data.Models) error {
+		current, err := getSeriesWithEditions(r.Context(), tx, id)
+		if err != nil {
+			return err
+		}
+		err = ifMatch(r, envelope{"series": current})
+		if err != nil {
+			return err
+		}
+		return tx.Series.RemoveEntry(r.Context(), id, input.WorkID)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			a.notFoundResponse(w, r)
+		case errors.Is(err, errPreconditionFailed):
+			a.preconditionFailedResponse(w, r)
+		default:
+			a.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = a.writeJSON(w, http.StatusOK, envelope{"message": "work successfully removed from the series"}, nil)
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+// getUserNextInSeriesHandler answers "which one comes next?" for every series
+// the user has started reading.
+func (a *applicationDependencies) getUserNextInSeriesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := a.readIDParam(r)
+	if err != nil {
+		a.notFoundResponse(w, r)
+		return
+	}
+
+	next, err := a.models.Series.GetNextForUser(r.Context(), int64(id))
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+		return
+	}
+
+	workIDs := make([]int, len(next))
+	for i, entry := range next {
+		workIDs[i] = entry.WorkID
+	}
+	editions, err := a.models.Books.GetAllByWorks(r.Context(), workIDs)
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+		return
+	}
+	for _, entry := range next {
+		entry.Books = editions[entry.WorkID]
+	}
+
+	err = a.writeJSON(w, http.StatusOK, envelope{"next_in_series": next}, nil)
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
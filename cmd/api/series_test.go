@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/RayMC17/bookclub-api/internal/data"
+)
+
+func insertTestSeries(t *testing.T, store *data.InMemory, name string, works ...int) *data.Series {
+	t.Helper()
+	series := &data.Series{Name: name}
+	err := store.Series.Insert(context.Background(), series)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, workID := range works {
+		err := store.Series.SetEntry(context.Background(), series.ID, &data.SeriesEntry{WorkID: workID, Position: float64(i + 1)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return series
+}
+
+func TestCreateSeriesHandler(t *testing.T) {
+	app, _ := newTestApplication(t)
+
+	res := do(t, app, http.MethodPost, "/v1/series", `{"name": "Dune Chronicles", "description": "Arrakis."}`)
+	assertStatus(t, res, http.StatusCreated)
+	if want := fmt.Sprintf("/v1/series/%v", res.field("series", "id")); res.header.Get("Location") != want {
+		t.Errorf("got Location %q, want %q", res.header.Get("Location"), want)
+	}
+
+	res = do(t, app, http.MethodPost, "/v1/series", `{"name": " "}`)
+	assertStatus(t, res, http.StatusUnprocessableEntity)
+	if res.field("error", "name") == nil {
+		t.Errorf("want a validation error for name, got %v", res.body)
+	}
+}
+
+func TestSeriesEntryHandlers(t *testing.T) {
+	app, store := newTestApplication(t)
+	dune := insertTestBook(t, store, "Dune", "Frank Herbert")
+	messiah := insertTestBook(t, store, "Dune Messiah", "Frank Herbert")
+	series := insertTestSeries(t, store, "Dune Chronicles")
+
+	path := fmt.Sprintf("/v1/series/%d/entries", series.ID)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"first", fmt.Sprintf(`{"work_id": %d, "position": 1}`, dune.WorkID), http.StatusOK},
+		{"second", fmt.Sprintf(`{"work_id": %d, "position": 2}`, messiah.WorkID), http.StatusOK},
+		{"taken position", fmt.Sprintf(`{"work_id": %d, "position": 1}`, messiah.WorkID), http.StatusUnprocessableEntity},
+		{"unknown work", `{"work_id": 999, "position": 3}`, http.StatusUnprocessableEntity},
+		{"three decimals", fmt.Sprintf(`{"work_id": %d, "position": 1.125}`, messiah.WorkID), http.StatusUnprocessableEntity},
+		{"moved", fmt.Sprintf(`{"work_id": %d, "position": 0.5}`, messiah.WorkID), http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := do(t, app, http.MethodPost, path, tt.body)
+			assertStatus(t, res, tt.wantStatus)
+		})
+	}
+
+	res := do(t, app, http.MethodPost, "/v1/series/999/entries", fmt.Sprintf(`{"work_id": %d, "position": 1}`, dune.WorkID))
+	assertStatus(t, res, http.StatusNotFound)
+
+	// Entries come back in reading order with the editions of their works
+	res = do(t, app, http.MethodGet, fmt.Sprintf("/v1/series/%d", series.ID), "")
+	assertStatus(t, res, http.StatusOK)
+	entries, _ := res.field("series", "entries").([]any)
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	first := entries[0].(map[string]any)
+	if first["title"] != "Dune Messiah" {
+		t.Errorf("got %v first, want Dune Messiah", first["title"])
+	}
+	if books, _ := first["books"].([]any); len(books) != 1 {
+		t.Errorf("got %d editions of the first entry, want 1", len(books))
+	}
+
+	// Books show the series they belong to
+	res = do(t, app, http.MethodGet, fmt.Sprintf("/v1/books/%d", dune.ID), "")
+	assertStatus(t, res, http.StatusOK)
+	if got := fmt.Sprint(res.field("book", "series")); got != fmt.Sprintf("[map[name:Dune Chronicles position:1 series_id:%d]]", series.ID) {
+		t.Errorf("got series %s", got)
+	}
+
+	body := fmt.Sprintf(`{"work_id": %d}`, dune.WorkID)
+	seriesPath := fmt.Sprintf("/v1/series/%d", series.ID)
+	res = doIfMatch(t, app, http.MethodDelete, path, body, seriesPath)
+	assertStatus(t, res, http.StatusOK)
+	res = doIfMatch(t, app, http.MethodDelete, path, body, seriesPath)
+	assertStatus(t, res, http.StatusNotFound)
+}
+
+func TestListSeriesHandler(t *testing.T) {
+	app, store := newTestApplication(t)
+	insertTestSeries(t, store, "Earthsea")
+	insertTestSeries(t, store, "Dune Chronicles")
+
+	res := do(t, app, http.MethodGet, "/v1/series", "")
+	assertStatus(t, res, http.StatusOK)
+	series, _ := res.field("series").([]any)
+	if len(series) != 2 {
+		t.Fatalf("got %d series, want 2", len(series))
+	}
+	if got := series[0].(map[string]any)["name"]; got != "Dune Chronicles" {
+		t.Errorf("got %v first, want Dune Chronicles", got)
+	}
+
+	res = do(t, app, http.MethodGet, "/v1/series?name=sea", "")
+	assertStatus(t, res, http.StatusOK)
+	if series, _ := res.field("series").([]any); len(series) != 1 {
+		t.Errorf("got %d series named like sea, want 1", len(series))
+	}
+}
+
+func TestGetUserNextInSeriesHandler(t *testing.T) {
+	app, store := newTestApplication(t)
+	alice := insertTestUser(t, store, "alice")
+	dune := insertTestBook(t, store, "Dune", "Frank Herbert")
+	messiah := insertTestBook(t, store, "Dune Messiah", "Frank Herbert")
+	children := insertTestBook(t, store, "Children of Dune", "Frank Herbert")
+	series := insertTestSeries(t, store, "Dune Chronicles", dune.WorkID, messiah.WorkID, children.WorkID)
+	insertTestSeries(t, store, "Unstarted", insertTestBook(t, store, "Earthsea", "Ursula K. Le Guin").WorkID)
+
+	reading := insertTestReadingList(t, store, "Nightstand", alice.ID)
+	completed := &data.ReadingList{Name: "Read", CreatedBy: alice.ID, Status: "completed"}
+	err := store.ReadingLists.Insert(context.Background(), completed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := fmt.Sprintf("/v1/users/%d/series/next", alice.ID)
+	next := func() []any {
+		t.Helper()
+		res := do(t, app, http.MethodGet, path, "")
+		assertStatus(t, res, http.StatusOK)
+		entries, _ := res.field("next_in_series").([]any)
+		return entries
+	}
+	add := func(list *data.ReadingList, book *data.Book) {
+		t.Helper()
+		err := store.ReadingLists.AddBook(context.Background(), list.ID, book.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Books that are only being read don't start a series
+	add(reading, dune)
+	if entries := next(); len(entries) != 0 {
+		t.Fatalf("got %v before finishing a book, want nothing", entries)
+	}
+
+	// Each finished book moves on to the next entry, until the series is done
+	for i, book := range []*data.Book{dune, messiah, children} {
+		add(completed, book)
+		entries := next()
+
+		if i == 2 {
+			if len(entries) != 0 {
+				t.Errorf("got %v after finishing the series, want nothing", entries)
+			}
+			break
+		}
+		if len(entries) != 1 {
+			t.Fatalf("got %d entries after finishing %s, want 1", len(entries), book.Title)
+		}
+		entry := entries[0].(map[string]any)
+		if entry["series_id"] != float64(series.ID) || entry["position"] != float64(i+2) {
+			t.Errorf("after finishing %s got %v at position %v, want position %d", book.Title, entry["title"], entry["position"], i+2)
+		}
+		if books, _ := entry["books"].([]any); len(books) != 1 {
+			t.Errorf("got %d editions of the next entry, want 1", len(books))
+		}
+	}
+}
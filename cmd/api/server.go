@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 )
@@ -23,6 +24,21 @@ func (a *applicationDependencies) serve() error {
 		ErrorLog:     slog.NewLogLogger(a.logger.Handler(), slog.LevelError),
 	}
 
+	// Serve metrics on the admin address, away from the public API. The
+	// server failing shuts the application down like a signal would.
+	var adminServer *http.Server
+	adminError := make(chan error, 1)
+	if a.config.adminAddr != "" {
+		adminServer = a.adminServer()
+		go func() {
+			a.logger.Info("starting admin server", "address", adminServer.Addr)
+			err := adminServer.ListenAndServe()
+			if !errors.Is(err, http.ErrServerClosed) {
+				adminError <- fmt.Errorf("admin server: %w", err)
+			}
+		}()
+	}
+
 	// Channel to capture shutdown errors
 	shutdownError := make(chan error)
 
@@ -31,17 +47,18 @@ func (a *applicationDependencies) serve() error {
 		// Create a channel to listen for interrupt/terminate signals
 		quit := make(chan os.Signal, 1)
 		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-		s := <-quit // Block until a signal is received
-
-		// Log the shutdown signal
-		a.logger.Info("shutting down server", "signal", s.String())
-
-		// Create a context with a timeout for the shutdown process
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		defer cancel()
+		defer signal.Stop(quit)
 
-		// Attempt to gracefully shutdown the server
-		shutdownError <- apiServer.Shutdown(ctx)
+		// Block until a signal is received or the admin server fails
+		select {
+		case s := <-quit:
+			a.logger.Info("shutting down server", "signal", s.String())
+			shutdownError <- a.shutdown(apiServer, adminServer)
+		case err := <-adminError:
+			a.logger.Error(err.Error())
+			a.logger.Info("shutting down server")
+			shutdownError <- errors.Join(err, a.shutdown(apiServer, adminServer))
+		}
 	}()
 
 	// Start the server
@@ -63,3 +80,90 @@ func (a *applicationDependencies) serve() error {
 	a.logger.Info("stopped server", "address", apiServer.Addr)
 	return nil
 }
+
+// shutdown fails the readiness probe and keeps serving for the drain delay,
+// so that load balancers stop sending new requests before the listeners
+// close. It then shuts the servers down, letting in-flight requests complete,
+// and stops the background tasks, all within the shutdown timeout. Nil
+// servers are skipped.
+func (a *applicationDependencies) shutdown(servers ...*http.Server) error {
+	// Fail the readiness probe so that no new traffic is sent our way, and
+	// keep serving while the load balancers notice
+	a.shuttingDown.Store(true)
+	if a.config.shutdownDelay > 0 {
+		a.logger.Info("draining traffic", "delay", a.config.shutdownDelay.String())
+		time.Sleep(a.config.shutdownDelay)
+	}
+
+	// Create a context with a timeout for the shutdown process
+	ctx, cancel := context.WithTimeout(context.Background(), a.config.shutdownTimeout)
+	defer cancel()
+
+	var err error
+	for _, server := range servers {
+		if server != nil {
+			err = errors.Join(err, server.Shutdown(ctx))
+		}
+	}
+
+	// Then stop the background tasks within the same deadline
+	a.logger.Info("completing background tasks")
+	return errors.Join(err, a.tasks.stop(ctx))
+}
+
+// backgroundTasks tracks the goroutines started with background, so that
+// shutdown can tell them to stop and wait for them. The zero value is ready
+// to use.
+type backgroundTasks struct {
+	wg     sync.WaitGroup
+	once   sync.Once
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func (b *backgroundTasks) init() {
+	b.once.Do(func() {
+		b.ctx, b.cancel = context.WithCancel(context.Background())
+	})
+}
+
+// stop cancels the context of the background tasks and waits for them to
+// return, giving up when ctx is done.
+func (b *backgroundTasks) stop(ctx context.Context) error {
+	b.init()
+	b.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for background tasks: %w", ctx.Err())
+	}
+}
+
+// background runs fn in a goroutine tracked by the application. The context
+// passed to fn is canceled when the server shuts down, and fn should return
+// promptly once it is. A panic in fn is logged rather than crashing the
+// process.
+func (a *applicationDependencies) background(fn func(ctx context.Context)) {
+	a.tasks.init()
+	a.tasks.wg.Add(1)
+
+	go func() {
+		defer a.tasks.wg.Done()
+		defer func() {
+			if err := recover(); err != nil {
+				a.metrics.panics.Inc()
+				a.logger.Error("background task panicked", "error", fmt.Sprintf("%v", err))
+			}
+		}()
+
+		fn(a.tasks.ctx)
+	}()
+}
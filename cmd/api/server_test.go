@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackgroundTasks(t *testing.T) {
+	t.Run("stopped on shutdown", func(t *testing.T) {
+		app, _ := newTestApplication(t)
+
+		stopped := make(chan struct{})
+		app.background(func(ctx context.Context) {
+			<-ctx.Done()
+			close(stopped)
+		})
+		app.background(func(ctx context.Context) {
+			panic("boom")
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		err := app.tasks.stop(ctx)
+		if err != nil {
+			t.Fatalf("got error %v stopping the background tasks", err)
+		}
+
+		select {
+		case <-stopped:
+		default:
+			t.Error("stop returned before the task did")
+		}
+	})
+
+	t.Run("deadline exceeded", func(t *testing.T) {
+		app, _ := newTestApplication(t)
+
+		release := make(chan struct{})
+		defer close(release)
+		app.background(func(ctx context.Context) {
+			<-release
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		err := app.tasks.stop(ctx)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("got error %v, want %v", err, context.DeadlineExceeded)
+		}
+	})
+}
+
+func TestShutdownDrainDelay(t *testing.T) {
+	app, _ := newTestApplication(t)
+	app.config.shutdownDelay = 200 * time.Millisecond
+	app.config.shutdownTimeout = time.Second
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{Handler: app.routes()}
+	go server.Serve(ln)
+	url := "http://" + ln.Addr().String() + "/v1/healthcheck/ready"
+
+	done := make(chan error, 1)
+	go func() {
+		done <- app.shutdown(server)
+	}()
+	for !app.shuttingDown.Load() {
+		time.Sleep(time.Millisecond)
+	}
+
+	// The server still answers during the delay, failing the readiness probe
+	res, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("request during the drain delay: %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("got status %d during the drain delay, want %d", res.StatusCode, http.StatusServiceUnavailable)
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("shutdown returned %v before the drain delay was over", err)
+	default:
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("got error %v shutting down", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("shutdown didn't return")
+	}
+	if _, err := http.Get(url); err == nil {
+		t.Error("the server still accepts connections after shutdown")
+	}
+}
+
+func TestServeAdminServerFailure(t *testing.T) {
+	// Hold the admin address so that the admin server can't listen on it
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer taken.Close()
+	free, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := free.Addr().(*net.TCPAddr).Port
+	free.Close()
+
+	app, _ := newTestApplication(t)
+	app.config.port = port
+	app.config.adminAddr = taken.Addr().String()
+	app.config.shutdownTimeout = time.Second
+
+	stopped := make(chan struct{})
+	app.background(func(ctx context.Context) {
+		<-ctx.Done()
+		close(stopped)
+	})
+
+	done := make(chan error, 1)
+	go func() {
+		done <- app.serve()
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "admin server") {
+			t.Fatalf("got error %v, want the admin server's", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("serve didn't return after the admin server failed")
+	}
+
+	// The failure went through the graceful shutdown
+	select {
+	case <-stopped:
+	default:
+		t.Error("the background tasks weren't stopped")
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/RayMC17/bookclub-api/internal/data"
+	"github.com/RayMC17/bookclub-api/internal/validator"
+	"github.com/julienschmidt/httprouter"
+)
+
+// bookTagInput is the body of POST and DELETE /v1/books/:id/tags.
+type bookTagInput struct {
+	UserID int    `json:"user_id"`
+	Tag    string `json:"tag"`
+}
+
+// tagBookHandler applies a user's personal tag to a book.
+func (a *applicationDependencies) tagBookHandler(w http.ResponseWriter, r *http.Request) {
+	bookID, err := a.readIDParam(r)
+	if err != nil {
+		a.notFoundResponse(w, r)
+		return
+	}
+
+	var input bookTagInput
+	err = a.readJSON(w, r, &input)
+	if err != nil {
+		a.badRequestResponse(w, r, err)
+		return
+	}
+
+	tag := data.NormalizeTagName(input.Tag)
+
+	v := validator.New()
+	v.Check(input.UserID > 0, "user_id", "must be provided")
+	data.ValidateTagName(v, tag)
+	if !v.Valid() {
+		a.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = a.models.Tags.Add(r.Context(), input.UserID, bookID, tag)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			a.notFoundResponse(w, r)
+		default:
+			a.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = a.writeJSON(w, http.StatusOK, envelope{"message": "book successfully tagged", "tag": tag}, nil)
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+// untagBookHandler removes a user's personal tag from a book.
+func (a *applicationDependencies) untagBookHandler(w http.ResponseWriter, r *http.Request) {
+	bookID, err := a.readIDParam(r)
+	if err != nil {
+		a.notFoundResponse(w, r)
+		return
+	}
+
+	_, err = a.models.Books.Get(r.Context(), bookID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			a.notFoundResponse(w, r)
+		default:
+			a.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+	tags, err := a.models.Tags.GetCountsForBook(r.Context(), bookID)
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+		return
+	}
+	if !a.checkIfMatch(w, r, envelope{"tags": tags}) {
+		return
+	}
+
+	var input bookTagInput
+	err = a.readJSON(w, r, &input)
+	if err != nil {
+		a.badRequestResponse(w, r, err)
+		return
+	}
+
+	tag := data.NormalizeTagName(input.Tag)
+
+	v := validator.New()
+	v.Check(input.UserID > 0, "user_id", "must be provided")
+	data.ValidateTagName(v, tag)
+	if !v.Valid() {
+		a.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = a.models.WithTx(r.Context(), func(tx data.Models) error {
+		current, err := tx.Tags.GetCountsForBook(r.Context(), bookID)
+		if err != nil {
+			return err
+		}
+		err = ifMatch(r, envelope{"tags": current})
+		if err != nil {
+			return err
+		}
+		return tx.Tags.Remove(r.Context(), input.UserID, bookID, tag)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			a.notFoundResponse(w, r)
+		case errors.Is(err, errPreconditionFailed):
+			a.preconditionFailedResponse(w, r)
+		default:
+			a.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = a.writeJSON(w, http.StatusOK, envelope{"message": "tag successfully removed from the book"}, nil)
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+// listBookTagsHandler returns the tags applied to a book with how many users
+// applied each of them.
+func (a *applicationDependencies) listBookTagsHandler(w http.ResponseWriter, r *http.Request) {
+	bookID, err := a.readIDParam(r)
+	if err != nil {
+		a.notFoundResponse(w, r)
+		return
+	}
+
+	_, err = a.models.Books.Get(r.Context(), bookID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			a.notFoundResponse(w, r)
+		default:
+			a.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	tags, err := a.models.Tags.GetCountsForBook(r.Context(), bookID)
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = a.writeTaggedJSON(w, r, http.StatusOK, envelope{"tags": tags}, nil, time.Time{})
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
+
+// listTagBooksHandler ranks the books a tag was applied to by how many users
+// applied it.
+func (a *applicationDependencies) listTagBooksHandler(w http.ResponseWriter, r *http.Request) {
+	params := httprouter.ParamsFromContext(r.Context())
+	tag := data.NormalizeTagName(params.ByName("name"))
+
+	var filters data.Filters
+	v := validator.New()
+	data.ValidateTagName(v, tag)
+
+	filters.Page = a.getSingleIntegerParameter(r.URL.Query(), "page", 1, v)
+	filters.PageSize = a.getSingleIntegerParameter(r.URL.Query(), "page_size", 10, v)
+	filters.Sort = a.getSingleQueryParameter(r.URL.Query(), "sort", "-tag_count")
+	filters.SortSafelist = []string{"tag_count", "title", "-tag_count", "-title"}
+
+	data.ValidateFilters(v, &filters)
+	if !v.Valid() {
+		a.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	books, metadata, err := a.models.Books.GetAllByTag(r.Context(), tag, filters)
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+		return
+	}
+
+	response := envelope{
+		"tag":      tag,
+		"books":    books,
+		"metadata": metadata,
+	}
+	err = a.writeTaggedJSON(w, r, http.StatusOK, response, nil, time.Time{})
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestTagHandlers(t *testing.T) {
+	app, store := newTestApplication(t)
+	alice := insertTestUser(t, store, "alice")
+	bob := insertTestUser(t, store, "bob")
+	dune := insertTestBook(t, store, "Dune", "Frank Herbert")
+	earthsea := insertTestBook(t, store, "A Wizard of Earthsea", "Ursula K. Le Guin")
+
+	tag := func(userID, bookID int, name string) testResponse {
+		t.Helper()
+		return do(t, app, http.MethodPost, fmt.Sprintf("/v1/books/%d/tags", bookID), fmt.Sprintf(`{"user_id": %d, "tag": %q}`, userID, name))
+	}
+
+	res := tag(alice.ID, dune.ID, "  Beach   READ ")
+	assertStatus(t, res, http.StatusOK)
+	if got := res.field("tag"); got != "beach read" {
+		t.Errorf("got tag %v, want the normalized %q", got, "beach read")
+	}
+	assertStatus(t, tag(bob.ID, dune.ID, "beach read"), http.StatusOK)
+	assertStatus(t, tag(bob.ID, dune.ID, "classic"), http.StatusOK)
+	assertStatus(t, tag(alice.ID, earthsea.ID, "beach read"), http.StatusOK)
+
+	assertStatus(t, tag(999, dune.ID, "classic"), http.StatusNotFound)
+	assertStatus(t, tag(alice.ID, 999, "classic"), http.StatusNotFound)
+	assertStatus(t, tag(alice.ID, dune.ID, " "), http.StatusUnprocessableEntity)
+
+	res = do(t, app, http.MethodGet, fmt.Sprintf("/v1/books/%d/tags", dune.ID), "")
+	assertStatus(t, res, http.StatusOK)
+	if got := fmt.Sprint(res.field("tags")); got != "[map[count:2 name:beach read] map[count:1 name:classic]]" {
+		t.Errorf("got tags %s", got)
+	}
+
+	res = do(t, app, http.MethodGet, "/v1/tags/Beach%20Read/books", "")
+	assertStatus(t, res, http.StatusOK)
+	books, _ := res.field("books").([]any)
+	if len(books) != 2 {
+		t.Fatalf("got %d books, want 2", len(books))
+	}
+	if first := books[0].(map[string]any); first["title"] != "Dune" || first["tag_count"] != 2.0 {
+		t.Errorf("got %v (%v) first, want Dune tagged twice", first["title"], first["tag_count"])
+	}
+
+	path := fmt.Sprintf("/v1/books/%d/tags", dune.ID)
+	body := fmt.Sprintf(`{"user_id": %d, "tag": "Classic"}`, bob.ID)
+	assertStatus(t, doIfMatch(t, app, http.MethodDelete, path, body, path), http.StatusOK)
+	assertStatus(t, doIfMatch(t, app, http.MethodDelete, path, body, path), http.StatusNotFound)
+}
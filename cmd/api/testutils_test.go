@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RayMC17/bookclub-api/internal/data"
+)
+
+// newTestApplication returns an application backed by the in-memory
+// repositories, with logging discarded and the rate limiter disabled.
+func newTestApplication(t *testing.T) (*applicationDependencies, *data.InMemory) {
+	t.Helper()
+
+	store := data.NewInMemory()
+	app := &applicationDependencies{
+		logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		models:  store.Models(),
+		metrics: newAppMetrics(nil),
+	}
+	app.limiter = app.newRateLimiter()
+	return app, store
+}
+
+// useRateLimiter rebuilds the application's rate limiter after a test has
+// changed its settings, stopping its background task when the test ends.
+func useRateLimiter(t *testing.T, app *applicationDependencies) {
+	t.Helper()
+
+	app.limiter = app.newRateLimiter()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if err := app.tasks.stop(ctx); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+// testResponse is a recorded response with its JSON body decoded.
+type testResponse struct {
+	status int
+	header http.Header
+	body   map[string]any
+}
+
+// do sends a request through the application's routes.
+func do(t *testing.T, app *applicationDependencies, method, path, body string) testResponse {
+	t.Helper()
+	return serve(t, app.routes(), method, path, body)
+}
+
+// doIfMatch sends a request through the application's routes with If-Match
+// set to the ETag of the current representation of resource, if it has one.
+func doIfMatch(t *testing.T, app *applicationDependencies, method, path, body, resource string) testResponse {
+	t.Helper()
+
+	etag := do(t, app, http.MethodGet, resource, "").header.Get("ETag")
+	r, w := newRecordedRequest(method, path, body)
+	if etag != "" {
+		r.Header.Set("If-Match", etag)
+	}
+	app.routes().ServeHTTP(w, r)
+	return decodeResponse(t, w)
+}
+
+// serve sends a request to handler and decodes the JSON response, if any.
+func serve(t *testing.T, handler http.Handler, method, path, body string) testResponse {
+	t.Helper()
+
+	r, w := newRecordedRequest(method, path, body)
+	handler.ServeHTTP(w, r)
+	return decodeResponse(t, w)
+}
+
+// decodeResponse decodes the JSON response recorded by w, if any.
+func decodeResponse(t *testing.T, w *httptest.ResponseRecorder) testResponse {
+	t.Helper()
+
+	res := testResponse{status: w.Code, header: w.Header()}
+	if w.Body.Len() > 0 {
+		err := json.Unmarshal(w.Body.Bytes(), &res.body)
+		if err != nil {
+			t.Fatalf("decoding response %q: %v", w.Body.String(), err)
+		}
+	}
+	return res
+}
+
+// newRecordedRequest returns a request from a fixed client address and a
+// recorder for its response.
+func newRecordedRequest(method, path, body string) (*http.Request, *httptest.ResponseRecorder) {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	r := httptest.NewRequest(method, path, reader)
+	r.RemoteAddr = "192.0.2.1:1234"
+	return r, httptest.NewRecorder()
+}
+
+// field walks the decoded JSON body along the given keys.
+func (res testResponse) field(keys ...string) any {
+	var value any = res.body
+	for _, key := range keys {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+	return value
+}
+
+func assertStatus(t *testing.T, res testResponse, want int) {
+	t.Helper()
+	if res.status != want {
+		t.Fatalf("got status %d, want %d (body: %v)", res.status, want, res.body)
+	}
+}
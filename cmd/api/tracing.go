@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/RayMC17/bookclub-api/internal/trace"
+)
+
+// newTracer returns the tracer selected by the -trace-* flags, or nil when
+// tracing is off.
+func newTracer(settings serverConfig, onError func(error)) (*trace.Tracer, error) {
+	if settings.trace.sampleRate < 0 || settings.trace.sampleRate > 1 {
+		return nil, fmt.Errorf("invalid -trace-sample-rate %v: must be between 0 and 1", settings.trace.sampleRate)
+	}
+
+	var exporter trace.Exporter
+	switch settings.trace.exporter {
+	case "none":
+		return nil, nil
+	case "stdout":
+		exporter = trace.NewWriterExporter(os.Stdout)
+	case "otlp":
+		exporter = trace.NewOTLPExporter(settings.trace.otlpEndpoint, "bookclub-api")
+	default:
+		return nil, fmt.Errorf("invalid -trace-exporter %q: must be none, stdout or otlp", settings.trace.exporter)
+	}
+
+	return trace.NewTracer(exporter, settings.trace.sampleRate, onError), nil
+}
+
+// Middleware: Tracing
+//
+// startTrace starts the server span of a request, continuing the trace of the
+// caller when the request has a W3C traceparent header. The span is renamed
+// after the matched route once the request has been handled.
+func (a *applicationDependencies) startTrace(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := a.tracer.StartServer(r.Context(), r.Method, r.Header.Get("traceparent"))
+		if span == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		defer span.End()
+
+		rw := newResponseWriter(w)
+		next.ServeHTTP(rw, r.WithContext(ctx))
+
+		span.SetAttributes(
+			"http.request.method", r.Method,
+			"url.path", r.URL.Path,
+			"http.response.status_code", rw.status,
+			"client.address", clientIPFromContext(r),
+			"request_id", requestIDFromContext(r),
+		)
+		if route := routeInfoFromContext(r); route != nil && route.pattern != unmatchedRoute {
+			span.SetName(r.Method + " " + route.pattern)
+			span.SetAttributes("http.route", route.pattern)
+		}
+		if rw.status >= http.StatusInternalServerError {
+			span.RecordError(fmt.Errorf("%d %s", rw.status, strings.ToLower(http.StatusText(rw.status))))
+		}
+	})
+}
+
+// traceHandler wraps a route's handler in a span named after the route.
+func traceHandler(method, pattern string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := trace.Start(r.Context(), "handler "+method+" "+pattern, trace.KindInternal)
+		defer span.End()
+		handler(w, r.WithContext(ctx))
+	}
+}
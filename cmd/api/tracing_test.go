@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/RayMC17/bookclub-api/internal/trace"
+)
+
+// recordingExporter keeps the exported spans in memory.
+type recordingExporter struct {
+	mu    sync.Mutex
+	spans []*trace.SpanData
+}
+
+func (e *recordingExporter) Export(ctx context.Context, spans []*trace.SpanData) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+func (e *recordingExporter) Shutdown(ctx context.Context) error { return nil }
+
+func (e *recordingExporter) byName() map[string]*trace.SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	spans := make(map[string]*trace.SpanData)
+	for _, span := range e.spans {
+		spans[span.Name] = span
+	}
+	return spans
+}
+
+func TestTracing(t *testing.T) {
+	app, store := newTestApplication(t)
+	book := insertTestBook(t, store, "Dune", "Frank Herbert")
+	path := fmt.Sprintf("/v1/books/%d", book.ID)
+
+	exporter := &recordingExporter{}
+	app.tracer = trace.NewTracer(exporter, 1, func(err error) { t.Error(err) })
+	app.config.limiter.enabled = true
+	app.config.limiter.rps = 100
+	app.config.limiter.burst = 100
+	useRateLimiter(t, app)
+
+	t.Run("continues the caller's trace", func(t *testing.T) {
+		r, w := newRecordedRequest(http.MethodGet, path, "")
+		r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		app.routes().ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("got status %d", w.Code)
+		}
+		err := app.tracer.ForceFlush(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		spans := exporter.byName()
+		server, handler, limiter := spans["GET /v1/books/:id"], spans["handler GET /v1/books/:id"], spans["rateLimit"]
+		if server == nil || handler == nil || limiter == nil {
+			t.Fatalf("missing spans, got %v", spans)
+		}
+		if server.SpanContext.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || server.ParentSpanID.String() != "00f067aa0ba902b7" {
+			t.Errorf("server span didn't continue the trace: %+v", server.SpanContext)
+		}
+		if server.Kind != trace.KindServer {
+			t.Errorf("got server span kind %v", server.Kind)
+		}
+		for _, child := range []*trace.SpanData{handler, limiter} {
+			if child.SpanContext.TraceID != server.SpanContext.TraceID || child.ParentSpanID != server.SpanContext.SpanID {
+				t.Errorf("span %q is not a child of the server span", child.Name)
+			}
+		}
+	})
+
+	t.Run("unsampled caller", func(t *testing.T) {
+		exporter.spans = nil
+		r, w := newRecordedRequest(http.MethodGet, path, "")
+		r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+		app.routes().ServeHTTP(w, r)
+		app.tracer.ForceFlush(context.Background())
+		if len(exporter.byName()) != 0 {
+			t.Errorf("exported spans of an unsampled trace: %v", exporter.byName())
+		}
+	})
+
+	t.Run("sample rate", func(t *testing.T) {
+		exporter.spans = nil
+		app.tracer = trace.NewTracer(exporter, 0, nil)
+		do(t, app, http.MethodGet, path, "")
+		app.tracer.ForceFlush(context.Background())
+		if len(exporter.byName()) != 0 {
+			t.Errorf("exported spans with a sample rate of 0: %v", exporter.byName())
+		}
+	})
+}
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		header string
+		valid  bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", true},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", false},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", false},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", false},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", false},
+		{"garbage", false},
+	}
+
+	for _, tt := range tests {
+		sc, ok := trace.ParseTraceparent(tt.header)
+		if ok != tt.valid {
+			t.Errorf("ParseTraceparent(%q): got valid %t, want %t", tt.header, ok, tt.valid)
+		}
+		if ok && tt.header[:2] == "00" && sc.Traceparent() != tt.header {
+			t.Errorf("got %q back from %q", sc.Traceparent(), tt.header)
+		}
+	}
+}
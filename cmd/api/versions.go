@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// legacyPrefix is the prefix the API was first served under. Its paths
+// redirect to the same paths under /v1 until legacySunset.
+const legacyPrefix = "/api/v1"
+
+var (
+	legacyDeprecated = time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+	legacySunset     = time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
+)
+
+// withVersionHandler tells handler the API version the request was routed
+// to, so that a handler registered for several versions can shape its
+// responses for each.
+func withVersionHandler(version string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler(w, withAPIVersion(r, version))
+	}
+}
+
+// legacyRoutes redirects the requests under legacyPrefix to /v1, with a 308
+// so that the method and body are kept. The Deprecation (RFC 9745) and
+// Sunset (RFC 8594) headers tell clients to move.
+func (a *applicationDependencies) legacyRoutes(router patternRouter) {
+	for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete} {
+		router.HandlerFunc(method, legacyPrefix+"/*path", a.legacyRedirectHandler)
+	}
+}
+
+func (a *applicationDependencies) legacyRedirectHandler(w http.ResponseWriter, r *http.Request) {
+	target := "/v1" + strings.TrimPrefix(r.URL.EscapedPath(), legacyPrefix)
+	if r.URL.RawQuery != "" {
+		target += "?" + r.URL.RawQuery
+	}
+
+	w.Header().Set("Location", target)
+	w.Header().Set("Deprecation", "@"+strconv.FormatInt(legacyDeprecated.Unix(), 10))
+	w.Header().Set("Sunset", legacySunset.Format(http.TimeFormat))
+	w.Header().Set("Link", "<"+target+`>; rel="successor-version"`)
+	w.WriteHeader(http.StatusPermanentRedirect)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestLegacyRedirects(t *testing.T) {
+	app, store := newTestApplication(t)
+	book := insertTestBook(t, store, "Dune", "Frank Herbert")
+	handler := app.routes()
+
+	for _, method := range []string{http.MethodGet, http.MethodPut} {
+		res := serve(t, handler, method, fmt.Sprintf("/api/v1/books/%d?fields=title", book.ID), "")
+		assertStatus(t, res, http.StatusPermanentRedirect)
+		if want := fmt.Sprintf("/v1/books/%d?fields=title", book.ID); res.header.Get("Location") != want {
+			t.Errorf("%s: got Location %q, want %q", method, res.header.Get("Location"), want)
+		}
+		if got := res.header.Get("Deprecation"); got != "@1792281600" {
+			t.Errorf("%s: got Deprecation %q", method, got)
+		}
+		if got := res.header.Get("Sunset"); got != "Fri, 30 Apr 2027 00:00:00 GMT" {
+			t.Errorf("%s: got Sunset %q", method, got)
+		}
+	}
+
+	assertStatus(t, serve(t, handler, http.MethodGet, "/v1/healthcheck", ""), http.StatusOK)
+}
+
+func TestAPIVersion(t *testing.T) {
+	router := patternRouter{Router: httprouter.New()}.apiVersion("v2")
+
+	var version string
+	router.HandlerFunc(http.MethodGet, "/books", func(w http.ResponseWriter, r *http.Request) {
+		version = apiVersionFromContext(r)
+	})
+
+	r, w := newRecordedRequest(http.MethodGet, "/v2/books", "")
+	router.ServeHTTP(w, r)
+	if version != "v2" {
+		t.Errorf("got version %q, want v2", version)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/RayMC17/bookclub-api/internal/data"
+)
+
+// getWorkHandler returns a work with its aggregated rating and all of its editions.
+func (a *applicationDependencies) getWorkHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := a.readIDParam(r)
+	if err != nil {
+		a.notFoundResponse(w, r)
+		return
+	}
+
+	work, err := a.models.Works.Get(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			a.notFoundResponse(w, r)
+		default:
+			a.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	work.Editions, err = a.models.Books.GetAllByWork(r.Context(), id)
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = a.writeJSON(w, http.StatusOK, envelope{"work": work}, nil)
+	if err != nil {
+		a.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/RayMC17/bookclub-api/internal/data"
+)
+
+func TestGetWorkHandler(t *testing.T) {
+	app, store := newTestApplication(t)
+	dune := insertTestBook(t, store, "Dune", "Frank Herbert")
+
+	translation := &data.Book{
+		Title:        "Duna",
+		WorkID:       dune.WorkID,
+		Contributors: data.ContributorsFromAuthors([]string{"Frank Herbert"}, nil),
+		ISBN:         "9788576570486",
+		Language:     "pt",
+	}
+	err := store.Books.Insert(context.Background(), translation)
+	if err != nil {
+		t.Fatal(err)
+	}
+	insertTestReview(t, store, dune.ID, "alice", 5)
+	insertTestReview(t, store, translation.ID, "bob", 4)
+
+	res := do(t, app, http.MethodGet, fmt.Sprintf("/v1/works/%d", dune.WorkID), "")
+	assertStatus(t, res, http.StatusOK)
+
+	// Ratings are aggregated over every edition
+	if got := res.field("work", "average_rating"); got != 4.5 {
+		t.Errorf("got average rating %v, want 4.5", got)
+	}
+	if got := res.field("work", "ratings_count"); got != 2.0 {
+		t.Errorf("got ratings count %v, want 2", got)
+	}
+	if editions, _ := res.field("work", "editions").([]any); len(editions) != 2 {
+		t.Errorf("got %d editions, want 2", len(editions))
+	}
+
+	res = do(t, app, http.MethodGet, "/v1/works/999", "")
+	assertStatus(t, res, http.StatusNotFound)
+}
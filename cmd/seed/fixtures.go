@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/RayMC17/bookclub-api/internal/data"
+)
+
+// fixture is a curated data set. Records refer to each other by username and
+// ISBN rather than ID, so fixtures can be loaded into a database that already
+// has data in it.
+type fixture struct {
+	Users []struct {
+		Username string `json:"username"`
+		Email    string `json:"email"`
+	} `json:"users"`
+
+	Books []struct {
+		Title           string   `json:"title"`
+		Authors         []string `json:"authors"`
+		ISBN            string   `json:"isbn"`
+		PublicationDate string   `json:"publication_date"`
+		Description     string   `json:"description"`
+		Genres          []string `json:"genres"`
+		Format          string   `json:"format"`
+		Language        string   `json:"language"`
+		PageCount       int      `json:"page_count"`
+		Publisher       string   `json:"publisher"`
+	} `json:"books"`
+
+	ReadingLists []struct {
+		Name        string   `json:"name"`
+		Description string   `json:"description"`
+		Owner       string   `json:"owner"`
+		Status      string   `json:"status"`
+		Books       []string `json:"books"`
+	} `json:"reading_lists"`
+
+	Reviews []struct {
+		ISBN    string `json:"isbn"`
+		Author  string `json:"author"`
+		Rating  int    `json:"rating"`
+		Content string `json:"content"`
+	} `json:"reviews"`
+}
+
+// readFixture decodes a JSON fixture file.
+func readFixture(path string) (*fixture, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var f fixture
+	dec := json.NewDecoder(bytes.NewReader(contents))
+	dec.DisallowUnknownFields()
+	err = dec.Decode(&f)
+	if err != nil {
+		return nil, fmt.Errorf("fixture %s: %w", path, err)
+	}
+
+	return &f, nil
+}
+
+// loadFixture inserts the records of a fixture in dependency order.
+func (s *seeder) loadFixture(ctx context.Context, f *fixture) error {
+	for _, u := range f.Users {
+		err := s.insertUser(ctx, &data.User{Username: u.Username, Email: u.Email})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, b := range f.Books {
+		if !validISBN13(b.ISBN) {
+			return fmt.Errorf("book %q: %q is not a valid ISBN-13", b.Title, b.ISBN)
+		}
+
+		published, err := time.Parse("2006-01-02", b.PublicationDate)
+		if err != nil {
+			return fmt.Errorf("book %q: publication_date must be YYYY-MM-DD", b.Title)
+		}
+
+		book := &data.Book{
+			Title:           b.Title,
+			Authors:         b.Authors,
+			ISBN:            b.ISBN,
+			PublicationDate: published,
+			Description:     b.Description,
+			Format:          valueOr(b.Format, "unknown"),
+			Language:        valueOr(b.Language, "en"),
+			PageCount:       b.PageCount,
+			Publisher:       b.Publisher,
+		}
+
+		err = s.insertBook(ctx, book, b.Genres)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, l := range f.ReadingLists {
+		owner, ok := s.byUsername[l.Owner]
+		if !ok {
+			return fmt.Errorf("reading list %q: unknown owner %q", l.Name, l.Owner)
+		}
+
+		list := &data.ReadingList{
+			Name:        l.Name,
+			Description: l.Description,
+			CreatedBy:   owner,
+			Status:      valueOr(l.Status, "currently reading"),
+		}
+		for _, isbn := range l.Books {
+			bookID, ok := s.byISBN[isbn]
+			if !ok {
+				return fmt.Errorf("reading list %q: unknown book %q", l.Name, isbn)
+			}
+			list.Books = append(list.Books, bookID)
+		}
+
+		err := s.insertReadingList(ctx, list)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, r := range f.Reviews {
+		bookID, ok := s.byISBN[r.ISBN]
+		if !ok {
+			return fmt.Errorf("review by %q: unknown book %q", r.Author, r.ISBN)
+		}
+		userID, ok := s.byUsername[r.Author]
+		if !ok {
+			return fmt.Errorf("review of %q: unknown author %q", r.ISBN, r.Author)
+		}
+
+		err := s.insertReview(ctx, &data.Review{
+			BookID:  int64(bookID),
+			UserID:  int64(userID),
+			Author:  r.Author,
+			Rating:  r.Rating,
+			Content: r.Content,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RayMC17/bookclub-api/internal/data"
+)
+
+// seeder inserts users, books, reading lists and reviews through the data
+// models and remembers what it created so later records can refer to it.
+type seeder struct {
+	db  *sql.DB
+	rng *rand.Rand
+
+	users   data.UserModel
+	books   data.BookModel
+	genres  data.GenreModel
+	lists   data.ReadingListModel
+	reviews data.ReviewModel
+
+	userIDs    []int
+	usernames  map[int]string
+	byUsername map[string]int
+	bookIDs    []int
+	byISBN     map[string]int
+	genreCache map[string]data.Genre
+
+	counts struct {
+		users, books, lists, reviews int
+	}
+}
+
+func newSeeder(db *sql.DB, rng *rand.Rand) *seeder {
+	return &seeder{
+		db:         db,
+		rng:        rng,
+		users:      data.UserModel{DB: db},
+		books:      data.BookModel{DB: db},
+		genres:     data.GenreModel{DB: db},
+		lists:      data.ReadingListModel{DB: db},
+		reviews:    data.ReviewModel{DB: db},
+		usernames:  make(map[int]string),
+		byUsername: make(map[string]int),
+		byISBN:     make(map[string]int),
+		genreCache: make(map[string]data.Genre),
+	}
+}
+
+// reset deletes the data the seeder creates, restarting the ID sequences so
+// that a reset followed by a seed is reproducible. The genre taxonomy is left
+// alone because it is part of the migrations.
+func (s *seeder) reset(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	query := `
+        TRUNCATE reviews, book_reviews, reading_list_books, reading_lists, user_book_tags, tags,
+            book_genres, book_authors, authors, series_entries, series, books, works, users
+        RESTART IDENTITY CASCADE`
+	_, err := s.db.ExecContext(ctx, query)
+	return err
+}
+
+func (s *seeder) insertUser(ctx context.Context, user *data.User) error {
+	err := s.users.Insert(ctx, user)
+	if err != nil {
+		return fmt.Errorf("insert user %q: %w", user.Username, err)
+	}
+
+	s.userIDs = append(s.userIDs, user.ID)
+	s.usernames[user.ID] = user.Username
+	s.byUsername[user.Username] = user.ID
+	s.counts.users++
+	return nil
+}
+
+func (s *seeder) insertBook(ctx context.Context, book *data.Book, genres []string) error {
+	for _, name := range genres {
+		genre, err := s.resolveGenre(ctx, name)
+		if err != nil {
+			return fmt.Errorf("book %q: %w", book.Title, err)
+		}
+		book.Genres = append(book.Genres, genre)
+	}
+	book.Contributors = data.ContributorsFromAuthors(book.Authors, nil)
+
+	err := s.books.Insert(ctx, book)
+	if err != nil {
+		return fmt.Errorf("insert book %q: %w", book.Title, err)
+	}
+
+	s.bookIDs = append(s.bookIDs, book.ID)
+	s.byISBN[book.ISBN] = book.ID
+	s.counts.books++
+	return nil
+}
+
+func (s *seeder) insertReadingList(ctx context.Context, list *data.ReadingList) error {
+	err := s.lists.Insert(ctx, list)
+	if err != nil {
+		return fmt.Errorf("insert reading list %q: %w", list.Name, err)
+	}
+
+	for _, bookID := range list.Books {
+		err = s.lists.AddBook(ctx, list.ID, bookID)
+		if err != nil {
+			return fmt.Errorf("add book %d to reading list %q: %w", bookID, list.Name, err)
+		}
+	}
+
+	s.counts.lists++
+	return nil
+}
+
+func (s *seeder) insertReview(ctx context.Context, review *data.Review) error {
+	err := s.reviews.Insert(ctx, review)
+	if err != nil {
+		return fmt.Errorf("insert review of book %d: %w", review.BookID, err)
+	}
+
+	err = s.books.UpdateRating(ctx, int(review.BookID))
+	if err != nil {
+		return fmt.Errorf("update rating of book %d: %w", review.BookID, err)
+	}
+
+	s.counts.reviews++
+	return nil
+}
+
+func (s *seeder) resolveGenre(ctx context.Context, name string) (data.Genre, error) {
+	if genre, ok := s.genreCache[name]; ok {
+		return genre, nil
+	}
+
+	genre, err := s.genres.Resolve(ctx, name)
+	if errors.Is(err, data.ErrUnknownGenre) {
+		return data.Genre{}, fmt.Errorf("unknown genre %q", name)
+	}
+	if err != nil {
+		return data.Genre{}, err
+	}
+
+	s.genreCache[name] = *genre
+	return *genre, nil
+}
+
+// generate inserts the requested number of random records. Users and books
+// created earlier (e.g. from fixtures) can be picked for lists and reviews.
+func (s *seeder) generate(ctx context.Context, users, books, lists, reviews int) error {
+	for i := 0; i < users; i++ {
+		first := pick(s.rng, firstNames)
+		last := pick(s.rng, lastNames)
+		username := fmt.Sprintf("%s.%s%d", strings.ToLower(first), strings.ToLower(last), len(s.userIDs)+1)
+
+		err := s.insertUser(ctx, &data.User{
+			Username: username,
+			Email:    username + "@example.com",
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := 0; i < books; i++ {
+		book := s.randomBook()
+		genres := []string{pick(s.rng, genreSlugs)}
+		if s.rng.Intn(3) == 0 {
+			genres = append(genres, pick(s.rng, genreSlugs))
+		}
+
+		err := s.insertBook(ctx, book, dedupe(genres))
+		if err != nil {
+			return err
+		}
+	}
+
+	if (lists > 0 || reviews > 0) && (len(s.userIDs) == 0 || len(s.bookIDs) == 0) {
+		return errors.New("reading lists and reviews need at least one user and one book")
+	}
+
+	for i := 0; i < lists; i++ {
+		owner := s.userIDs[s.rng.Intn(len(s.userIDs))]
+		list := &data.ReadingList{
+			Name:        fmt.Sprintf("%s %s", pick(s.rng, listAdjectives), pick(s.rng, listNouns)),
+			Description: fmt.Sprintf("Books %s is reading.", s.usernames[owner]),
+			CreatedBy:   owner,
+			Status:      pick(s.rng, listStatuses),
+			Books:       s.sampleBooks(1 + s.rng.Intn(8)),
+		}
+
+		err := s.insertReadingList(ctx, list)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := 0; i < reviews; i++ {
+		author := s.userIDs[s.rng.Intn(len(s.userIDs))]
+		rating := ratingWeights[s.rng.Intn(len(ratingWeights))]
+		review := &data.Review{
+			BookID:  int64(s.bookIDs[s.rng.Intn(len(s.bookIDs))]),
+			UserID:  int64(author),
+			Author:  s.usernames[author],
+			Rating:  rating,
+			Content: pick(s.rng, reviewTemplates[rating]),
+		}
+
+		err := s.insertReview(ctx, review)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *seeder) randomBook() *data.Book {
+	authors := []string{pick(s.rng, firstNames) + " " + pick(s.rng, lastNames)}
+	if s.rng.Intn(5) == 0 {
+		authors = append(authors, pick(s.rng, firstNames)+" "+pick(s.rng, lastNames))
+	}
+
+	isbn := randomISBN13(s.rng)
+	for s.byISBN[isbn] != 0 {
+		isbn = randomISBN13(s.rng)
+	}
+
+	title := fmt.Sprintf("The %s %s", pick(s.rng, titleAdjectives), pick(s.rng, titleNouns))
+
+	return &data.Book{
+		Title:           title,
+		Authors:         authors,
+		ISBN:            isbn,
+		PublicationDate: time.Date(1950+s.rng.Intn(74), time.Month(1+s.rng.Intn(12)), 1+s.rng.Intn(28), 0, 0, 0, 0, time.UTC),
+		Description:     fmt.Sprintf("%s is a %s story about %s.", title, pick(s.rng, descriptionMoods), pick(s.rng, descriptionSubjects)),
+		Format:          pick(s.rng, data.BookFormats[:len(data.BookFormats)-1]),
+		Language:        "en",
+		PageCount:       120 + s.rng.Intn(780),
+		Publisher:       pick(s.rng, publishers),
+	}
+}
+
+// sampleBooks picks up to n distinct books.
+func (s *seeder) sampleBooks(n int) []int {
+	if n > len(s.bookIDs) {
+		n = len(s.bookIDs)
+	}
+
+	ids := make([]int, 0, n)
+	for _, i := range s.rng.Perm(len(s.bookIDs))[:n] {
+		ids = append(ids, s.bookIDs[i])
+	}
+	return ids
+}
+
+// randomISBN13 returns a random ISBN-13 in the 978 prefix with a valid check
+// digit.
+func randomISBN13(rng *rand.Rand) string {
+	digits := "978"
+	for i := 0; i < 9; i++ {
+		digits += strconv.Itoa(rng.Intn(10))
+	}
+	return digits + strconv.Itoa(isbn13CheckDigit(digits))
+}
+
+// isbn13CheckDigit computes the check digit for the first 12 digits of an
+// ISBN-13.
+func isbn13CheckDigit(digits string) int {
+	sum := 0
+	for i, d := range digits[:12] {
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += int(d-'0') * weight
+	}
+	return (10 - sum%10) % 10
+}
+
+// validISBN13 reports whether isbn is 13 digits with a correct check digit.
+func validISBN13(isbn string) bool {
+	if len(isbn) != 13 {
+		return false
+	}
+	for _, d := range isbn {
+		if d < '0' || d > '9' {
+			return false
+		}
+	}
+	return int(isbn[12]-'0') == isbn13CheckDigit(isbn)
+}
+
+func pick[T any](rng *rand.Rand, items []T) T {
+	return items[rng.Intn(len(items))]
+}
+
+func dedupe(items []string) []string {
+	seen := make(map[string]bool, len(items))
+	out := items[:0]
+	for _, item := range items {
+		if !seen[item] {
+			seen[item] = true
+			out = append(out, item)
+		}
+	}
+	return out
+}
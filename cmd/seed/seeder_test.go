@@ -0,0 +1,103 @@
+package main
+
+import (
+	"math/rand"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestISBN13CheckDigit(t *testing.T) {
+	tests := []struct {
+		isbn  string
+		valid bool
+	}{
+		{"9780306406157", true},
+		{"9780441172719", true},
+		{"9780141439518", true},
+		{"9780306406150", false},
+		{"9780306406158", false},
+		{"978030640615", false},
+		{"97803064061570", false},
+		{"978030640615X", false},
+		{"978-0306406157", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := validISBN13(tt.isbn); got != tt.valid {
+			t.Errorf("validISBN13(%q) = %t, want %t", tt.isbn, got, tt.valid)
+		}
+	}
+
+	// A check digit of 10 wraps to 0
+	if got := isbn13CheckDigit("978000000004"); got != 0 {
+		t.Errorf("isbn13CheckDigit(978000000004) = %d, want 0", got)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		if isbn := randomISBN13(rng); !validISBN13(isbn) {
+			t.Fatalf("randomISBN13 returned invalid ISBN %q", isbn)
+		}
+	}
+}
+
+func TestRandomBooksAreDeterministic(t *testing.T) {
+	generate := func(seed int64) []any {
+		s := newSeeder(nil, rand.New(rand.NewSource(seed)))
+		var out []any
+		for i := 0; i < 20; i++ {
+			book := s.randomBook()
+			s.byISBN[book.ISBN] = i + 1
+			s.bookIDs = append(s.bookIDs, i+1)
+			out = append(out, book)
+		}
+		for i := 0; i < 5; i++ {
+			out = append(out, s.sampleBooks(8))
+		}
+		return out
+	}
+
+	first, second := generate(42), generate(42)
+	if !reflect.DeepEqual(first, second) {
+		t.Error("the same seed produced different books")
+	}
+	if reflect.DeepEqual(first, generate(43)) {
+		t.Error("different seeds produced the same books")
+	}
+}
+
+func TestFixtures(t *testing.T) {
+	paths, err := filepath.Glob("../../fixtures/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("no fixtures found")
+	}
+
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			f, err := readFixture(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(f.Books) == 0 {
+				t.Fatal("the fixture has no books")
+			}
+			for _, b := range f.Books {
+				if !validISBN13(b.ISBN) {
+					t.Errorf("book %q: %q is not a valid ISBN-13", b.Title, b.ISBN)
+				}
+			}
+
+			again, err := readFixture(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(f, again) {
+				t.Error("reading the fixture twice gave different results")
+			}
+		})
+	}
+}
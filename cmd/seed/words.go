@@ -0,0 +1,63 @@
+package main
+
+// Word lists for the generated data. Changing them changes what a given seed
+// produces.
+var (
+	firstNames = []string{
+		"Ada", "Amara", "Boris", "Chen", "Dara", "Elena", "Farid", "Grace", "Hugo", "Imani",
+		"Jonas", "Keiko", "Liam", "Maya", "Nikolai", "Olu", "Priya", "Quinn", "Rosa", "Sami",
+		"Tomas", "Uma", "Vera", "Wen", "Yara", "Zane",
+	}
+
+	lastNames = []string{
+		"Adeyemi", "Bennett", "Castillo", "Dubois", "Eriksen", "Fischer", "Garcia", "Haddad",
+		"Ivanova", "Johansson", "Kowalski", "Lindqvist", "Morales", "Nakamura", "Okafor",
+		"Petrov", "Quispe", "Rossi", "Silva", "Tanaka", "Usman", "Vasquez", "Wright", "Young",
+	}
+
+	titleAdjectives = []string{
+		"Silent", "Broken", "Hidden", "Last", "Golden", "Forgotten", "Burning", "Distant",
+		"Hollow", "Crimson", "Endless", "Quiet", "Wandering", "Shattered", "Northern", "Midnight",
+	}
+
+	titleNouns = []string{
+		"Garden", "River", "Empire", "Archive", "Lighthouse", "Orchard", "Signal", "Harbor",
+		"Kingdom", "Witness", "Atlas", "Engine", "Tide", "Crown", "Cartographer", "Station",
+	}
+
+	descriptionMoods = []string{
+		"haunting", "sweeping", "tender", "gripping", "funny", "quietly devastating", "sharp", "luminous",
+	}
+
+	descriptionSubjects = []string{
+		"family and memory", "a city on the brink", "an unlikely friendship", "the cost of ambition",
+		"a long journey home", "secrets buried for decades", "the edge of the known world", "love and loss",
+	}
+
+	publishers = []string{
+		"Harbor House", "Northwind Press", "Lantern Books", "Meridian", "Blue Heron", "Stonebridge",
+	}
+
+	// genreSlugs are genres seeded by the genre taxonomy migration.
+	genreSlugs = []string{
+		"science-fiction", "fantasy", "mystery", "thriller", "romance", "horror", "historical-fiction",
+		"literary-fiction", "young-adult", "biography", "history", "science", "self-help", "essays",
+		"space-opera", "cyberpunk", "epic-fantasy", "urban-fantasy", "memoir",
+	}
+
+	listAdjectives = []string{"Summer", "Winter", "Weekend", "Bedtime", "Book Club", "Commute", "Holiday"}
+	listNouns      = []string{"Reads", "Stack", "Picks", "Queue", "Favorites", "Shelf"}
+	listStatuses   = []string{"currently reading", "completed"}
+
+	// ratingWeights skews generated ratings towards 4 and 5 stars, like real
+	// review data.
+	ratingWeights = []int{1, 2, 2, 3, 3, 3, 4, 4, 4, 4, 5, 5, 5, 5}
+
+	reviewTemplates = map[int][]string{
+		1: {"Couldn't finish it.", "Not for me at all.", "The premise was wasted."},
+		2: {"Had its moments but mostly dragged.", "Some good ideas, weak execution."},
+		3: {"Solid, if forgettable.", "Enjoyable enough for a long flight.", "A decent read."},
+		4: {"Really enjoyed this one.", "Great characters and a satisfying ending.", "Would recommend."},
+		5: {"An instant favorite.", "Couldn't put it down.", "Beautifully written from start to finish."},
+	}
+)
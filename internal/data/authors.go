@@ -0,0 +1,480 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/RayMC17/bookclub-api/internal/validator"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrDuplicateAuthor = errors.New("duplicate author")
+	ErrUnknownAuthor   = errors.New("unknown author")
+	ErrAuthorHasBooks  = errors.New("author is credited on books")
+)
+
+// ContributorRoles lists the roles a person can have on a book.
+var ContributorRoles = []string{"author", "translator", "illustrator", "editor"}
+
+// Author represents a person who contributed to one or more books.
+type Author struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Bio       string    `json:"bio"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Contributor links an author to a book in a given role.
+type Contributor struct {
+	AuthorID int    `json:"author_id"`
+	Name     string `json:"name"`
+	Role     string `json:"role"`
+}
+
+// AuthorModel handles the database interactions for authors.
+type AuthorModel struct {
+	DB       DBTX
+	Timeouts Timeouts
+}
+
+// ValidateAuthor validates the author data.
+func ValidateAuthor(v *validator.Validator, author *Author) {
+	v.Check(strings.TrimSpace(author.Name) != "", "name", "must be provided")
+	v.Check(len(author.Name) <= 255, "name", "must not be more than 255 characters long")
+	v.Check(normalizeName(author.Name) != "", "name", "must contain at least one letter or digit")
+	v.Check(len(author.Bio) <= 5000, "bio", "must not be more than 5000 characters long")
+}
+
+// ValidateContributors validates the contributors of a book.
+func ValidateContributors(v *validator.Validator, contributors []Contributor) {
+	hasAuthor := false
+	for _, c := range contributors {
+		v.Check(c.AuthorID > 0 || strings.TrimSpace(c.Name) != "", "contributors", "each contributor must have an author_id or a name")
+		v.Check(len(c.Name) <= 255, "contributors", "names must not be more than 255 characters long")
+		v.Check(validator.In(c.Role, ContributorRoles...), "contributors", "role must be one of author, translator, illustrator or editor")
+		if c.Role == "author" {
+			hasAuthor = true
+		}
+	}
+	v.Check(hasAuthor, "authors", "must have at least one author")
+}
+
+// ContributorsFromAuthors builds the contributor list for a legacy list of
+// author names, keeping any non-author contributors from existing.
+func ContributorsFromAuthors(authors []string, existing []Contributor) []Contributor {
+	contributors := make([]Contributor, 0, len(authors)+len(existing))
+	for _, name := range authors {
+		contributors = append(contributors, Contributor{Name: name, Role: "author"})
+	}
+	for _, c := range existing {
+		if c.Role != "author" {
+			contributors = append(contributors, c)
+		}
+	}
+	return contributors
+}
+
+// normalizeName reduces a name to its lower-cased letters and digits so that
+// "J.R.R. Tolkien" and "J. R. R. Tolkien" resolve to the same author and
+// "Sci-Fi" and "sci fi" to the same genre alias. It must stay in sync with the
+// normalization used by the migrations.
+func normalizeName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Insert adds a new author to the database.
+func (m *AuthorModel) Insert(ctx context.Context, author *Author) error {
+	query := `
+        INSERT INTO authors (name, normalized_name, bio)
+        VALUES ($1, $2, $3)
+        RETURNING id, created_at`
+	args := []interface{}{strings.TrimSpace(author.Name), normalizeName(author.Name), author.Bio}
+
+	ctx, cancel := m.Timeouts.write(ctx)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&author.ID, &author.CreatedAt)
+	if isUniqueViolation(err) {
+		return ErrDuplicateAuthor
+	}
+	return err
+}
+
+// Get retrieves an author by ID.
+func (m *AuthorModel) Get(ctx context.Context, id int) (*Author, error) {
+	query := `
+        SELECT id, name, bio, created_at
+        FROM authors
+        WHERE id = $1`
+
+	ctx, cancel := m.Timeouts.read(ctx)
+	defer cancel()
+
+	var author Author
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(&author.ID, &author.Name, &author.Bio, &author.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRecordNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &author, nil
+}
+
+// Update renames an author and refreshes the legacy authors column of every
+// book the author is credited on.
+func (m *AuthorModel) Update(ctx context.Context, author *Author) error {
+	ctx, cancel := m.Timeouts.write(ctx)
+	defer cancel()
+
+	tx, err := beginTx(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+        UPDATE authors
+        SET name = $1, normalized_name = $2, bio = $3
+        WHERE id = $4`
+	args := []interface{}{strings.TrimSpace(author.Name), normalizeName(author.Name), author.Bio, author.ID}
+
+	result, err := tx.ExecContext(ctx, query, args...)
+	if isUniqueViolation(err) {
+		return ErrDuplicateAuthor
+	}
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	bookIDs, err := bookIDsForAuthors(ctx, tx, []int{author.ID})
+	if err != nil {
+		return err
+	}
+	err = refreshBookAuthors(ctx, tx, bookIDs)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Delete removes an author who isn't credited on any book. Authors with books
+// are left in place and ErrAuthorHasBooks returned, since deleting them would
+// leave books without an author; they're merged into another author instead.
+func (m *AuthorModel) Delete(ctx context.Context, id int) error {
+	ctx, cancel := m.Timeouts.write(ctx)
+	defer cancel()
+
+	tx, err := beginTx(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Locking the author holds off books being credited to them meanwhile
+	var lockedID int
+	err = tx.QueryRowContext(ctx, `SELECT id FROM authors WHERE id = $1 FOR UPDATE`, id).Scan(&lockedID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrRecordNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	bookIDs, err := bookIDsForAuthors(ctx, tx, []int{id})
+	if err != nil {
+		return err
+	}
+	if len(bookIDs) > 0 {
+		return ErrAuthorHasBooks
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM authors WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return tx.Commit()
+}
+
+// GetAll retrieves all authors with an optional name filter and pagination.
+func (m *AuthorModel) GetAll(ctx context.Context, name string, filters Filters) ([]*Author, Metadata, error) {
+	query := fmt.Sprintf(`
+        SELECT COUNT(*) OVER(), id, name, bio, created_at
+        FROM authors
+        WHERE (name ILIKE '%%' || $1 || '%%' OR $1 = '')
+        ORDER BY %s %s, id ASC
+        LIMIT $2 OFFSET $3`, filters.SortColumn(), filters.SortDirection())
+
+	ctx, cancel := m.Timeouts.read(ctx)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, name, filters.Limit(), filters.Offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	authors := []*Author{}
+
+	for rows.Next() {
+		var author Author
+		err := rows.Scan(&totalRecords, &author.ID, &author.Name, &author.Bio, &author.CreatedAt)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		authors = append(authors, &author)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return authors, metadata, nil
+}
+
+// Merge folds the duplicate authors into the target author. Every book
+// credited to a duplicate is re-credited to the target in the same role and
+// the duplicates are deleted.
+func (m *AuthorModel) Merge(ctx context.Context, targetID int, duplicateIDs []int) error {
+	ctx, cancel := m.Timeouts.write(ctx)
+	defer cancel()
+
+	tx, err := beginTx(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM authors WHERE id = $1)`, targetID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrRecordNotFound
+	}
+
+	bookIDs, err := bookIDsForAuthors(ctx, tx, duplicateIDs)
+	if err != nil {
+		return err
+	}
+
+	query := `
+        INSERT INTO book_authors (book_id, author_id, role, position)
+        SELECT book_id, $1, role, position
+        FROM book_authors
+        WHERE author_id = ANY($2)
+        ON CONFLICT DO NOTHING`
+	_, err = tx.ExecContext(ctx, query, targetID, pq.Array(duplicateIDs))
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM authors WHERE id = ANY($1) AND id <> $2`, pq.Array(duplicateIDs), targetID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected != int64(len(duplicateIDs)) {
+		return ErrRecordNotFound
+	}
+
+	err = refreshBookAuthors(ctx, tx, bookIDs)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// setContributors replaces the contributors of a book, creating authors that
+// don't exist yet, and keeps the legacy books.authors column in sync. A person
+// listed twice in the same role, by ID or under two spellings of their name,
+// is credited once.
+func setContributors(ctx context.Context, tx DBTX, book *Book) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM book_authors WHERE book_id = $1`, book.ID)
+	if err != nil {
+		return err
+	}
+
+	type credit struct {
+		authorID int
+		role     string
+	}
+	credited := make(map[credit]bool, len(book.Contributors))
+	contributors := make([]Contributor, 0, len(book.Contributors))
+
+	for _, c := range book.Contributors {
+		if c.AuthorID > 0 {
+			err = tx.QueryRowContext(ctx, `SELECT name FROM authors WHERE id = $1`, c.AuthorID).Scan(&c.Name)
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrUnknownAuthor
+			}
+		} else {
+			query := `
+                INSERT INTO authors (name, normalized_name)
+                VALUES ($1, $2)
+                ON CONFLICT (normalized_name) DO UPDATE SET normalized_name = EXCLUDED.normalized_name
+                RETURNING id, name`
+			err = tx.QueryRowContext(ctx, query, strings.TrimSpace(c.Name), normalizeName(c.Name)).Scan(&c.AuthorID, &c.Name)
+		}
+		if err != nil {
+			return err
+		}
+
+		if credited[credit{c.AuthorID, c.Role}] {
+			continue
+		}
+		credited[credit{c.AuthorID, c.Role}] = true
+
+		query := `
+            INSERT INTO book_authors (book_id, author_id, role, position)
+            VALUES ($1, $2, $3, $4)`
+		_, err = tx.ExecContext(ctx, query, book.ID, c.AuthorID, c.Role, len(contributors))
+		if err != nil {
+			return err
+		}
+		contributors = append(contributors, c)
+	}
+
+	book.Contributors = contributors
+	book.Authors = []string{}
+	for _, c := range book.Contributors {
+		if c.Role == "author" {
+			book.Authors = append(book.Authors, c.Name)
+		}
+	}
+
+	return refreshBookAuthors(ctx, tx, []int{book.ID})
+}
+
+// loadContributors fills in the contributors of the given books.
+func loadContributors(ctx context.Context, db queryer, books ...*Book) error {
+	if len(books) == 0 {
+		return nil
+	}
+
+	byID := make(map[int]*Book, len(books))
+	ids := make([]int, 0, len(books))
+	for _, book := range books {
+		book.Contributors = []Contributor{}
+		byID[book.ID] = book
+		ids = append(ids, book.ID)
+	}
+
+	query := `
+        SELECT ba.book_id, a.id, a.name, ba.role
+        FROM book_authors ba
+        JOIN authors a ON a.id = ba.author_id
+        WHERE ba.book_id = ANY($1)
+        ORDER BY ba.book_id, ba.position, a.name`
+
+	rows, err := db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bookID int
+		var c Contributor
+		err := rows.Scan(&bookID, &c.AuthorID, &c.Name, &c.Role)
+		if err != nil {
+			return err
+		}
+		if book, ok := byID[bookID]; ok {
+			book.Contributors = append(book.Contributors, c)
+		}
+	}
+
+	return rows.Err()
+}
+
+// bookIDsForAuthors returns the IDs of the books credited to any of the authors.
+func bookIDsForAuthors(ctx context.Context, tx DBTX, authorIDs []int) ([]int, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT DISTINCT book_id FROM book_authors WHERE author_id = ANY($1)`, pq.Array(authorIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// refreshBookAuthors rebuilds the legacy books.authors column from book_authors.
+func refreshBookAuthors(ctx context.Context, tx DBTX, bookIDs []int) error {
+	if len(bookIDs) == 0 {
+		return nil
+	}
+
+	query := `
+        UPDATE books b
+        SET authors = COALESCE((
+            SELECT array_agg(a.name ORDER BY ba.position)
+            FROM book_authors ba
+            JOIN authors a ON a.id = ba.author_id
+            WHERE ba.book_id = b.id AND ba.role = 'author'
+        ), '{}')
+        WHERE b.id = ANY($1)`
+	_, err := tx.ExecContext(ctx, query, pq.Array(bookIDs))
+	return err
+}
+
+// queryer is implemented by both *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// isUniqueViolation reports whether err is a PostgreSQL unique_violation.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// isForeignKeyViolation reports whether err is a PostgreSQL foreign_key_violation.
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503"
+}
@@ -13,18 +13,34 @@ import (
 	"github.com/lib/pq" // Update the path according to your module path
 )
 
-var ErrRecordNotFound = errors.New("record not found")
+var (
+	ErrRecordNotFound = errors.New("record not found")
+	ErrUnknownWork    = errors.New("unknown work")
+)
+
+// BookFormats lists the physical or digital formats an edition can have.
+var BookFormats = []string{"hardcover", "paperback", "ebook", "audiobook", "unknown"}
 
-// Book model definition
+// Book model definition. Each book is one edition of a Work; reviews and
+// ratings are aggregated across the editions of a work while reading lists
+// keep pointing at the specific edition being read.
 type Book struct {
-	ID              int       `json:"id"`
-	Title           string    `json:"title"`
-	Authors         []string  `json:"authors"`
-	ISBN            string    `json:"isbn"`
-	PublicationDate time.Time `json:"publication_date"`
-	Genre           string    `json:"genre"`
-	Description     string    `json:"description"`
-	AverageRating   float64   `json:"average_rating"`
+	ID              int           `json:"id"`
+	Title           string        `json:"title"`
+	Authors         []string      `json:"authors"`
+	ISBN            string        `json:"isbn"`
+	PublicationDate time.Time     `json:"publication_date"`
+	Description     string        `json:"description"`
+	AverageRating   float64       `json:"average_rating"`
+	Contributors    []Contributor `json:"contributors"`
+	WorkID          int           `json:"work_id"`
+	Format          string        `json:"format"`
+	Language        string        `json:"language"`
+	PageCount       int           `json:"page_count"`
+	Publisher       string        `json:"publisher"`
+	Series          []SeriesInfo  `json:"series"`
+	Genres          []Genre       `json:"genres"`
+	UpdatedAt       time.Time     `json:"updated_at"`
 }
 
 // // ReadingList model definition
@@ -38,20 +54,27 @@ type Book struct {
 
 // BookModel struct and methods
 type BookModel struct {
-	DB *sql.DB
+	DB       DBTX
+	Timeouts Timeouts
 }
 
 func ValidateBook(v *validator.Validator, book *Book) {
 	v.Check(book.Title != "", "title", "must be provided")
 	v.Check(len(book.Title) <= 255, "title", "must not be more than 255 characters long")
-	v.Check(len(book.Authors) > 0, "authors", "must have at least one author")
+	ValidateContributors(v, book.Contributors)
 	v.Check(book.ISBN != "", "isbn", "must be provided")
 	v.Check(len(book.ISBN) == 13, "isbn", "must be exactly 13 characters long")
 	v.Check(book.PublicationDate.Before(time.Now()), "publication_date", "must be in the past")
-	v.Check(book.Genre != "", "genre", "must be provided")
-	v.Check(len(book.Genre) <= 50, "genre", "must not be more than 50 characters long")
+	v.Check(len(book.Genres) > 0, "genres", "must have at least one genre")
+	v.Check(len(book.Genres) <= 10, "genres", "must not have more than 10 genres")
 	v.Check(len(book.Description) <= 1000, "description", "must not be more than 1000 characters long")
 	v.Check(book.AverageRating >= 0 && book.AverageRating <= 5, "average_rating", "must be between 0 and 5")
+	v.Check(book.WorkID >= 0, "work_id", "must be a valid work ID")
+	v.Check(validator.In(book.Format, BookFormats...), "format", "must be one of hardcover, paperback, ebook, audiobook or unknown")
+	v.Check(len(book.Language) >= 2 && len(book.Language) <= 10, "language", "must be a language code between 2 and 10 characters long")
+	v.Check(book.PageCount >= 0, "page_count", "must not be negative")
+	v.Check(book.PageCount <= 100000, "page_count", "must not be more than 100000")
+	v.Check(len(book.Publisher) <= 255, "publisher", "must not be more than 255 characters long")
 }
 
 // ValidateReadingList function to validate ReadingList fields
@@ -59,100 +82,370 @@ func ValidateReadingList(v *validator.Validator, readingList *ReadingList) {
 	v.Check(readingList.Name != "", "name", "must be provided")
 	v.Check(len(readingList.Name) <= 255, "name", "must not be more than 255 characters long")
 	v.Check(len(readingList.Description) <= 1000, "description", "must not be more than 1000 characters long")
-
-	allowedStatuses := []string{"public", "private"}
-	v.Check(validator.In(readingList.Status, allowedStatuses...), "status", "must be either 'public' or 'private'")
+	v.Check(validator.In(readingList.Status, ReadingListStatuses...), "status", "must be either 'currently reading' or 'completed'")
 }
 
 // BookModel methods (Insert, Get, Update, Delete, GetAll) as defined in your code
-// Insert a new book
-func (m *BookModel) Insert(book *Book) error {
+
+// bookColumns is the column list scanned by bookDestinations.
+const bookColumns = `id, title, authors, isbn, publication_date, description, average_rating,
+        work_id, format, language, page_count, publisher, updated_at`
+
+// bookDestinations returns the scan destinations matching bookColumns.
+func bookDestinations(book *Book) []interface{} {
+	return []interface{}{
+		&book.ID,
+		&book.Title,
+		pq.Array(&book.Authors),
+		&book.ISBN,
+		&book.PublicationDate,
+		&book.Description,
+		&book.AverageRating,
+		&book.WorkID,
+		&book.Format,
+		&book.Language,
+		&book.PageCount,
+		&book.Publisher,
+		&book.UpdatedAt,
+	}
+}
+
+// Insert a new book along with its contributors. When no work is given a new
+// work is created for the book.
+func (m *BookModel) Insert(ctx context.Context, book *Book) error {
 	//authors := strings.Join(book.Authors, ",")
 	query := `
-        INSERT INTO books (title, authors, isbn, publication_date, genre, description, average_rating)
-        VALUES ($1, $2, $3, $4, $5, $6, $7)
-        RETURNING id`
-	args := []interface{}{book.Title, pq.Array(book.Authors), book.ISBN, book.PublicationDate, book.Genre, book.Description, book.AverageRating}
+        INSERT INTO books (title, authors, isbn, publication_date, description, average_rating,
+            work_id, format, language, page_count, publisher)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+        RETURNING id, updated_at`
+
+	ctx, cancel := m.Timeouts.write(ctx)
+	defer cancel()
+
+	tx, err := beginTx(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if book.WorkID == 0 {
+		err = tx.QueryRowContext(ctx, `INSERT INTO works (title, description) VALUES ($1, $2) RETURNING id`,
+			book.Title, book.Description).Scan(&book.WorkID)
+		if err != nil {
+			return err
+		}
+	}
+
+	args := []interface{}{book.Title, pq.Array(book.Authors), book.ISBN, book.PublicationDate, book.Description, book.AverageRating,
+		book.WorkID, book.Format, book.Language, book.PageCount, book.Publisher}
 
-	return m.DB.QueryRow(query, args...).Scan(&book.ID)
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&book.ID, &book.UpdatedAt)
+	if isForeignKeyViolation(err) {
+		return ErrUnknownWork
+	}
+	if err != nil {
+		return err
+	}
+
+	err = setContributors(ctx, tx, book)
+	if err != nil {
+		return err
+	}
+
+	err = setGenres(ctx, tx, book)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 // Get a single book by ID
-func (m *BookModel) Get(id int) (*Book, error) {
+func (m *BookModel) Get(ctx context.Context, id int) (*Book, error) {
 	query := `
-        SELECT id, title, authors, isbn, publication_date, genre, description, average_rating
+        SELECT ` + bookColumns + `
         FROM books
         WHERE id = $1`
 
+	ctx, cancel := m.Timeouts.read(ctx)
+	defer cancel()
+
 	var book Book
-	err := m.DB.QueryRow(query, id).Scan(
-		&book.ID, &book.Title, &book.Authors, &book.ISBN,
-		&book.PublicationDate, &book.Genre, &book.Description, &book.AverageRating,
-	)
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(bookDestinations(&book)...)
 	if err == sql.ErrNoRows {
 		return nil, ErrRecordNotFound
 	}
-	return &book, err
+	if err != nil {
+		return nil, err
+	}
+
+	err = loadBookRelations(ctx, m.DB, &book)
+	if err != nil {
+		return nil, err
+	}
+	return &book, nil
 }
 
-// Update a book
-func (m *BookModel) Update(book *Book) error {
+// Update a book and replace its contributors
+func (m *BookModel) Update(ctx context.Context, book *Book) error {
 	query := `
         UPDATE books
-        SET title = $1, authors = $2, isbn = $3, publication_date = $4, genre = $5, description = $6, average_rating = $7
-        WHERE id = $8`
-	args := []interface{}{book.Title, book.Authors, book.ISBN, book.PublicationDate, book.Genre, book.Description, book.AverageRating, book.ID}
+        SET title = $1, authors = $2, isbn = $3, publication_date = $4, description = $5, average_rating = $6,
+            work_id = $7, format = $8, language = $9, page_count = $10, publisher = $11, updated_at = NOW()
+        WHERE id = $12
+        RETURNING updated_at`
+	args := []interface{}{book.Title, pq.Array(book.Authors), book.ISBN, book.PublicationDate, book.Description, book.AverageRating,
+		book.WorkID, book.Format, book.Language, book.PageCount, book.Publisher, book.ID}
+
+	ctx, cancel := m.Timeouts.write(ctx)
+	defer cancel()
+
+	tx, err := beginTx(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var previousWorkID int
+	err = tx.QueryRowContext(ctx, `SELECT work_id FROM books WHERE id = $1 FOR UPDATE`, book.ID).Scan(&previousWorkID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrRecordNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(&book.UpdatedAt)
+	if isForeignKeyViolation(err) {
+		return ErrUnknownWork
+	}
+	if err != nil {
+		return err
+	}
+
+	err = setContributors(ctx, tx, book)
+	if err != nil {
+		return err
+	}
+
+	err = setGenres(ctx, tx, book)
+	if err != nil {
+		return err
+	}
+
+	if previousWorkID != book.WorkID {
+		err = deleteWorkIfOrphaned(ctx, tx, previousWorkID)
+		if err != nil {
+			return err
+		}
+	}
 
-	_, err := m.DB.Exec(query, args...)
-	return err
+	return tx.Commit()
 }
 
-// Delete a book by ID
-func (m *BookModel) Delete(id int) error {
-	query := `DELETE FROM books WHERE id = $1`
-	_, err := m.DB.Exec(query, id)
-	return err
+// Delete a book by ID. The book's work is removed along with its last edition.
+func (m *BookModel) Delete(ctx context.Context, id int) error {
+	ctx, cancel := m.Timeouts.write(ctx)
+	defer cancel()
+
+	tx, err := beginTx(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `DELETE FROM books WHERE id = $1 RETURNING work_id`
+	var workID int
+	err = tx.QueryRowContext(ctx, query, id).Scan(&workID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrRecordNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	err = deleteWorkIfOrphaned(ctx, tx, workID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-// GetAll retrieves all books with optional filters and pagination.
-func (m *BookModel) GetAll(title string, author string, filters Filters) ([]*Book, Metadata, error) {
+// UpdateRating recomputes the average rating of a book from its reviews.
+// Ratings are aggregated per work, so every edition of the book's work gets
+// the average of the reviews of all of them.
+func (m *BookModel) UpdateRating(ctx context.Context, id int) error {
+	query := `
+        UPDATE books
+        SET average_rating = COALESCE((
+            SELECT AVG(r.rating)
+            FROM reviews r
+            JOIN books reviewed ON reviewed.id = r.book_id
+            WHERE reviewed.work_id = books.work_id
+        ), 0), updated_at = NOW()
+        WHERE work_id = (SELECT work_id FROM books WHERE id = $1)`
+
+	ctx, cancel := m.Timeouts.write(ctx)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetAll retrieves all books with optional filters and pagination. A non-zero
+// genreID restricts the results to that genre and all of its descendants.
+func (m *BookModel) GetAll(ctx context.Context, title string, author string, genreID int, filters Filters) ([]*Book, Metadata, error) {
 	query := fmt.Sprintf(`
-        SELECT COUNT(*) OVER(), id, title, authors, isbn, publication_date, genre, description, average_rating
+        SELECT COUNT(*) OVER(), `+bookColumns+`
         FROM books
         WHERE (title ILIKE '%%' || $1 || '%%' OR $1 = '')
         AND (ARRAY_TO_STRING(authors, ',') ILIKE '%%' || $2 || '%%' OR $2 = '')
+        AND ($5 = 0 OR id IN (
+            SELECT book_id FROM book_genres WHERE genre_id IN (
+                WITH RECURSIVE descendants AS (
+                    SELECT id FROM genres WHERE id = $5
+                    UNION ALL
+                    SELECT g.id FROM genres g JOIN descendants d ON g.parent_id = d.id
+                )
+                SELECT id FROM descendants
+            )
+        ))
         ORDER BY %s %s, id ASC
         LIMIT $3 OFFSET $4`, filters.SortColumn(), filters.SortDirection())
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := m.Timeouts.read(ctx)
+	defer cancel()
+
+	books, totalRecords, err := m.queryBooks(ctx, query, title, author, filters.Limit(), filters.Offset(), genreID)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return books, metadata, nil
+}
+
+// GetAllByAuthor retrieves the books an author contributed to in any role.
+func (m *BookModel) GetAllByAuthor(ctx context.Context, authorID int, filters Filters) ([]*Book, Metadata, error) {
+	query := fmt.Sprintf(`
+        SELECT COUNT(*) OVER(), `+bookColumns+`
+        FROM books
+        WHERE id IN (SELECT book_id FROM book_authors WHERE author_id = $1)
+        ORDER BY %s %s, id ASC
+        LIMIT $2 OFFSET $3`, filters.SortColumn(), filters.SortDirection())
+
+	ctx, cancel := m.Timeouts.read(ctx)
+	defer cancel()
+
+	books, totalRecords, err := m.queryBooks(ctx, query, authorID, filters.Limit(), filters.Offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return books, metadata, nil
+}
+
+// GetAllByWork retrieves every edition of a work, oldest first.
+func (m *BookModel) GetAllByWork(ctx context.Context, workID int) ([]*Book, error) {
+	query := `
+        SELECT COUNT(*) OVER(), ` + bookColumns + `
+        FROM books
+        WHERE work_id = $1
+        ORDER BY publication_date ASC, id ASC`
+
+	ctx, cancel := m.Timeouts.read(ctx)
+	defer cancel()
+
+	books, _, err := m.queryBooks(ctx, query, workID)
+	return books, err
+}
+
+// GetAllByWorks retrieves the editions of several works in one query, keyed
+// by work ID, each oldest first.
+func (m *BookModel) GetAllByWorks(ctx context.Context, workIDs []int) (map[int][]*Book, error) {
+	query := `
+        SELECT COUNT(*) OVER(), ` + bookColumns + `
+        FROM books
+        WHERE work_id = ANY($1)
+        ORDER BY publication_date ASC, id ASC`
+
+	ctx, cancel := m.Timeouts.read(ctx)
+	defer cancel()
+
+	books, _, err := m.queryBooks(ctx, query, pq.Array(workIDs))
+	if err != nil {
+		return nil, err
+	}
+	return editionsByWork(workIDs, books), nil
+}
+
+// editionsByWork groups books by work, giving every work in workIDs a list,
+// empty if none of the books are editions of it.
+func editionsByWork(workIDs []int, books []*Book) map[int][]*Book {
+	editions := make(map[int][]*Book, len(workIDs))
+	for _, id := range workIDs {
+		editions[id] = []*Book{}
+	}
+	for _, book := range books {
+		editions[book.WorkID] = append(editions[book.WorkID], book)
+	}
+	return editions
+}
+
+// GetAllByTag retrieves the books a tag was applied to, ranked by how many
+// users applied it.
+func (m *BookModel) GetAllByTag(ctx context.Context, tag string, filters Filters) ([]*TaggedBook, Metadata, error) {
+	query := fmt.Sprintf(`
+        SELECT COUNT(*) OVER(), `+bookColumns+`, tagged.tag_count
+        FROM books
+        JOIN (
+            SELECT ubt.book_id, COUNT(DISTINCT ubt.user_id) AS tag_count
+            FROM user_book_tags ubt
+            JOIN tags t ON t.id = ubt.tag_id
+            WHERE t.name = $1
+            GROUP BY ubt.book_id
+        ) AS tagged ON tagged.book_id = books.id
+        ORDER BY %s %s, id ASC
+        LIMIT $2 OFFSET $3`, filters.SortColumn(), filters.SortDirection())
+
+	ctx, cancel := m.Timeouts.read(ctx)
 	defer cancel()
 
-	rows, err := m.DB.QueryContext(ctx, query, title, author, filters.Limit(), filters.Offset())
+	rows, err := m.DB.QueryContext(ctx, query, tag, filters.Limit(), filters.Offset())
 	if err != nil {
 		return nil, Metadata{}, err
 	}
 	defer rows.Close()
 
 	totalRecords := 0
+	tagged := []*TaggedBook{}
 	books := []*Book{}
 
 	for rows.Next() {
-		var book Book
-		err := rows.Scan(
-			&totalRecords,
-			&book.ID,
-			&book.Title,
-			pq.Array(&book.Authors),
-			&book.ISBN,
-			&book.PublicationDate,
-			&book.Genre,
-			&book.Description,
-			&book.AverageRating,
-		)
+		taggedBook := TaggedBook{Book: &Book{}}
+		dest := append([]interface{}{&totalRecords}, bookDestinations(taggedBook.Book)...)
+		dest = append(dest, &taggedBook.TagCount)
+		err := rows.Scan(dest...)
 		if err != nil {
 			return nil, Metadata{}, err
 		}
-		books = append(books, &book)
+		tagged = append(tagged, &taggedBook)
+		books = append(books, taggedBook.Book)
 	}
 
 	err = rows.Err()
@@ -160,19 +453,74 @@ func (m *BookModel) GetAll(title string, author string, filters Filters) ([]*Boo
 		return nil, Metadata{}, err
 	}
 
+	err = loadBookRelations(ctx, m.DB, books...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
 	metadata := CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
-	return books, metadata, nil
+	return tagged, metadata, nil
+}
+
+// loadBookRelations fills in the contributors, series and genres of the books.
+func loadBookRelations(ctx context.Context, db queryer, books ...*Book) error {
+	err := loadContributors(ctx, db, books...)
+	if err != nil {
+		return err
+	}
+
+	err = loadSeries(ctx, db, books...)
+	if err != nil {
+		return err
+	}
+
+	return loadGenres(ctx, db, books...)
+}
+
+// queryBooks runs a query selecting COUNT(*) OVER() followed by bookColumns
+// and returns the books with their relations and the total record count.
+func (m *BookModel) queryBooks(ctx context.Context, query string, args ...interface{}) ([]*Book, int, error) {
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	books := []*Book{}
+
+	for rows.Next() {
+		var book Book
+		dest := append([]interface{}{&totalRecords}, bookDestinations(&book)...)
+		err := rows.Scan(dest...)
+		if err != nil {
+			return nil, 0, err
+		}
+		books = append(books, &book)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = loadBookRelations(ctx, m.DB, books...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return books, totalRecords, nil
 }
 
 // GetAll retrieves all reading lists based on the filters.
-func (m *ReadingListModel) GetAll(filters Filters) ([]*ReadingList, Metadata, error) {
+func (m *ReadingListModel) GetAll(ctx context.Context, filters Filters) ([]*ReadingList, Metadata, error) {
 	query := fmt.Sprintf(`
         SELECT COUNT(*) OVER(), id, name, description, status, created_at, updated_at
         FROM reading_lists
         ORDER BY %s %s
         LIMIT $1 OFFSET $2`, filters.SortColumn(), filters.SortDirection())
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := m.Timeouts.read(ctx)
 	defer cancel()
 
 	rows, err := m.DB.QueryContext(ctx, query, filters.Limit(), filters.Offset())
@@ -209,15 +557,15 @@ func (m *ReadingListModel) GetAll(filters Filters) ([]*ReadingList, Metadata, er
 	return readingLists, metadata, nil
 }
 
-func (m *ReadingListModel) GetAllByUser(userID int64, filters Filters) ([]*ReadingList, Metadata, error) {
+func (m *ReadingListModel) GetAllByUser(ctx context.Context, userID int64, filters Filters) ([]*ReadingList, Metadata, error) {
 	query := fmt.Sprintf(`
         SELECT COUNT(*) OVER(), id, name, description, status
         FROM reading_lists
-        WHERE user_id = $1
+        WHERE created_by = $1
         ORDER BY %s %s, id ASC
         LIMIT $2 OFFSET $3`, filters.SortColumn(), filters.SortDirection())
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := m.Timeouts.read(ctx)
 	defer cancel()
 
 	rows, err := m.DB.QueryContext(ctx, query, userID, filters.Limit(), filters.Offset())
@@ -0,0 +1,250 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"unicode"
+
+	"github.com/RayMC17/bookclub-api/internal/validator"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrDuplicateGenre = errors.New("duplicate genre")
+	ErrUnknownGenre   = errors.New("unknown genre")
+)
+
+// Genre is a node in the genre taxonomy. A book can belong to several genres
+// and filtering by a genre includes all of its descendants.
+type Genre struct {
+	ID       int      `json:"id"`
+	Name     string   `json:"name"`
+	Slug     string   `json:"slug"`
+	ParentID *int     `json:"parent_id"`
+	Children []*Genre `json:"children,omitempty"`
+}
+
+// GenreModel handles the database interactions for genres.
+type GenreModel struct {
+	DB       DBTX
+	Timeouts Timeouts
+}
+
+// ValidateGenre validates the genre data.
+func ValidateGenre(v *validator.Validator, genre *Genre, aliases []string) {
+	v.Check(strings.TrimSpace(genre.Name) != "", "name", "must be provided")
+	v.Check(len(genre.Name) <= 100, "name", "must not be more than 100 characters long")
+	v.Check(slugify(genre.Name) != "", "name", "must contain at least one letter or digit")
+	v.Check(genre.ParentID == nil || *genre.ParentID > 0, "parent_id", "must be a valid genre ID")
+
+	for _, alias := range aliases {
+		v.Check(normalizeName(alias) != "", "aliases", "must contain at least one letter or digit")
+		v.Check(len(alias) <= 100, "aliases", "must not be more than 100 characters long")
+	}
+}
+
+// slugify turns a genre name into its URL-friendly slug ("Sci-Fi" -> "sci-fi").
+func slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteRune('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return b.String()
+}
+
+// BuildGenreTree arranges a flat list of genres into a forest of root genres
+// with their children nested below them.
+func BuildGenreTree(genres []*Genre) []*Genre {
+	byID := make(map[int]*Genre, len(genres))
+	for _, genre := range genres {
+		genre.Children = nil
+		byID[genre.ID] = genre
+	}
+
+	roots := []*Genre{}
+	for _, genre := range genres {
+		if genre.ParentID != nil {
+			if parent, ok := byID[*genre.ParentID]; ok {
+				parent.Children = append(parent.Children, genre)
+				continue
+			}
+		}
+		roots = append(roots, genre)
+	}
+	return roots
+}
+
+// Insert adds a new genre and its aliases. The genre's own name is always
+// registered as an alias.
+func (m *GenreModel) Insert(ctx context.Context, genre *Genre, aliases []string) error {
+	ctx, cancel := m.Timeouts.write(ctx)
+	defer cancel()
+
+	tx, err := beginTx(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	genre.Name = strings.TrimSpace(genre.Name)
+	genre.Slug = slugify(genre.Name)
+
+	query := `
+        INSERT INTO genres (name, slug, parent_id)
+        VALUES ($1, $2, $3)
+        RETURNING id`
+	err = tx.QueryRowContext(ctx, query, genre.Name, genre.Slug, genre.ParentID).Scan(&genre.ID)
+	switch {
+	case isUniqueViolation(err):
+		return ErrDuplicateGenre
+	case isForeignKeyViolation(err):
+		return ErrUnknownGenre
+	case err != nil:
+		return err
+	}
+
+	for _, alias := range append([]string{genre.Name}, aliases...) {
+		_, err = tx.ExecContext(ctx, `INSERT INTO genre_aliases (alias, genre_id) VALUES ($1, $2)`, normalizeName(alias), genre.ID)
+		if isUniqueViolation(err) {
+			return ErrDuplicateGenre
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetAll retrieves every genre as a flat list ordered by name.
+func (m *GenreModel) GetAll(ctx context.Context) ([]*Genre, error) {
+	query := `
+        SELECT id, name, slug, parent_id
+        FROM genres
+        ORDER BY name ASC, id ASC`
+
+	ctx, cancel := m.Timeouts.read(ctx)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	genres := []*Genre{}
+	for rows.Next() {
+		var genre Genre
+		err := rows.Scan(&genre.ID, &genre.Name, &genre.Slug, &genre.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		genres = append(genres, &genre)
+	}
+
+	return genres, rows.Err()
+}
+
+// Resolve looks up a genre by its slug, name or one of its aliases, ignoring
+// case, spacing and punctuation.
+func (m *GenreModel) Resolve(ctx context.Context, name string) (*Genre, error) {
+	query := `
+        SELECT g.id, g.name, g.slug, g.parent_id
+        FROM genres g
+        WHERE g.slug = $1
+        OR g.id = (SELECT genre_id FROM genre_aliases WHERE alias = $2)
+        LIMIT 1`
+
+	ctx, cancel := m.Timeouts.read(ctx)
+	defer cancel()
+
+	var genre Genre
+	err := m.DB.QueryRowContext(ctx, query, strings.ToLower(strings.TrimSpace(name)), normalizeName(name)).Scan(
+		&genre.ID, &genre.Name, &genre.Slug, &genre.ParentID,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUnknownGenre
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &genre, nil
+}
+
+// setGenres replaces the genres of a book and reloads their details.
+func setGenres(ctx context.Context, tx DBTX, book *Book) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM book_genres WHERE book_id = $1`, book.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, genre := range book.Genres {
+		query := `
+            INSERT INTO book_genres (book_id, genre_id)
+            VALUES ($1, $2)
+            ON CONFLICT DO NOTHING`
+		_, err = tx.ExecContext(ctx, query, book.ID, genre.ID)
+		if isForeignKeyViolation(err) {
+			return ErrUnknownGenre
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return loadGenres(ctx, tx, book)
+}
+
+// loadGenres fills in the genres of the given books.
+func loadGenres(ctx context.Context, db queryer, books ...*Book) error {
+	if len(books) == 0 {
+		return nil
+	}
+
+	byID := make(map[int]*Book, len(books))
+	ids := make([]int, 0, len(books))
+	for _, book := range books {
+		book.Genres = []Genre{}
+		byID[book.ID] = book
+		ids = append(ids, book.ID)
+	}
+
+	query := `
+        SELECT bg.book_id, g.id, g.name, g.slug, g.parent_id
+        FROM book_genres bg
+        JOIN genres g ON g.id = bg.genre_id
+        WHERE bg.book_id = ANY($1)
+        ORDER BY bg.book_id, g.name`
+
+	rows, err := db.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bookID int
+		var genre Genre
+		err := rows.Scan(&bookID, &genre.ID, &genre.Name, &genre.Slug, &genre.ParentID)
+		if err != nil {
+			return err
+		}
+		if book, ok := byID[bookID]; ok {
+			book.Genres = append(book.Genres, genre)
+		}
+	}
+
+	return rows.Err()
+}
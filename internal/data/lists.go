@@ -3,115 +3,250 @@ package data
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"time"
+
+	"github.com/lib/pq"
 )
 
+// ErrInvalidOrder is returned by Reorder when the new order doesn't name every
+// book on the reading list exactly once.
+var ErrInvalidOrder = errors.New("invalid reading list order")
+
+// ReadingListStatuses lists the statuses a reading list can have. Books on a
+// "completed" list count as read, e.g. when working out the next book of a
+// series.
+var ReadingListStatuses = []string{"currently reading", "completed"}
+
 // ReadingList represents a reading list in the book club system.
 type ReadingList struct {
 	ID          int       `json:"id"`
 	Name        string    `json:"name"`
 	Description string    `json:"description"`
 	CreatedBy   int       `json:"created_by"`
-	Books       []int     `json:"books"`  // List of book IDs
-	Status      string    `json:"status"` // "currently reading" or "completed"
+	Books       []int     `json:"books"` // IDs of the books on the list, in reading order
+	Status      string    `json:"status" enum:"currently reading,completed"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
 // ReadingListModel handles the database interactions for reading lists.
 type ReadingListModel struct {
-	DB *sql.DB
+	DB       DBTX
+	Timeouts Timeouts
 }
 
 // Insert a new reading list
-func (m *ReadingListModel) Insert(list *ReadingList) error {
+func (m *ReadingListModel) Insert(ctx context.Context, list *ReadingList) error {
 	query := `
 		INSERT INTO reading_lists (name, description, created_by, status, created_at)
 		VALUES ($1, $2, $3, $4, $5)
 		RETURNING id`
 	args := []interface{}{list.Name, list.Description, list.CreatedBy, list.Status, time.Now()}
 
-	return m.DB.QueryRow(query, args...).Scan(&list.ID)
+	ctx, cancel := m.Timeouts.write(ctx)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&list.ID)
 }
 
 // Get a single reading list by ID
-func (m *ReadingListModel) Get(id int) (*ReadingList, error) {
+func (m *ReadingListModel) Get(ctx context.Context, id int) (*ReadingList, error) {
 	query := `
 		SELECT id, name, description, created_by, status, created_at
 		FROM reading_lists
 		WHERE id = $1`
 
 	var list ReadingList
-	err := m.DB.QueryRow(query, id).Scan(
+	ctx, cancel := m.Timeouts.read(ctx)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
 		&list.ID, &list.Name, &list.Description, &list.CreatedBy, &list.Status, &list.CreatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, ErrRecordNotFound
 	}
-	return &list, err
+	if err != nil {
+		return nil, err
+	}
+
+	list.Books, err = listBookIDs(ctx, m.DB, list.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// listBookIDs returns the IDs of the books on a reading list in reading order.
+func listBookIDs(ctx context.Context, db queryer, readingListID int) ([]int, error) {
+	query := `
+        SELECT book_id
+        FROM reading_list_books
+        WHERE reading_list_id = $1
+        ORDER BY position, book_id`
+
+	rows, err := db.QueryContext(ctx, query, readingListID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	bookIDs := []int{}
+	for rows.Next() {
+		var bookID int
+		err := rows.Scan(&bookID)
+		if err != nil {
+			return nil, err
+		}
+		bookIDs = append(bookIDs, bookID)
+	}
+	return bookIDs, rows.Err()
 }
 
 // Update an existing reading list
-func (m *ReadingListModel) Update(list *ReadingList) error {
+func (m *ReadingListModel) Update(ctx context.Context, list *ReadingList) error {
 	query := `
 		UPDATE reading_lists
 		SET name = $1, description = $2, status = $3
 		WHERE id = $4`
 	args := []interface{}{list.Name, list.Description, list.Status, list.ID}
 
-	_, err := m.DB.Exec(query, args...)
+	ctx, cancel := m.Timeouts.write(ctx)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, args...)
 	return err
 }
 
 // Delete a reading list by ID
-func (m *ReadingListModel) Delete(id int) error {
-    query := `DELETE FROM reading_lists WHERE id = $1`
-    ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-    defer cancel()
-
-    result, err := m.DB.ExecContext(ctx, query, id)
-    if err != nil {
-        return err
-    }
-
-    rowsAffected, err := result.RowsAffected()
-    if err != nil {
-        return err
-    }
-    if rowsAffected == 0 {
-        return ErrRecordNotFound
-    }
-
-    return nil
+func (m *ReadingListModel) Delete(ctx context.Context, id int) error {
+	query := `DELETE FROM reading_lists WHERE id = $1`
+	ctx, cancel := m.Timeouts.write(ctx)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
 }
 
-func (m *ReadingListModel) AddBook(readingListID int, bookID int) error {
-    query := `
-        INSERT INTO reading_list_books (reading_list_id, book_id)
-        VALUES ($1, $2)
-    `
-    _, err := m.DB.Exec(query, readingListID, bookID)
-    return err
+// AddBook puts a book at the end of a reading list. Adding a book twice is a
+// no-op.
+func (m *ReadingListModel) AddBook(ctx context.Context, readingListID int, bookID int) error {
+	query := `
+        INSERT INTO reading_list_books (reading_list_id, book_id, position)
+        SELECT $1, $2, COALESCE(MAX(position), 0) + 1
+        FROM reading_list_books
+        WHERE reading_list_id = $1
+        ON CONFLICT DO NOTHING`
+	ctx, cancel := m.Timeouts.write(ctx)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, readingListID, bookID)
+	if isForeignKeyViolation(err) {
+		return ErrRecordNotFound
+	}
+	return err
 }
 
-func (m *ReadingListModel) RemoveBook(readingListID int, bookID int) error {
-    query := `
+func (m *ReadingListModel) RemoveBook(ctx context.Context, readingListID int, bookID int) error {
+	query := `
         DELETE FROM reading_list_books
         WHERE reading_list_id = $1 AND book_id = $2
     `
-    result, err := m.DB.Exec(query, readingListID, bookID)
-    if err != nil {
-        return err
-    }
-
-    rowsAffected, err := result.RowsAffected()
-    if err != nil {
-        return err
-    }
-
-    if rowsAffected == 0 {
-        return ErrRecordNotFound
-    }
-
-    return nil
-}
\ No newline at end of file
+	ctx, cancel := m.Timeouts.write(ctx)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, readingListID, bookID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// Reorder puts the books on a reading list in the order of bookIDs, which
+// must name every book on the list exactly once.
+func (m *ReadingListModel) Reorder(ctx context.Context, readingListID int, bookIDs []int) error {
+	ctx, cancel := m.Timeouts.write(ctx)
+	defer cancel()
+
+	tx, err := beginTx(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, `SELECT true FROM reading_lists WHERE id = $1 FOR UPDATE`, readingListID).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrRecordNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	current, err := listBookIDs(ctx, tx, readingListID)
+	if err != nil {
+		return err
+	}
+	if !samePermutation(current, bookIDs) {
+		return ErrInvalidOrder
+	}
+
+	query := `
+        UPDATE reading_list_books rlb
+        SET position = ordered.position
+        FROM unnest($2::int[]) WITH ORDINALITY AS ordered(book_id, position)
+        WHERE rlb.reading_list_id = $1 AND rlb.book_id = ordered.book_id`
+	_, err = tx.ExecContext(ctx, query, readingListID, pq.Array(bookIDs))
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE reading_lists SET updated_at = NOW() WHERE id = $1`, readingListID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// samePermutation reports whether order holds exactly the IDs in current,
+// each once, in any order.
+func samePermutation(current, order []int) bool {
+	if len(current) != len(order) {
+		return false
+	}
+	remaining := make(map[int]bool, len(current))
+	for _, id := range current {
+		remaining[id] = true
+	}
+	for _, id := range order {
+		if !remaining[id] {
+			return false
+		}
+		delete(remaining, id)
+	}
+	return true
+}
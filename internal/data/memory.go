@@ -0,0 +1,1350 @@
+package data
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// InMemory holds in-memory implementations of the repositories. They share
+// one store, so relationships behave like they do in PostgreSQL: deleting a
+// book removes its reviews, reviews are aggregated per work, and so on. All
+// methods are safe for concurrent use. Operations complete immediately, so the
+// contexts passed to them are ignored.
+type InMemory struct {
+	Books        *InMemoryBooks
+	Reviews      *InMemoryReviews
+	ReadingLists *InMemoryReadingLists
+	Users        *InMemoryUsers
+	Genres       *InMemoryGenres
+	Authors      *InMemoryAuthors
+	Works        *InMemoryWorks
+	Series       *InMemorySeries
+	Tags         *InMemoryTags
+}
+
+// NewInMemory returns empty in-memory repositories.
+func NewInMemory() *InMemory {
+	s := &memoryStore{
+		books:         make(map[int]*Book),
+		works:         make(map[int]*Work),
+		authors:       make(map[string]int),
+		authorRecords: make(map[int]*Author),
+		genres:        make(map[int]*Genre),
+		aliases:       make(map[string]int),
+		reviews:       make(map[int64]*Review),
+		lists:         make(map[int]*ReadingList),
+		listBooks:     make(map[int][]int),
+		users:         make(map[int]*User),
+		series:        make(map[int]*Series),
+		seriesEntries: make(map[int]map[int]float64),
+		tags:          make(map[userTag]bool),
+		sequences:     make(map[string]int),
+	}
+
+	return &InMemory{
+		Books:        &InMemoryBooks{s},
+		Reviews:      &InMemoryReviews{s},
+		ReadingLists: &InMemoryReadingLists{s},
+		Users:        &InMemoryUsers{s},
+		Genres:       &InMemoryGenres{s},
+		Authors:      &InMemoryAuthors{s},
+		Works:        &InMemoryWorks{s},
+		Series:       &InMemorySeries{s},
+		Tags:         &InMemoryTags{s},
+	}
+}
+
+// Models returns the in-memory repositories as Models. Their WithTx runs the
+// callback directly: every single operation is atomic, but a failing callback
+// doesn't undo the operations it already made.
+func (m *InMemory) Models() Models {
+	return Models{
+		Books:        m.Books,
+		Reviews:      m.Reviews,
+		ReadingLists: m.ReadingLists,
+		Users:        m.Users,
+		Genres:       m.Genres,
+		Authors:      m.Authors,
+		Works:        m.Works,
+		Series:       m.Series,
+		Tags:         m.Tags,
+	}
+}
+
+// memoryStore is the state shared by the in-memory repositories.
+type memoryStore struct {
+	mu sync.RWMutex
+
+	books         map[int]*Book
+	works         map[int]*Work
+	authors       map[string]int // normalized name -> author ID
+	authorRecords map[int]*Author
+	genres        map[int]*Genre
+	aliases       map[string]int // normalized alias -> genre ID
+	reviews       map[int64]*Review
+	lists         map[int]*ReadingList
+	listBooks     map[int][]int // reading list ID -> book IDs in reading order
+	users         map[int]*User
+	series        map[int]*Series
+	seriesEntries map[int]map[int]float64 // series ID -> work ID -> position
+	tags          map[userTag]bool
+	sequences     map[string]int
+}
+
+// userTag is a tag a user applied to a book.
+type userTag struct {
+	userID int
+	bookID int
+	name   string
+}
+
+// nextID returns the next value of the named sequence, starting at 1.
+func (s *memoryStore) nextID(name string) int {
+	s.sequences[name]++
+	return s.sequences[name]
+}
+
+// sortAndPaginate orders items by the filters' sort column, breaking ties by
+// ascending ID like the SQL queries do, and returns the requested page.
+func sortAndPaginate[T any](items []T, filters Filters, id func(T) int64, columns map[string]func(a, b T) int) ([]T, Metadata) {
+	compare := columns[filters.SortColumn()]
+	descending := filters.SortDirection() == "DESC"
+
+	sort.SliceStable(items, func(i, j int) bool {
+		if compare != nil {
+			c := compare(items[i], items[j])
+			if descending {
+				c = -c
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+		return id(items[i]) < id(items[j])
+	})
+
+	total := len(items)
+	start := min(filters.Offset(), total)
+	end := min(start+filters.Limit(), total)
+	return items[start:end], CalculateMetadata(total, filters.Page, filters.PageSize)
+}
+
+// containsFold reports whether substr is within s, ignoring case, like ILIKE.
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// InMemoryBooks is an in-memory implementation of Books.
+type InMemoryBooks struct {
+	s *memoryStore
+}
+
+func copyBook(book *Book) *Book {
+	c := *book
+	c.Authors = append([]string{}, book.Authors...)
+	c.Contributors = append([]Contributor{}, book.Contributors...)
+	c.Series = append([]SeriesInfo{}, book.Series...)
+	c.Genres = append([]Genre{}, book.Genres...)
+	return &c
+}
+
+var bookSortColumns = map[string]func(a, b *Book) int{
+	"id":    func(a, b *Book) int { return cmp.Compare(a.ID, b.ID) },
+	"title": func(a, b *Book) int { return cmp.Compare(a.Title, b.Title) },
+	"author": func(a, b *Book) int {
+		return cmp.Compare(strings.Join(a.Authors, ","), strings.Join(b.Authors, ","))
+	},
+}
+
+// prepare resolves the contributors and genres of a book the way
+// setContributors and setGenres do, without changing anything if the book
+// refers to an unknown author or genre.
+func (m *InMemoryBooks) prepare(book *Book) error {
+	for _, c := range book.Contributors {
+		if _, ok := m.s.authorRecords[c.AuthorID]; c.AuthorID > 0 && !ok {
+			return ErrUnknownAuthor
+		}
+	}
+
+	genres := make([]Genre, 0, len(book.Genres))
+	seen := make(map[int]bool, len(book.Genres))
+	for _, g := range book.Genres {
+		genre, ok := m.s.genres[g.ID]
+		if !ok {
+			return ErrUnknownGenre
+		}
+		if !seen[g.ID] {
+			seen[g.ID] = true
+			genres = append(genres, Genre{ID: genre.ID, Name: genre.Name, Slug: genre.Slug, ParentID: genre.ParentID})
+		}
+	}
+	sort.Slice(genres, func(i, j int) bool { return genres[i].Name < genres[j].Name })
+
+	contributors := make([]Contributor, 0, len(book.Contributors))
+	for _, c := range book.Contributors {
+		if c.AuthorID == 0 {
+			c.AuthorID = m.s.authorNamed(c.Name)
+		}
+		c.Name = m.s.authorRecords[c.AuthorID].Name
+
+		credited := slices.ContainsFunc(contributors, func(other Contributor) bool {
+			return other.AuthorID == c.AuthorID && other.Role == c.Role
+		})
+		if !credited {
+			contributors = append(contributors, c)
+		}
+	}
+
+	book.Contributors = contributors
+	book.Authors = []string{}
+	for _, c := range contributors {
+		if c.Role == "author" {
+			book.Authors = append(book.Authors, c.Name)
+		}
+	}
+
+	book.Genres = genres
+	book.Series = []SeriesInfo{}
+	return nil
+}
+
+// authorNamed returns the ID of the author with the name, once normalized,
+// creating the author if needed.
+func (s *memoryStore) authorNamed(name string) int {
+	id, ok := s.authors[normalizeName(name)]
+	if !ok {
+		id = s.nextID("authors")
+		s.authors[normalizeName(name)] = id
+		s.authorRecords[id] = &Author{ID: id, Name: strings.TrimSpace(name), CreatedAt: time.Now()}
+	}
+	return id
+}
+
+// read returns a copy of a stored book with its series membership.
+func (m *InMemoryBooks) read(book *Book) *Book {
+	c := copyBook(book)
+	c.Series = m.s.seriesInfo(book.WorkID)
+	return c
+}
+
+// Insert adds a book, creating a new work for it when WorkID is zero.
+func (m *InMemoryBooks) Insert(ctx context.Context, book *Book) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if book.WorkID != 0 && m.s.works[book.WorkID] == nil {
+		return ErrUnknownWork
+	}
+
+	err := m.prepare(book)
+	if err != nil {
+		return err
+	}
+
+	if book.WorkID == 0 {
+		book.WorkID = m.s.nextID("works")
+		m.s.works[book.WorkID] = &Work{ID: book.WorkID, Title: book.Title, CreatedAt: time.Now()}
+	}
+
+	book.ID = m.s.nextID("books")
+	book.UpdatedAt = time.Now()
+	m.s.books[book.ID] = copyBook(book)
+	return nil
+}
+
+// Get retrieves a book by ID.
+func (m *InMemoryBooks) Get(ctx context.Context, id int) (*Book, error) {
+	m.s.mu.RLock()
+	defer m.s.mu.RUnlock()
+
+	book, ok := m.s.books[id]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+	return m.read(book), nil
+}
+
+// Update replaces a book, removing its previous work if it was the last
+// edition of it.
+func (m *InMemoryBooks) Update(ctx context.Context, book *Book) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	previous, ok := m.s.books[book.ID]
+	if !ok {
+		return ErrRecordNotFound
+	}
+	if m.s.works[book.WorkID] == nil {
+		return ErrUnknownWork
+	}
+
+	err := m.prepare(book)
+	if err != nil {
+		return err
+	}
+
+	book.UpdatedAt = time.Now()
+	m.s.books[book.ID] = copyBook(book)
+	m.deleteWorkIfOrphaned(previous.WorkID)
+	return nil
+}
+
+// Delete removes a book along with its reviews, reading list entries and
+// tags.
+func (m *InMemoryBooks) Delete(ctx context.Context, id int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	book, ok := m.s.books[id]
+	if !ok {
+		return ErrRecordNotFound
+	}
+
+	delete(m.s.books, id)
+	for reviewID, review := range m.s.reviews {
+		if review.BookID == int64(id) {
+			delete(m.s.reviews, reviewID)
+		}
+	}
+	for listID, books := range m.s.listBooks {
+		m.s.listBooks[listID] = slices.DeleteFunc(books, func(bookID int) bool { return bookID == id })
+	}
+	for tag := range m.s.tags {
+		if tag.bookID == id {
+			delete(m.s.tags, tag)
+		}
+	}
+	m.deleteWorkIfOrphaned(book.WorkID)
+	return nil
+}
+
+// UpdateRating sets the average rating of every edition of the book's work
+// to the average of the reviews of all of them.
+func (m *InMemoryBooks) UpdateRating(ctx context.Context, id int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	book, ok := m.s.books[id]
+	if !ok {
+		return ErrRecordNotFound
+	}
+
+	sum, count := 0, 0
+	for _, review := range m.s.reviews {
+		reviewed, ok := m.s.books[int(review.BookID)]
+		if ok && reviewed.WorkID == book.WorkID {
+			sum += review.Rating
+			count++
+		}
+	}
+
+	rating := 0.0
+	if count > 0 {
+		rating = float64(sum) / float64(count)
+	}
+	for _, edition := range m.s.books {
+		if edition.WorkID == book.WorkID {
+			edition.AverageRating = rating
+			edition.UpdatedAt = time.Now()
+		}
+	}
+	return nil
+}
+
+// deleteWorkIfOrphaned removes the work, and its series entries, when it no
+// longer has any editions.
+func (m *InMemoryBooks) deleteWorkIfOrphaned(workID int) {
+	for _, book := range m.s.books {
+		if book.WorkID == workID {
+			return
+		}
+	}
+	delete(m.s.works, workID)
+	for _, entries := range m.s.seriesEntries {
+		delete(entries, workID)
+	}
+}
+
+// GetAll retrieves the books matching the filters. A non-zero genreID
+// includes the genre's descendants.
+func (m *InMemoryBooks) GetAll(ctx context.Context, title string, author string, genreID int, filters Filters) ([]*Book, Metadata, error) {
+	m.s.mu.RLock()
+	defer m.s.mu.RUnlock()
+
+	var genreIDs map[int]bool
+	if genreID != 0 {
+		genreIDs = m.s.genreDescendants(genreID)
+	}
+
+	return m.query(filters, func(book *Book) bool {
+		if !containsFold(book.Title, title) || !containsFold(strings.Join(book.Authors, ","), author) {
+			return false
+		}
+		if genreIDs == nil {
+			return true
+		}
+		for _, genre := range book.Genres {
+			if genreIDs[genre.ID] {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// GetAllByAuthor retrieves the books an author contributed to in any role.
+func (m *InMemoryBooks) GetAllByAuthor(ctx context.Context, authorID int, filters Filters) ([]*Book, Metadata, error) {
+	m.s.mu.RLock()
+	defer m.s.mu.RUnlock()
+
+	return m.query(filters, func(book *Book) bool {
+		for _, c := range book.Contributors {
+			if c.AuthorID == authorID {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// GetAllByWork retrieves every edition of a work, oldest first.
+func (m *InMemoryBooks) GetAllByWork(ctx context.Context, workID int) ([]*Book, error) {
+	m.s.mu.RLock()
+	defer m.s.mu.RUnlock()
+
+	books := []*Book{}
+	for _, book := range m.s.books {
+		if book.WorkID == workID {
+			books = append(books, m.read(book))
+		}
+	}
+	sort.Slice(books, func(i, j int) bool {
+		if !books[i].PublicationDate.Equal(books[j].PublicationDate) {
+			return books[i].PublicationDate.Before(books[j].PublicationDate)
+		}
+		return books[i].ID < books[j].ID
+	})
+	return books, nil
+}
+
+// GetAllByWorks retrieves the editions of several works, keyed by work ID,
+// each oldest first.
+func (m *InMemoryBooks) GetAllByWorks(ctx context.Context, workIDs []int) (map[int][]*Book, error) {
+	var books []*Book
+	for _, id := range workIDs {
+		editions, err := m.GetAllByWork(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		books = append(books, editions...)
+	}
+	return editionsByWork(workIDs, books), nil
+}
+
+// GetAllByTag retrieves the books a tag was applied to, ranked by how many
+// users applied it.
+func (m *InMemoryBooks) GetAllByTag(ctx context.Context, tag string, filters Filters) ([]*TaggedBook, Metadata, error) {
+	m.s.mu.RLock()
+	defer m.s.mu.RUnlock()
+
+	counts := make(map[int]int)
+	for t := range m.s.tags {
+		if t.name == tag {
+			counts[t.bookID]++
+		}
+	}
+
+	tagged := []*TaggedBook{}
+	for bookID, count := range counts {
+		tagged = append(tagged, &TaggedBook{Book: m.read(m.s.books[bookID]), TagCount: count})
+	}
+
+	tagged, metadata := sortAndPaginate(tagged, filters, func(b *TaggedBook) int64 { return int64(b.ID) }, taggedBookSortColumns)
+	return tagged, metadata, nil
+}
+
+var taggedBookSortColumns = map[string]func(a, b *TaggedBook) int{
+	"tag_count": func(a, b *TaggedBook) int { return cmp.Compare(a.TagCount, b.TagCount) },
+	"title":     func(a, b *TaggedBook) int { return cmp.Compare(a.Title, b.Title) },
+}
+
+func (m *InMemoryBooks) query(filters Filters, match func(*Book) bool) ([]*Book, Metadata, error) {
+	books := []*Book{}
+	for _, book := range m.s.books {
+		if match(book) {
+			books = append(books, m.read(book))
+		}
+	}
+
+	books, metadata := sortAndPaginate(books, filters, func(b *Book) int64 { return int64(b.ID) }, bookSortColumns)
+	return books, metadata, nil
+}
+
+// InMemoryReviews is an in-memory implementation of Reviews. The reviews
+// whose writer isn't recorded are matched to users by their author's
+// username.
+type InMemoryReviews struct {
+	s *memoryStore
+}
+
+// writtenBy reports whether the review was written by user.
+func writtenBy(review *Review, user *User) bool {
+	if review.UserID != 0 {
+		return review.UserID == int64(user.ID)
+	}
+	return review.Author == user.Username
+}
+
+var reviewSortColumns = map[string]func(a, b *Review) int{
+	"id":     func(a, b *Review) int { return cmp.Compare(a.ID, b.ID) },
+	"rating": func(a, b *Review) int { return cmp.Compare(a.Rating, b.Rating) },
+	"author": func(a, b *Review) int { return cmp.Compare(a.Author, b.Author) },
+}
+
+// Insert adds a review of an existing book.
+func (m *InMemoryReviews) Insert(ctx context.Context, review *Review) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if _, ok := m.s.books[int(review.BookID)]; !ok {
+		return ErrRecordNotFound
+	}
+
+	review.ID = int64(m.s.nextID("reviews"))
+	review.CreatedAt = time.Now()
+	review.UpdatedAt = review.CreatedAt
+	c := *review
+	m.s.reviews[review.ID] = &c
+	return nil
+}
+
+// Get retrieves a review by ID.
+func (m *InMemoryReviews) Get(ctx context.Context, id int64) (*Review, error) {
+	m.s.mu.RLock()
+	defer m.s.mu.RUnlock()
+
+	review, ok := m.s.reviews[id]
+	if !ok {
+		return nil, ErrNoRecord
+	}
+	c := *review
+	return &c, nil
+}
+
+// Update changes the author, rating and content of a review.
+func (m *InMemoryReviews) Update(ctx context.Context, review *Review) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	stored, ok := m.s.reviews[review.ID]
+	if !ok {
+		return ErrNoRecord
+	}
+	stored.Author = review.Author
+	stored.Rating = review.Rating
+	stored.Content = review.Content
+	stored.UpdatedAt = time.Now()
+	review.UpdatedAt = stored.UpdatedAt
+	return nil
+}
+
+// Delete removes a review.
+func (m *InMemoryReviews) Delete(ctx context.Context, id int64) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if _, ok := m.s.reviews[id]; !ok {
+		return ErrNoRecord
+	}
+	delete(m.s.reviews, id)
+	return nil
+}
+
+// DeleteAllByUser removes the reviews written by a user and returns the IDs of
+// the books they reviewed.
+func (m *InMemoryReviews) DeleteAllByUser(ctx context.Context, userID int64) ([]int64, error) {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	bookIDs := []int64{}
+	user, ok := m.s.users[int(userID)]
+	if !ok {
+		return bookIDs, nil
+	}
+
+	for id, review := range m.s.reviews {
+		if writtenBy(review, user) {
+			delete(m.s.reviews, id)
+			if !slices.Contains(bookIDs, review.BookID) {
+				bookIDs = append(bookIDs, review.BookID)
+			}
+		}
+	}
+	slices.Sort(bookIDs)
+	return bookIDs, nil
+}
+
+// GetAll retrieves the reviews of every edition of the book's work.
+func (m *InMemoryReviews) GetAll(ctx context.Context, bookID int64, rating int, author string, filters Filters) ([]*Review, Metadata, error) {
+	m.s.mu.RLock()
+	defer m.s.mu.RUnlock()
+
+	book, ok := m.s.books[int(bookID)]
+	if !ok {
+		return []*Review{}, Metadata{}, nil
+	}
+
+	return m.query(filters, func(review *Review) bool {
+		reviewed, ok := m.s.books[int(review.BookID)]
+		return ok && reviewed.WorkID == book.WorkID &&
+			containsFold(review.Author, author) &&
+			(rating == 0 || review.Rating == rating)
+	})
+}
+
+// GetAllByUser retrieves the reviews written by a user.
+func (m *InMemoryReviews) GetAllByUser(ctx context.Context, userID int64, filters Filters) ([]*Review, Metadata, error) {
+	m.s.mu.RLock()
+	defer m.s.mu.RUnlock()
+
+	user, ok := m.s.users[int(userID)]
+	if !ok {
+		return []*Review{}, Metadata{}, nil
+	}
+
+	return m.query(filters, func(review *Review) bool {
+		return writtenBy(review, user)
+	})
+}
+
+func (m *InMemoryReviews) query(filters Filters, match func(*Review) bool) ([]*Review, Metadata, error) {
+	reviews := []*Review{}
+	for _, review := range m.s.reviews {
+		if match(review) {
+			c := *review
+			reviews = append(reviews, &c)
+		}
+	}
+
+	reviews, metadata := sortAndPaginate(reviews, filters, func(r *Review) int64 { return r.ID }, reviewSortColumns)
+	return reviews, metadata, nil
+}
+
+// InMemoryReadingLists is an in-memory implementation of ReadingLists. Like
+// the PostgreSQL model, the books on a list are only changed through AddBook,
+// RemoveBook and Reorder, and only Get returns them.
+type InMemoryReadingLists struct {
+	s *memoryStore
+}
+
+var readingListSortColumns = map[string]func(a, b *ReadingList) int{
+	"id":   func(a, b *ReadingList) int { return cmp.Compare(a.ID, b.ID) },
+	"name": func(a, b *ReadingList) int { return cmp.Compare(a.Name, b.Name) },
+}
+
+func copyReadingList(list *ReadingList) *ReadingList {
+	c := *list
+	c.Books = nil
+	return &c
+}
+
+// Insert adds a new reading list.
+func (m *InMemoryReadingLists) Insert(ctx context.Context, list *ReadingList) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	list.ID = m.s.nextID("reading_lists")
+	list.CreatedAt = time.Now()
+	list.UpdatedAt = list.CreatedAt
+	m.s.lists[list.ID] = copyReadingList(list)
+	m.s.listBooks[list.ID] = []int{}
+	return nil
+}
+
+// Get retrieves a reading list by ID.
+func (m *InMemoryReadingLists) Get(ctx context.Context, id int) (*ReadingList, error) {
+	m.s.mu.RLock()
+	defer m.s.mu.RUnlock()
+
+	list, ok := m.s.lists[id]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+	c := copyReadingList(list)
+	c.Books = slices.Clone(m.s.listBooks[id])
+	return c, nil
+}
+
+// Update changes the name, description and status of a reading list.
+func (m *InMemoryReadingLists) Update(ctx context.Context, list *ReadingList) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	stored, ok := m.s.lists[list.ID]
+	if !ok {
+		return ErrRecordNotFound
+	}
+	stored.Name = list.Name
+	stored.Description = list.Description
+	stored.Status = list.Status
+	return nil
+}
+
+// Delete removes a reading list.
+func (m *InMemoryReadingLists) Delete(ctx context.Context, id int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if _, ok := m.s.lists[id]; !ok {
+		return ErrRecordNotFound
+	}
+	delete(m.s.lists, id)
+	delete(m.s.listBooks, id)
+	return nil
+}
+
+// AddBook puts a book at the end of a reading list. Adding a book twice is a
+// no-op.
+func (m *InMemoryReadingLists) AddBook(ctx context.Context, readingListID int, bookID int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	books, ok := m.s.listBooks[readingListID]
+	if !ok {
+		return ErrRecordNotFound
+	}
+	if _, ok := m.s.books[bookID]; !ok {
+		return ErrRecordNotFound
+	}
+	if !slices.Contains(books, bookID) {
+		m.s.listBooks[readingListID] = append(books, bookID)
+	}
+	return nil
+}
+
+// RemoveBook takes a book off a reading list.
+func (m *InMemoryReadingLists) RemoveBook(ctx context.Context, readingListID int, bookID int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	books := m.s.listBooks[readingListID]
+	i := slices.Index(books, bookID)
+	if i < 0 {
+		return ErrRecordNotFound
+	}
+	m.s.listBooks[readingListID] = slices.Delete(books, i, i+1)
+	return nil
+}
+
+// Reorder puts the books on a reading list in the order of bookIDs, which
+// must name every book on the list exactly once.
+func (m *InMemoryReadingLists) Reorder(ctx context.Context, readingListID int, bookIDs []int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	list, ok := m.s.lists[readingListID]
+	if !ok {
+		return ErrRecordNotFound
+	}
+	if !samePermutation(m.s.listBooks[readingListID], bookIDs) {
+		return ErrInvalidOrder
+	}
+	m.s.listBooks[readingListID] = slices.Clone(bookIDs)
+	list.UpdatedAt = time.Now()
+	return nil
+}
+
+// GetAll retrieves all reading lists.
+func (m *InMemoryReadingLists) GetAll(ctx context.Context, filters Filters) ([]*ReadingList, Metadata, error) {
+	m.s.mu.RLock()
+	defer m.s.mu.RUnlock()
+
+	return m.query(filters, func(*ReadingList) bool { return true })
+}
+
+// GetAllByUser retrieves the reading lists created by a user.
+func (m *InMemoryReadingLists) GetAllByUser(ctx context.Context, userID int64, filters Filters) ([]*ReadingList, Metadata, error) {
+	m.s.mu.RLock()
+	defer m.s.mu.RUnlock()
+
+	return m.query(filters, func(list *ReadingList) bool { return int64(list.CreatedBy) == userID })
+}
+
+func (m *InMemoryReadingLists) query(filters Filters, match func(*ReadingList) bool) ([]*ReadingList, Metadata, error) {
+	lists := []*ReadingList{}
+	for _, list := range m.s.lists {
+		if match(list) {
+			lists = append(lists, copyReadingList(list))
+		}
+	}
+
+	lists, metadata := sortAndPaginate(lists, filters, func(l *ReadingList) int64 { return int64(l.ID) }, readingListSortColumns)
+	return lists, metadata, nil
+}
+
+// InMemoryUsers is an in-memory implementation of Users.
+type InMemoryUsers struct {
+	s *memoryStore
+}
+
+// Insert adds a new user.
+func (m *InMemoryUsers) Insert(ctx context.Context, user *User) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	user.ID = m.s.nextID("users")
+	user.CreatedAt = time.Now()
+	user.UpdatedAt = user.CreatedAt
+	c := *user
+	m.s.users[user.ID] = &c
+	return nil
+}
+
+// Get retrieves a user by ID.
+func (m *InMemoryUsers) Get(ctx context.Context, id int) (*User, error) {
+	m.s.mu.RLock()
+	defer m.s.mu.RUnlock()
+
+	user, ok := m.s.users[id]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+	c := *user
+	return &c, nil
+}
+
+// Update changes the username and email of a user.
+func (m *InMemoryUsers) Update(ctx context.Context, user *User) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	stored, ok := m.s.users[user.ID]
+	if !ok {
+		return ErrRecordNotFound
+	}
+	stored.Username = user.Username
+	stored.Email = user.Email
+	return nil
+}
+
+// Delete removes a user along with their reading lists and tags.
+func (m *InMemoryUsers) Delete(ctx context.Context, id int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if _, ok := m.s.users[id]; !ok {
+		return ErrRecordNotFound
+	}
+	delete(m.s.users, id)
+	for listID, list := range m.s.lists {
+		if list.CreatedBy == id {
+			delete(m.s.lists, listID)
+			delete(m.s.listBooks, listID)
+		}
+	}
+	for tag := range m.s.tags {
+		if tag.userID == id {
+			delete(m.s.tags, tag)
+		}
+	}
+	return nil
+}
+
+// InMemoryGenres is an in-memory implementation of Genres. It starts out
+// empty rather than with the taxonomy seeded by the migrations.
+type InMemoryGenres struct {
+	s *memoryStore
+}
+
+// Insert adds a genre and its aliases.
+func (m *InMemoryGenres) Insert(ctx context.Context, genre *Genre, aliases []string) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	genre.Name = strings.TrimSpace(genre.Name)
+	genre.Slug = slugify(genre.Name)
+
+	if genre.ParentID != nil {
+		if _, ok := m.s.genres[*genre.ParentID]; !ok {
+			return ErrUnknownGenre
+		}
+	}
+
+	normalized := []string{}
+	for _, alias := range append([]string{genre.Name}, aliases...) {
+		alias = normalizeName(alias)
+		if _, exists := m.s.aliases[alias]; exists {
+			return ErrDuplicateGenre
+		}
+		normalized = append(normalized, alias)
+	}
+	for _, existing := range m.s.genres {
+		if existing.Slug == genre.Slug || existing.Name == genre.Name {
+			return ErrDuplicateGenre
+		}
+	}
+
+	genre.ID = m.s.nextID("genres")
+	c := *genre
+	c.Children = nil
+	m.s.genres[genre.ID] = &c
+	for _, alias := range normalized {
+		m.s.aliases[alias] = genre.ID
+	}
+	return nil
+}
+
+// GetAll retrieves every genre as a flat list ordered by name.
+func (m *InMemoryGenres) GetAll(ctx context.Context) ([]*Genre, error) {
+	m.s.mu.RLock()
+	defer m.s.mu.RUnlock()
+
+	genres := []*Genre{}
+	for _, genre := range m.s.genres {
+		c := *genre
+		genres = append(genres, &c)
+	}
+	sort.Slice(genres, func(i, j int) bool {
+		if genres[i].Name != genres[j].Name {
+			return genres[i].Name < genres[j].Name
+		}
+		return genres[i].ID < genres[j].ID
+	})
+	return genres, nil
+}
+
+// Resolve looks up a genre by its slug, name or one of its aliases.
+func (m *InMemoryGenres) Resolve(ctx context.Context, name string) (*Genre, error) {
+	m.s.mu.RLock()
+	defer m.s.mu.RUnlock()
+
+	slug := strings.ToLower(strings.TrimSpace(name))
+	for _, genre := range m.s.genres {
+		if genre.Slug == slug {
+			c := *genre
+			return &c, nil
+		}
+	}
+
+	if id, ok := m.s.aliases[normalizeName(name)]; ok {
+		c := *m.s.genres[id]
+		return &c, nil
+	}
+
+	return nil, ErrUnknownGenre
+}
+
+// genreDescendants returns the IDs of a genre and all of its descendants.
+func (s *memoryStore) genreDescendants(id int) map[int]bool {
+	ids := map[int]bool{id: true}
+	for changed := true; changed; {
+		changed = false
+		for _, genre := range s.genres {
+			if genre.ParentID != nil && ids[*genre.ParentID] && !ids[genre.ID] {
+				ids[genre.ID] = true
+				changed = true
+			}
+		}
+	}
+	return ids
+}
+
+// InMemoryAuthors is an in-memory implementation of Authors.
+type InMemoryAuthors struct {
+	s *memoryStore
+}
+
+var authorSortColumns = map[string]func(a, b *Author) int{
+	"id":   func(a, b *Author) int { return cmp.Compare(a.ID, b.ID) },
+	"name": func(a, b *Author) int { return cmp.Compare(a.Name, b.Name) },
+}
+
+// Insert adds a new author.
+func (m *InMemoryAuthors) Insert(ctx context.Context, author *Author) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if _, exists := m.s.authors[normalizeName(author.Name)]; exists {
+		return ErrDuplicateAuthor
+	}
+
+	author.ID = m.s.nextID("authors")
+	author.Name = strings.TrimSpace(author.Name)
+	author.CreatedAt = time.Now()
+	c := *author
+	m.s.authors[normalizeName(author.Name)] = author.ID
+	m.s.authorRecords[author.ID] = &c
+	return nil
+}
+
+// Get retrieves an author by ID.
+func (m *InMemoryAuthors) Get(ctx context.Context, id int) (*Author, error) {
+	m.s.mu.RLock()
+	defer m.s.mu.RUnlock()
+
+	author, ok := m.s.authorRecords[id]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+	c := *author
+	return &c, nil
+}
+
+// Update renames an author and the credits on the books they contributed to.
+func (m *InMemoryAuthors) Update(ctx context.Context, author *Author) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	stored, ok := m.s.authorRecords[author.ID]
+	if !ok {
+		return ErrRecordNotFound
+	}
+	if id, exists := m.s.authors[normalizeName(author.Name)]; exists && id != author.ID {
+		return ErrDuplicateAuthor
+	}
+
+	delete(m.s.authors, normalizeName(stored.Name))
+	stored.Name = strings.TrimSpace(author.Name)
+	stored.Bio = author.Bio
+	m.s.authors[normalizeName(stored.Name)] = stored.ID
+
+	m.s.refreshCredits()
+	return nil
+}
+
+// Delete removes an author who isn't credited on any book, returning
+// ErrAuthorHasBooks otherwise.
+func (m *InMemoryAuthors) Delete(ctx context.Context, id int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	author, ok := m.s.authorRecords[id]
+	if !ok {
+		return ErrRecordNotFound
+	}
+	for _, book := range m.s.books {
+		for _, c := range book.Contributors {
+			if c.AuthorID == id {
+				return ErrAuthorHasBooks
+			}
+		}
+	}
+
+	delete(m.s.authorRecords, id)
+	delete(m.s.authors, normalizeName(author.Name))
+	return nil
+}
+
+// GetAll retrieves the authors whose name contains name.
+func (m *InMemoryAuthors) GetAll(ctx context.Context, name string, filters Filters) ([]*Author, Metadata, error) {
+	m.s.mu.RLock()
+	defer m.s.mu.RUnlock()
+
+	authors := []*Author{}
+	for _, author := range m.s.authorRecords {
+		if containsFold(author.Name, name) {
+			c := *author
+			authors = append(authors, &c)
+		}
+	}
+
+	authors, metadata := sortAndPaginate(authors, filters, func(a *Author) int64 { return int64(a.ID) }, authorSortColumns)
+	return authors, metadata, nil
+}
+
+// Merge folds the duplicate authors into the target author, re-crediting
+// their books to the target in the same roles.
+func (m *InMemoryAuthors) Merge(ctx context.Context, targetID int, duplicateIDs []int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if _, ok := m.s.authorRecords[targetID]; !ok {
+		return ErrRecordNotFound
+	}
+	for _, id := range duplicateIDs {
+		if _, ok := m.s.authorRecords[id]; !ok || id == targetID {
+			return ErrRecordNotFound
+		}
+	}
+
+	for _, book := range m.s.books {
+		contributors := make([]Contributor, 0, len(book.Contributors))
+		for _, c := range book.Contributors {
+			if slices.Contains(duplicateIDs, c.AuthorID) {
+				c.AuthorID = targetID
+			}
+			credited := slices.ContainsFunc(contributors, func(other Contributor) bool {
+				return other.AuthorID == c.AuthorID && other.Role == c.Role
+			})
+			if !credited {
+				contributors = append(contributors, c)
+			}
+		}
+		book.Contributors = contributors
+	}
+
+	for _, id := range duplicateIDs {
+		delete(m.s.authors, normalizeName(m.s.authorRecords[id].Name))
+		delete(m.s.authorRecords, id)
+	}
+
+	m.s.refreshCredits()
+	return nil
+}
+
+// refreshCredits brings the contributor names and the authors of every book
+// in line with the author records, like refreshBookAuthors does.
+func (s *memoryStore) refreshCredits() {
+	for _, book := range s.books {
+		book.Authors = []string{}
+		for i, c := range book.Contributors {
+			book.Contributors[i].Name = s.authorRecords[c.AuthorID].Name
+			if c.Role == "author" {
+				book.Authors = append(book.Authors, book.Contributors[i].Name)
+			}
+		}
+	}
+}
+
+// InMemoryWorks is an in-memory implementation of Works.
+type InMemoryWorks struct {
+	s *memoryStore
+}
+
+// Get retrieves a work by ID together with the ratings of all its editions.
+func (m *InMemoryWorks) Get(ctx context.Context, id int) (*Work, error) {
+	m.s.mu.RLock()
+	defer m.s.mu.RUnlock()
+
+	work, ok := m.s.works[id]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+
+	c := *work
+	sum := 0
+	for _, review := range m.s.reviews {
+		reviewed, ok := m.s.books[int(review.BookID)]
+		if ok && reviewed.WorkID == id {
+			sum += review.Rating
+			c.RatingsCount++
+		}
+	}
+	if c.RatingsCount > 0 {
+		c.AverageRating = float64(sum) / float64(c.RatingsCount)
+	}
+	return &c, nil
+}
+
+// InMemorySeries is an in-memory implementation of SeriesStore.
+type InMemorySeries struct {
+	s *memoryStore
+}
+
+var seriesSortColumns = map[string]func(a, b *Series) int{
+	"id":   func(a, b *Series) int { return cmp.Compare(a.ID, b.ID) },
+	"name": func(a, b *Series) int { return cmp.Compare(a.Name, b.Name) },
+}
+
+// Insert adds a new series.
+func (m *InMemorySeries) Insert(ctx context.Context, series *Series) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	series.ID = m.s.nextID("series")
+	series.CreatedAt = time.Now()
+	c := *series
+	c.Entries = nil
+	m.s.series[series.ID] = &c
+	m.s.seriesEntries[series.ID] = make(map[int]float64)
+	return nil
+}
+
+// Get retrieves a series by ID with its entries in reading order.
+func (m *InMemorySeries) Get(ctx context.Context, id int) (*Series, error) {
+	m.s.mu.RLock()
+	defer m.s.mu.RUnlock()
+
+	series, ok := m.s.series[id]
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+
+	c := *series
+	c.Entries = m.s.entries(id)
+	return &c, nil
+}
+
+// GetAll retrieves the series whose name contains name.
+func (m *InMemorySeries) GetAll(ctx context.Context, name string, filters Filters) ([]*Series, Metadata, error) {
+	m.s.mu.RLock()
+	defer m.s.mu.RUnlock()
+
+	all := []*Series{}
+	for _, series := range m.s.series {
+		if containsFold(series.Name, name) {
+			c := *series
+			all = append(all, &c)
+		}
+	}
+
+	all, metadata := sortAndPaginate(all, filters, func(s *Series) int64 { return int64(s.ID) }, seriesSortColumns)
+	return all, metadata, nil
+}
+
+// SetEntry adds a work to a series or moves it to a new position.
+func (m *InMemorySeries) SetEntry(ctx context.Context, seriesID int, entry *SeriesEntry) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	entries, ok := m.s.seriesEntries[seriesID]
+	if !ok || m.s.works[entry.WorkID] == nil {
+		return ErrUnknownWork
+	}
+	for workID, position := range entries {
+		if workID != entry.WorkID && position == entry.Position {
+			return ErrDuplicatePosition
+		}
+	}
+
+	entries[entry.WorkID] = entry.Position
+	return nil
+}
+
+// RemoveEntry removes a work from a series.
+func (m *InMemorySeries) RemoveEntry(ctx context.Context, seriesID int, workID int) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if _, ok := m.s.seriesEntries[seriesID][workID]; !ok {
+		return ErrRecordNotFound
+	}
+	delete(m.s.seriesEntries[seriesID], workID)
+	return nil
+}
+
+// GetNextForUser returns, for every series the user has started, the first
+// entry whose work isn't on one of their "completed" reading lists.
+func (m *InMemorySeries) GetNextForUser(ctx context.Context, userID int64) ([]*NextInSeries, error) {
+	m.s.mu.RLock()
+	defer m.s.mu.RUnlock()
+
+	finished := make(map[int]bool)
+	for listID, list := range m.s.lists {
+		if int64(list.CreatedBy) != userID || list.Status != "completed" {
+			continue
+		}
+		for _, bookID := range m.s.listBooks[listID] {
+			finished[m.s.books[bookID].WorkID] = true
+		}
+	}
+
+	next := []*NextInSeries{}
+	for seriesID, series := range m.s.series {
+		entries := m.s.entries(seriesID)
+		started := slices.ContainsFunc(entries, func(e *SeriesEntry) bool { return finished[e.WorkID] })
+		if !started {
+			continue
+		}
+		for _, entry := range entries {
+			if !finished[entry.WorkID] {
+				next = append(next, &NextInSeries{SeriesID: seriesID, SeriesName: series.Name, SeriesEntry: *entry})
+				break
+			}
+		}
+	}
+
+	sort.Slice(next, func(i, j int) bool { return next[i].SeriesID < next[j].SeriesID })
+	return next, nil
+}
+
+// entries returns the entries of a series in reading order.
+func (s *memoryStore) entries(seriesID int) []*SeriesEntry {
+	entries := []*SeriesEntry{}
+	for workID, position := range s.seriesEntries[seriesID] {
+		entries = append(entries, &SeriesEntry{WorkID: workID, Title: s.works[workID].Title, Position: position})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Position < entries[j].Position })
+	return entries
+}
+
+// seriesInfo returns the series a work belongs to, ordered by name.
+func (s *memoryStore) seriesInfo(workID int) []SeriesInfo {
+	infos := []SeriesInfo{}
+	for seriesID, entries := range s.seriesEntries {
+		if position, ok := entries[workID]; ok {
+			infos = append(infos, SeriesInfo{SeriesID: seriesID, Name: s.series[seriesID].Name, Position: position})
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Name != infos[j].Name {
+			return infos[i].Name < infos[j].Name
+		}
+		return infos[i].SeriesID < infos[j].SeriesID
+	})
+	return infos
+}
+
+// InMemoryTags is an in-memory implementation of Tags.
+type InMemoryTags struct {
+	s *memoryStore
+}
+
+// Add applies a tag to a book on behalf of a user.
+func (m *InMemoryTags) Add(ctx context.Context, userID int, bookID int, name string) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	if _, ok := m.s.users[userID]; !ok {
+		return ErrRecordNotFound
+	}
+	if _, ok := m.s.books[bookID]; !ok {
+		return ErrRecordNotFound
+	}
+	m.s.tags[userTag{userID, bookID, name}] = true
+	return nil
+}
+
+// Remove takes a user's tag off a book.
+func (m *InMemoryTags) Remove(ctx context.Context, userID int, bookID int, name string) error {
+	m.s.mu.Lock()
+	defer m.s.mu.Unlock()
+
+	tag := userTag{userID, bookID, name}
+	if !m.s.tags[tag] {
+		return ErrRecordNotFound
+	}
+	delete(m.s.tags, tag)
+	return nil
+}
+
+// GetCountsForBook returns every tag applied to a book with the number of
+// users that applied it, most popular first.
+func (m *InMemoryTags) GetCountsForBook(ctx context.Context, bookID int) ([]*TagCount, error) {
+	m.s.mu.RLock()
+	defer m.s.mu.RUnlock()
+
+	counts := make(map[string]int)
+	for tag := range m.s.tags {
+		if tag.bookID == bookID {
+			counts[tag.name]++
+		}
+	}
+
+	tagCounts := []*TagCount{}
+	for name, count := range counts {
+		tagCounts = append(tagCounts, &TagCount{Name: name, Count: count})
+	}
+	sort.Slice(tagCounts, func(i, j int) bool {
+		if tagCounts[i].Count != tagCounts[j].Count {
+			return tagCounts[i].Count > tagCounts[j].Count
+		}
+		return tagCounts[i].Name < tagCounts[j].Name
+	})
+	return tagCounts, nil
+}
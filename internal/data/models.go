@@ -0,0 +1,191 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/RayMC17/bookclub-api/internal/trace"
+	"github.com/lib/pq"
+)
+
+// DBTX is implemented by both *sql.DB and *sql.Tx, so a model can run its
+// queries either on the pool or inside a transaction.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// txScope is a transaction started by beginTx. When the model was already
+// running inside a transaction the scope joins it, and committing or rolling
+// back is left to whoever started that transaction.
+type txScope struct {
+	DBTX
+	tx *sql.Tx
+}
+
+// beginTx starts a transaction on db, or joins the transaction db already is.
+// The statements of the transaction are traced.
+func beginTx(ctx context.Context, db DBTX) (txScope, error) {
+	pool, ok := primaryDB(untraceDB(db))
+	if !ok {
+		return txScope{DBTX: traceDB(db)}, nil
+	}
+
+	tx, err := pool.BeginTx(ctx, nil)
+	if err != nil {
+		return txScope{}, err
+	}
+	return txScope{DBTX: traceDB(tx), tx: tx}, nil
+}
+
+func (s txScope) Commit() error {
+	if s.tx == nil {
+		return nil
+	}
+	return s.tx.Commit()
+}
+
+func (s txScope) Rollback() error {
+	if s.tx == nil {
+		return nil
+	}
+	return s.tx.Rollback()
+}
+
+// Retry policy for transactions aborted with a serialization failure.
+const (
+	maxTxAttempts  = 5
+	txRetryBackoff = 20 * time.Millisecond
+)
+
+// Models groups the models so that a unit of work touching several tables
+// can run them all in one transaction with WithTx.
+type Models struct {
+	Books        Books
+	Reviews      Reviews
+	ReadingLists ReadingLists
+	Users        Users
+	Genres       Genres
+	Authors      Authors
+	Works        Works
+	Series       SeriesStore
+	Tags         Tags
+
+	// db is nil inside a transaction and for the in-memory models, in which
+	// case WithTx runs the callback directly.
+	db       *sql.DB
+	timeouts Timeouts
+}
+
+// NewModels returns the PostgreSQL models using the given pool.
+func NewModels(db *sql.DB, timeouts Timeouts) Models {
+	models := newModels(db, timeouts)
+	models.db = db
+	models.timeouts = timeouts
+	return models
+}
+
+// NewReplicatedModels returns the PostgreSQL models using the replica set,
+// whose replicas serve the read-only queries.
+func NewReplicatedModels(replicas *ReplicaSet, timeouts Timeouts) Models {
+	models := newModels(replicas, timeouts)
+	models.db = replicas.Primary()
+	models.timeouts = timeouts
+	return models
+}
+
+// primaryDB returns the pool db runs its writes on, unless db is a
+// transaction.
+func primaryDB(db DBTX) (*sql.DB, bool) {
+	switch db := db.(type) {
+	case *sql.DB:
+		return db, true
+	case *ReplicaSet:
+		return db.Primary(), true
+	default:
+		return nil, false
+	}
+}
+
+// newModels returns the models using db, with their statements traced.
+func newModels(db DBTX, timeouts Timeouts) Models {
+	db = traceDB(db)
+	return Models{
+		Books:        &BookModel{DB: db, Timeouts: timeouts},
+		Reviews:      &ReviewModel{DB: db, Timeouts: timeouts},
+		ReadingLists: &ReadingListModel{DB: db, Timeouts: timeouts},
+		Users:        &UserModel{DB: db, Timeouts: timeouts},
+		Genres:       &GenreModel{DB: db, Timeouts: timeouts},
+		Authors:      &AuthorModel{DB: db, Timeouts: timeouts},
+		Works:        &WorkModel{DB: db, Timeouts: timeouts},
+		Series:       &SeriesModel{DB: db, Timeouts: timeouts},
+		Tags:         &TagModel{DB: db, Timeouts: timeouts},
+	}
+}
+
+// WithTx runs fn with models bound to a serializable transaction, which is
+// committed when fn returns nil and rolled back otherwise. If the transaction
+// is aborted by a serialization failure, fn is run again in a new transaction
+// after a short backoff, so fn must not have side effects outside the
+// database. Calling WithTx on the models passed to fn runs the inner
+// callback in the same transaction.
+func (m Models) WithTx(ctx context.Context, fn func(tx Models) error) error {
+	if m.db == nil {
+		return fn(m)
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := m.runTx(ctx, fn)
+		if !isSerializationFailure(err) || attempt == maxTxAttempts {
+			return err
+		}
+
+		// Back off exponentially with jitter so that the conflicting
+		// transactions don't collide again.
+		backoff := txRetryBackoff << (attempt - 1)
+		backoff += time.Duration(rand.Int63n(int64(backoff)))
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+func (m Models) runTx(ctx context.Context, fn func(tx Models) error) (err error) {
+	ctx, span := trace.Start(ctx, "transaction", trace.KindInternal)
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+
+	ctx, cancel := m.timeouts.write(ctx)
+	defer cancel()
+
+	tx, err := m.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = fn(newModels(tx, m.timeouts))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// isSerializationFailure reports whether err is a PostgreSQL
+// serialization_failure, raised when concurrent transactions conflict.
+func isSerializationFailure(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "40001"
+}
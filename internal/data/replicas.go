@@ -0,0 +1,167 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"sync/atomic"
+
+	"github.com/lib/pq"
+)
+
+type (
+	replicaOKContextKey struct{}
+	primaryContextKey   struct{}
+)
+
+// allowReplica marks ctx as belonging to a query that only reads data, which
+// a ReplicaSet may then send to a replica.
+func allowReplica(ctx context.Context) context.Context {
+	return context.WithValue(ctx, replicaOKContextKey{}, true)
+}
+
+// WithPrimary returns a copy of ctx whose queries all go to the primary, so
+// that a request reads its own writes, or those of a recent request, before
+// they have reached the replicas.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryContextKey{}, true)
+}
+
+// usesReplica reports whether a query run with ctx may go to a replica.
+func usesReplica(ctx context.Context) bool {
+	replicaOK, _ := ctx.Value(replicaOKContextKey{}).(bool)
+	primary, _ := ctx.Value(primaryContextKey{}).(bool)
+	return replicaOK && !primary
+}
+
+// ReplicaSet is a DBTX that sends the read-only queries of the models to the
+// read replicas in turn, and every other statement to the primary. A replica
+// failing with a connection error is skipped until CheckHealth finds it
+// reachable again; its query is retried on the primary.
+type ReplicaSet struct {
+	primary  *sql.DB
+	replicas []*replica
+	next     atomic.Uint64
+}
+
+type replica struct {
+	name    string
+	db      *sql.DB
+	healthy atomic.Bool
+}
+
+// ReplicaStatus describes a replica, as reported by the health checks.
+type ReplicaStatus struct {
+	Name    string      `json:"name"`
+	Healthy bool        `json:"healthy"`
+	Stats   sql.DBStats `json:"stats"`
+}
+
+// NewReplicaSet returns a set without replicas, which sends everything to
+// primary until replicas are added.
+func NewReplicaSet(primary *sql.DB) *ReplicaSet {
+	return &ReplicaSet{primary: primary}
+}
+
+// AddReplica adds a replica under a name used in the health reports, such as
+// its redacted DSN. Replicas must be added before the set is used.
+func (s *ReplicaSet) AddReplica(name string, db *sql.DB) {
+	r := &replica{name: name, db: db}
+	r.healthy.Store(true)
+	s.replicas = append(s.replicas, r)
+}
+
+// Primary returns the pool of the primary.
+func (s *ReplicaSet) Primary() *sql.DB {
+	return s.primary
+}
+
+// pick returns the next healthy replica, or nil if there is none.
+func (s *ReplicaSet) pick() *replica {
+	n := uint64(len(s.replicas))
+	if n == 0 {
+		return nil
+	}
+	start := s.next.Add(1)
+	for i := uint64(0); i < n; i++ {
+		r := s.replicas[(start+i)%n]
+		if r.healthy.Load() {
+			return r
+		}
+	}
+	return nil
+}
+
+func (s *ReplicaSet) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return s.primary.ExecContext(ctx, query, args...)
+}
+
+func (s *ReplicaSet) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if r := s.replicaFor(ctx); r != nil {
+		rows, err := r.db.QueryContext(ctx, query, args...)
+		if !isConnectionError(ctx, err) {
+			return rows, err
+		}
+		r.healthy.Store(false)
+	}
+	return s.primary.QueryContext(ctx, query, args...)
+}
+
+func (s *ReplicaSet) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	if r := s.replicaFor(ctx); r != nil {
+		row := r.db.QueryRowContext(ctx, query, args...)
+		if !isConnectionError(ctx, row.Err()) {
+			return row
+		}
+		r.healthy.Store(false)
+	}
+	return s.primary.QueryRowContext(ctx, query, args...)
+}
+
+func (s *ReplicaSet) replicaFor(ctx context.Context) *replica {
+	if !usesReplica(ctx) {
+		return nil
+	}
+	return s.pick()
+}
+
+// CheckHealth pings every replica, bringing back the ones that answer and
+// skipping the others.
+func (s *ReplicaSet) CheckHealth(ctx context.Context) {
+	for _, r := range s.replicas {
+		r.healthy.Store(r.db.PingContext(ctx) == nil)
+	}
+}
+
+// Status reports the health and pool statistics of each replica.
+func (s *ReplicaSet) Status() []ReplicaStatus {
+	statuses := make([]ReplicaStatus, 0, len(s.replicas))
+	for _, r := range s.replicas {
+		statuses = append(statuses, ReplicaStatus{Name: r.name, Healthy: r.healthy.Load(), Stats: r.db.Stats()})
+	}
+	return statuses
+}
+
+// isConnectionError reports whether err means the database couldn't be
+// reached, as opposed to the query failing on it. A query outliving ctx says
+// nothing about the database, which may just be slow to answer it.
+func isConnectionError(ctx context.Context, err error) bool {
+	if err == nil || errors.Is(err, sql.ErrNoRows) {
+		return false
+	}
+	if ctx.Err() != nil || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	// connection_exception, cannot_connect_now and admin_shutdown
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && (pqErr.Code.Class() == "08" || pqErr.Code == "57P03" || pqErr.Code == "57P01")
+}
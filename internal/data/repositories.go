@@ -0,0 +1,115 @@
+package data
+
+import "context"
+
+// The interfaces below describe the storage used by the HTTP handlers. The
+// PostgreSQL models implement them for production, and the in-memory
+// implementations returned by NewInMemory implement them for tests.
+
+// Books stores book editions.
+type Books interface {
+	Insert(ctx context.Context, book *Book) error
+	Get(ctx context.Context, id int) (*Book, error)
+	Update(ctx context.Context, book *Book) error
+	Delete(ctx context.Context, id int) error
+	UpdateRating(ctx context.Context, id int) error
+	GetAll(ctx context.Context, title string, author string, genreID int, filters Filters) ([]*Book, Metadata, error)
+	GetAllByAuthor(ctx context.Context, authorID int, filters Filters) ([]*Book, Metadata, error)
+	GetAllByWork(ctx context.Context, workID int) ([]*Book, error)
+	GetAllByWorks(ctx context.Context, workIDs []int) (map[int][]*Book, error)
+	GetAllByTag(ctx context.Context, tag string, filters Filters) ([]*TaggedBook, Metadata, error)
+}
+
+// Reviews stores book reviews. Missing reviews are reported as ErrNoRecord.
+type Reviews interface {
+	Insert(ctx context.Context, review *Review) error
+	Get(ctx context.Context, id int64) (*Review, error)
+	Update(ctx context.Context, review *Review) error
+	Delete(ctx context.Context, id int64) error
+	DeleteAllByUser(ctx context.Context, userID int64) ([]int64, error)
+	GetAll(ctx context.Context, bookID int64, rating int, author string, filters Filters) ([]*Review, Metadata, error)
+	GetAllByUser(ctx context.Context, userID int64, filters Filters) ([]*Review, Metadata, error)
+}
+
+// ReadingLists stores reading lists and the books on them.
+type ReadingLists interface {
+	Insert(ctx context.Context, list *ReadingList) error
+	Get(ctx context.Context, id int) (*ReadingList, error)
+	Update(ctx context.Context, list *ReadingList) error
+	Delete(ctx context.Context, id int) error
+	AddBook(ctx context.Context, readingListID int, bookID int) error
+	RemoveBook(ctx context.Context, readingListID int, bookID int) error
+	Reorder(ctx context.Context, readingListID int, bookIDs []int) error
+	GetAll(ctx context.Context, filters Filters) ([]*ReadingList, Metadata, error)
+	GetAllByUser(ctx context.Context, userID int64, filters Filters) ([]*ReadingList, Metadata, error)
+}
+
+// Users stores user accounts.
+type Users interface {
+	Insert(ctx context.Context, user *User) error
+	Get(ctx context.Context, id int) (*User, error)
+	Update(ctx context.Context, user *User) error
+	Delete(ctx context.Context, id int) error
+}
+
+// Genres stores the genre taxonomy.
+type Genres interface {
+	Insert(ctx context.Context, genre *Genre, aliases []string) error
+	GetAll(ctx context.Context) ([]*Genre, error)
+	Resolve(ctx context.Context, name string) (*Genre, error)
+}
+
+// Authors stores the people credited on books.
+type Authors interface {
+	Insert(ctx context.Context, author *Author) error
+	Get(ctx context.Context, id int) (*Author, error)
+	Update(ctx context.Context, author *Author) error
+	Delete(ctx context.Context, id int) error
+	GetAll(ctx context.Context, name string, filters Filters) ([]*Author, Metadata, error)
+	Merge(ctx context.Context, targetID int, duplicateIDs []int) error
+}
+
+// Works stores the works grouping the editions of a book.
+type Works interface {
+	Get(ctx context.Context, id int) (*Work, error)
+}
+
+// SeriesStore stores series and their entries. Unlike the others it isn't
+// named after its records, as Series already is the record type.
+type SeriesStore interface {
+	Insert(ctx context.Context, series *Series) error
+	Get(ctx context.Context, id int) (*Series, error)
+	GetAll(ctx context.Context, name string, filters Filters) ([]*Series, Metadata, error)
+	SetEntry(ctx context.Context, seriesID int, entry *SeriesEntry) error
+	RemoveEntry(ctx context.Context, seriesID int, workID int) error
+	GetNextForUser(ctx context.Context, userID int64) ([]*NextInSeries, error)
+}
+
+// Tags stores the personal tags users apply to books.
+type Tags interface {
+	Add(ctx context.Context, userID int, bookID int, name string) error
+	Remove(ctx context.Context, userID int, bookID int, name string) error
+	GetCountsForBook(ctx context.Context, bookID int) ([]*TagCount, error)
+}
+
+var (
+	_ Books        = (*BookModel)(nil)
+	_ Reviews      = (*ReviewModel)(nil)
+	_ ReadingLists = (*ReadingListModel)(nil)
+	_ Users        = (*UserModel)(nil)
+	_ Genres       = (*GenreModel)(nil)
+	_ Authors      = (*AuthorModel)(nil)
+	_ Works        = (*WorkModel)(nil)
+	_ SeriesStore  = (*SeriesModel)(nil)
+	_ Tags         = (*TagModel)(nil)
+
+	_ Books        = (*InMemoryBooks)(nil)
+	_ Reviews      = (*InMemoryReviews)(nil)
+	_ ReadingLists = (*InMemoryReadingLists)(nil)
+	_ Users        = (*InMemoryUsers)(nil)
+	_ Genres       = (*InMemoryGenres)(nil)
+	_ Authors      = (*InMemoryAuthors)(nil)
+	_ Works        = (*InMemoryWorks)(nil)
+	_ SeriesStore  = (*InMemorySeries)(nil)
+	_ Tags         = (*InMemoryTags)(nil)
+)
@@ -1,203 +1,275 @@
 package data
 
 import (
-    "context"
-    "database/sql"
-    "errors"
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
-    "time"
+	"time"
 
-    "github.com/RayMC17/bookclub-api/internal/validator"
+	"github.com/RayMC17/bookclub-api/internal/validator"
 )
 
 var ErrNoRecord = errors.New("record not found")
 
 // Review represents a review for a book.
 type Review struct {
-    ID        int64     `json:"id"`
-    BookID    int64     `json:"book_id"`
-    Author    string    `json:"author"`
-    Rating    int       `json:"rating"`
-    Content   string    `json:"content"`
-    CreatedAt time.Time `json:"created_at"`
+	ID     int64 `json:"id"`
+	BookID int64 `json:"book_id"`
+	// UserID is the user who wrote the review, or 0 when it isn't known.
+	UserID    int64     `json:"-"`
+	Author    string    `json:"author"`
+	Rating    int       `json:"rating"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 // ReviewModel wraps a SQL database connection pool.
 type ReviewModel struct {
-    DB *sql.DB
+	DB       DBTX
+	Timeouts Timeouts
 }
 
 // ValidateReview validates the review data.
 func ValidateReview(v *validator.Validator, review *Review) {
-    v.Check(review.Author != "", "author", "must be provided")
-    v.Check(len(review.Author) <= 100, "author", "must not be more than 100 characters long")
+	v.Check(review.Author != "", "author", "must be provided")
+	v.Check(len(review.Author) <= 100, "author", "must not be more than 100 characters long")
 
-    v.Check(review.Rating >= 1 && review.Rating <= 5, "rating", "must be between 1 and 5")
-    
-    v.Check(review.Content != "", "content", "must be provided")
-    v.Check(len(review.Content) <= 1000, "content", "must not be more than 1000 characters long")
+	v.Check(review.Rating >= 1 && review.Rating <= 5, "rating", "must be between 1 and 5")
+
+	v.Check(review.Content != "", "content", "must be provided")
+	v.Check(len(review.Content) <= 1000, "content", "must not be more than 1000 characters long")
 }
 
 // Insert adds a new review to the database.
-func (m *ReviewModel) Insert(review *Review) error {
-    query := `
-        INSERT INTO reviews (book_id, author, rating, content, created_at)
-        VALUES ($1, $2, $3, $4, NOW())
-        RETURNING id, created_at`
+func (m *ReviewModel) Insert(ctx context.Context, review *Review) error {
+	query := `
+        INSERT INTO reviews (book_id, user_id, author, rating, content, created_at)
+        VALUES ($1, NULLIF($2, 0), $3, $4, $5, NOW())
+        RETURNING id, created_at, updated_at`
+
+	args := []interface{}{review.BookID, review.UserID, review.Author, review.Rating, review.Content}
 
-    args := []interface{}{review.BookID, review.Author, review.Rating, review.Content}
+	ctx, cancel := m.Timeouts.write(ctx)
+	defer cancel()
 
-    return m.DB.QueryRow(query, args...).Scan(&review.ID, &review.CreatedAt)
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&review.ID, &review.CreatedAt, &review.UpdatedAt)
 }
 
 // Get retrieves a specific review by ID.
-func (m *ReviewModel) Get(id int64) (*Review, error) {
-    query := `
-        SELECT id, book_id, author, rating, content, created_at
+func (m *ReviewModel) Get(ctx context.Context, id int64) (*Review, error) {
+	query := `
+        SELECT id, book_id, author, rating, content, created_at, updated_at
         FROM reviews
         WHERE id = $1`
 
-    var review Review
+	var review Review
 
-    err := m.DB.QueryRow(query, id).Scan(
-        &review.ID,
-        &review.BookID,
-        &review.Author,
-        &review.Rating,
-        &review.Content,
-        &review.CreatedAt,
-    )
+	ctx, cancel := m.Timeouts.read(ctx)
+	defer cancel()
 
-    if err == sql.ErrNoRows {
-        return nil, ErrNoRecord
-    } else if err != nil {
-        return nil, err
-    }
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&review.ID,
+		&review.BookID,
+		&review.Author,
+		&review.Rating,
+		&review.Content,
+		&review.CreatedAt,
+		&review.UpdatedAt,
+	)
 
-    return &review, nil
+	if err == sql.ErrNoRows {
+		return nil, ErrNoRecord
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &review, nil
 }
 
 // Update modifies the data of a specific review.
-func (m *ReviewModel) Update(review *Review) error {
-    query := `
+func (m *ReviewModel) Update(ctx context.Context, review *Review) error {
+	query := `
         UPDATE reviews
-        SET author = $1, rating = $2, content = $3
-        WHERE id = $4`
+        SET author = $1, rating = $2, content = $3, updated_at = NOW()
+        WHERE id = $4
+        RETURNING updated_at`
+
+	args := []interface{}{review.Author, review.Rating, review.Content, review.ID}
 
-    args := []interface{}{review.Author, review.Rating, review.Content, review.ID}
+	ctx, cancel := m.Timeouts.write(ctx)
+	defer cancel()
 
-    _, err := m.DB.Exec(query, args...)
-    return err
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&review.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrNoRecord
+	}
+	return err
 }
 
 // Delete removes a specific review from the database.
-func (m *ReviewModel) Delete(id int64) error {
-    query := `
+func (m *ReviewModel) Delete(ctx context.Context, id int64) error {
+	query := `
         DELETE FROM reviews
         WHERE id = $1`
 
-    _, err := m.DB.Exec(query, id)
-    return err
+	ctx, cancel := m.Timeouts.write(ctx)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrNoRecord
+	}
+
+	return nil
 }
 
+// DeleteAllByUser removes the reviews written by a user and returns the IDs
+// of the books they reviewed, so their ratings can be recomputed.
+func (m *ReviewModel) DeleteAllByUser(ctx context.Context, userID int64) ([]int64, error) {
+	query := `
+        DELETE FROM reviews
+        WHERE user_id = $1
+        RETURNING book_id`
+
+	ctx, cancel := m.Timeouts.write(ctx)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	seen := make(map[int64]bool)
+	bookIDs := []int64{}
+	for rows.Next() {
+		var bookID int64
+		err := rows.Scan(&bookID)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[bookID] {
+			seen[bookID] = true
+			bookIDs = append(bookIDs, bookID)
+		}
+	}
+	return bookIDs, rows.Err()
+}
 
-// GetAll retrieves all reviews for a specific book with optional filters for pagination and sorting.
-func (m *ReviewModel) GetAll(bookID int64, author string, filters Filters) ([]*Review, Metadata, error) {
-    query := `
-        SELECT COUNT(*) OVER(), id, book_id, author, rating, content, created_at
+// GetAll retrieves all reviews for a book with optional filters for pagination and sorting.
+// Reviews are aggregated at the work level, so the reviews of every edition of
+// the book's work are returned.
+func (m *ReviewModel) GetAll(ctx context.Context, bookID int64, rating int, author string, filters Filters) ([]*Review, Metadata, error) {
+	query := `
+        SELECT COUNT(*) OVER(), id, book_id, author, rating, content, created_at, updated_at
         FROM reviews
-        WHERE (book_id = $1)
-        AND (author ILIKE '%' || $2 || '%' OR $2 = '')
+        WHERE book_id IN (
+            SELECT id FROM books WHERE work_id = (SELECT work_id FROM books WHERE id = $1)
+        )
+        AND (author ILIKE '%%' || $2 || '%%' OR $2 = '')
+        AND (rating = $5 OR $5 = 0)
         ORDER BY %s %s, id ASC
         LIMIT $3 OFFSET $4`
 
-    formattedQuery := formatQuery(query, filters.SortColumn(), filters.SortDirection())
-
-    ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-    defer cancel()
-
-    rows, err := m.DB.QueryContext(ctx, formattedQuery, bookID, author, filters.Limit(), filters.Offset())
-    if err != nil {
-        return nil, Metadata{}, err
-    }
-    defer rows.Close()
-
-    totalRecords := 0
-    reviews := []*Review{}
-
-    for rows.Next() {
-        var review Review
-        err := rows.Scan(
-            &totalRecords,
-            &review.ID,
-            &review.BookID,
-            &review.Author,
-            &review.Rating,
-            &review.Content,
-            &review.CreatedAt,
-        )
-        if err != nil {
-            return nil, Metadata{}, err
-        }
-        reviews = append(reviews, &review)
-    }
-
-    err = rows.Err()
-    if err != nil {
-        return nil, Metadata{}, err
-    }
-
-    metadata := CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
-    return reviews, metadata, nil
+	formattedQuery := formatQuery(query, filters.SortColumn(), filters.SortDirection())
+
+	ctx, cancel := m.Timeouts.read(ctx)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, formattedQuery, bookID, author, filters.Limit(), filters.Offset(), rating)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	reviews := []*Review{}
+
+	for rows.Next() {
+		var review Review
+		err := rows.Scan(
+			&totalRecords,
+			&review.ID,
+			&review.BookID,
+			&review.Author,
+			&review.Rating,
+			&review.Content,
+			&review.CreatedAt,
+			&review.UpdatedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		reviews = append(reviews, &review)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return reviews, metadata, nil
 }
 
 // Helper function to safely format the SQL query with sort options.
 func formatQuery(query, sortColumn, sortDirection string) string {
-    return fmt.Sprintf(query, sortColumn, sortDirection)
+	return fmt.Sprintf(query, sortColumn, sortDirection)
 }
 
-
-func (m *ReviewModel) GetAllByUser(userID int64, filters Filters) ([]*Review, Metadata, error) {
-    query := fmt.Sprintf(`
-        SELECT COUNT(*) OVER(), id, book_id, author, content, rating
+func (m *ReviewModel) GetAllByUser(ctx context.Context, userID int64, filters Filters) ([]*Review, Metadata, error) {
+	query := fmt.Sprintf(`
+        SELECT COUNT(*) OVER(), id, book_id, author, content, rating, created_at, updated_at
         FROM reviews
         WHERE user_id = $1
         ORDER BY %s %s, id ASC
         LIMIT $2 OFFSET $3`, filters.SortColumn(), filters.SortDirection())
 
-    ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-    defer cancel()
-
-    rows, err := m.DB.QueryContext(ctx, query, userID, filters.Limit(), filters.Offset())
-    if err != nil {
-        return nil, Metadata{}, err
-    }
-    defer rows.Close()
-
-    totalRecords := 0
-    reviews := []*Review{}
-
-    for rows.Next() {
-        var review Review
-        err := rows.Scan(
-            &totalRecords,
-            &review.ID,
-            &review.BookID,
-            &review.Author,
-            &review.Content,
-            &review.Rating,
-        )
-        if err != nil {
-            return nil, Metadata{}, err
-        }
-        reviews = append(reviews, &review)
-    }
-
-    err = rows.Err()
-    if err != nil {
-        return nil, Metadata{}, err
-    }
-
-    metadata := CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
-    return reviews, metadata, nil
-}
\ No newline at end of file
+	ctx, cancel := m.Timeouts.read(ctx)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, filters.Limit(), filters.Offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	reviews := []*Review{}
+
+	for rows.Next() {
+		var review Review
+		err := rows.Scan(
+			&totalRecords,
+			&review.ID,
+			&review.BookID,
+			&review.Author,
+			&review.Content,
+			&review.Rating,
+			&review.CreatedAt,
+			&review.UpdatedAt,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		reviews = append(reviews, &review)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return reviews, metadata, nil
+}
@@ -0,0 +1,308 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/RayMC17/bookclub-api/internal/validator"
+	"github.com/lib/pq"
+)
+
+var ErrDuplicatePosition = errors.New("duplicate series position")
+
+// Series is an ordered collection of works, e.g. a trilogy.
+type Series struct {
+	ID          int            `json:"id"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	CreatedAt   time.Time      `json:"created_at"`
+	Entries     []*SeriesEntry `json:"entries,omitempty"`
+}
+
+// SeriesEntry places a work at a position in a series. Positions may be
+// fractional (2.5) for novellas set between two numbered entries.
+type SeriesEntry struct {
+	WorkID   int     `json:"work_id"`
+	Title    string  `json:"title"`
+	Position float64 `json:"position"`
+	Books    []*Book `json:"books"`
+}
+
+// SeriesInfo is the series membership shown on a book.
+type SeriesInfo struct {
+	SeriesID int     `json:"series_id"`
+	Name     string  `json:"name"`
+	Position float64 `json:"position"`
+}
+
+// NextInSeries is the next unread entry of a series a user has started.
+type NextInSeries struct {
+	SeriesID   int    `json:"series_id"`
+	SeriesName string `json:"series_name"`
+	SeriesEntry
+}
+
+// SeriesModel handles the database interactions for series.
+type SeriesModel struct {
+	DB       DBTX
+	Timeouts Timeouts
+}
+
+// ValidateSeries validates the series data.
+func ValidateSeries(v *validator.Validator, series *Series) {
+	v.Check(strings.TrimSpace(series.Name) != "", "name", "must be provided")
+	v.Check(len(series.Name) <= 255, "name", "must not be more than 255 characters long")
+	v.Check(len(series.Description) <= 1000, "description", "must not be more than 1000 characters long")
+}
+
+// ValidateSeriesEntry validates an entry being added to a series.
+func ValidateSeriesEntry(v *validator.Validator, entry *SeriesEntry) {
+	v.Check(entry.WorkID > 0, "work_id", "must be provided")
+	v.Check(entry.Position >= 0, "position", "must not be negative")
+	v.Check(entry.Position < 10000, "position", "must be less than 10000")
+	v.Check(math.Abs(entry.Position*100-math.Round(entry.Position*100)) < 1e-9, "position", "must not have more than two decimal places")
+}
+
+// Insert adds a new series to the database.
+func (m *SeriesModel) Insert(ctx context.Context, series *Series) error {
+	query := `
+        INSERT INTO series (name, description)
+        VALUES ($1, $2)
+        RETURNING id, created_at`
+
+	ctx, cancel := m.Timeouts.write(ctx)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, series.Name, series.Description).Scan(&series.ID, &series.CreatedAt)
+}
+
+// Get retrieves a series by ID with its entries in reading order. The
+// editions of the entries are loaded separately through BookModel.GetAllByWorks.
+func (m *SeriesModel) Get(ctx context.Context, id int) (*Series, error) {
+	query := `
+        SELECT id, name, description, created_at
+        FROM series
+        WHERE id = $1`
+
+	ctx, cancel := m.Timeouts.read(ctx)
+	defer cancel()
+
+	var series Series
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(&series.ID, &series.Name, &series.Description, &series.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRecordNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	query = `
+        SELECT se.work_id, w.title, se.position
+        FROM series_entries se
+        JOIN works w ON w.id = se.work_id
+        WHERE se.series_id = $1
+        ORDER BY se.position ASC`
+
+	rows, err := m.DB.QueryContext(ctx, query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	series.Entries = []*SeriesEntry{}
+	for rows.Next() {
+		var entry SeriesEntry
+		err := rows.Scan(&entry.WorkID, &entry.Title, &entry.Position)
+		if err != nil {
+			return nil, err
+		}
+		series.Entries = append(series.Entries, &entry)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	return &series, nil
+}
+
+// GetAll retrieves all series with an optional name filter and pagination.
+func (m *SeriesModel) GetAll(ctx context.Context, name string, filters Filters) ([]*Series, Metadata, error) {
+	query := fmt.Sprintf(`
+        SELECT COUNT(*) OVER(), id, name, description, created_at
+        FROM series
+        WHERE (name ILIKE '%%' || $1 || '%%' OR $1 = '')
+        ORDER BY %s %s, id ASC
+        LIMIT $2 OFFSET $3`, filters.SortColumn(), filters.SortDirection())
+
+	ctx, cancel := m.Timeouts.read(ctx)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, name, filters.Limit(), filters.Offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+	defer rows.Close()
+
+	totalRecords := 0
+	allSeries := []*Series{}
+
+	for rows.Next() {
+		var series Series
+		err := rows.Scan(&totalRecords, &series.ID, &series.Name, &series.Description, &series.CreatedAt)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		allSeries = append(allSeries, &series)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metadata := CalculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return allSeries, metadata, nil
+}
+
+// SetEntry adds a work to a series or moves it to a new position.
+func (m *SeriesModel) SetEntry(ctx context.Context, seriesID int, entry *SeriesEntry) error {
+	query := `
+        INSERT INTO series_entries (series_id, work_id, position)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (series_id, work_id) DO UPDATE SET position = EXCLUDED.position`
+
+	ctx, cancel := m.Timeouts.write(ctx)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, seriesID, entry.WorkID, entry.Position)
+	switch {
+	case isForeignKeyViolation(err):
+		return ErrUnknownWork
+	case isUniqueViolation(err):
+		return ErrDuplicatePosition
+	}
+	return err
+}
+
+// RemoveEntry removes a work from a series.
+func (m *SeriesModel) RemoveEntry(ctx context.Context, seriesID int, workID int) error {
+	query := `
+        DELETE FROM series_entries
+        WHERE series_id = $1 AND work_id = $2`
+
+	ctx, cancel := m.Timeouts.write(ctx)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, seriesID, workID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetNextForUser returns, for every series the user has started, the first
+// entry they haven't finished yet. A book counts as finished when it is on one
+// of the user's reading lists with the "completed" status; finishing any
+// edition finishes the work.
+func (m *SeriesModel) GetNextForUser(ctx context.Context, userID int64) ([]*NextInSeries, error) {
+	query := `
+        WITH finished AS (
+            SELECT DISTINCT b.work_id
+            FROM reading_lists rl
+            JOIN reading_list_books rlb ON rlb.reading_list_id = rl.id
+            JOIN books b ON b.id = rlb.book_id
+            WHERE rl.created_by = $1 AND rl.status = 'completed'
+        ), started AS (
+            SELECT DISTINCT se.series_id
+            FROM series_entries se
+            JOIN finished f ON f.work_id = se.work_id
+        )
+        SELECT DISTINCT ON (se.series_id) s.id, s.name, se.work_id, w.title, se.position
+        FROM started st
+        JOIN series s ON s.id = st.series_id
+        JOIN series_entries se ON se.series_id = st.series_id
+        JOIN works w ON w.id = se.work_id
+        WHERE se.work_id NOT IN (SELECT work_id FROM finished)
+        ORDER BY se.series_id, se.position ASC`
+
+	ctx, cancel := m.Timeouts.read(ctx)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	next := []*NextInSeries{}
+	for rows.Next() {
+		var entry NextInSeries
+		err := rows.Scan(&entry.SeriesID, &entry.SeriesName, &entry.WorkID, &entry.Title, &entry.Position)
+		if err != nil {
+			return nil, err
+		}
+		next = append(next, &entry)
+	}
+
+	return next, rows.Err()
+}
+
+// loadSeries fills in the series membership of the given books.
+func loadSeries(ctx context.Context, db queryer, books ...*Book) error {
+	if len(books) == 0 {
+		return nil
+	}
+
+	byWork := make(map[int][]*Book, len(books))
+	workIDs := make([]int, 0, len(books))
+	for _, book := range books {
+		book.Series = []SeriesInfo{}
+		if _, seen := byWork[book.WorkID]; !seen {
+			workIDs = append(workIDs, book.WorkID)
+		}
+		byWork[book.WorkID] = append(byWork[book.WorkID], book)
+	}
+
+	query := `
+        SELECT se.work_id, s.id, s.name, se.position
+        FROM series_entries se
+        JOIN series s ON s.id = se.series_id
+        WHERE se.work_id = ANY($1)
+        ORDER BY s.name, s.id`
+
+	rows, err := db.QueryContext(ctx, query, pq.Array(workIDs))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var workID int
+		var info SeriesInfo
+		err := rows.Scan(&workID, &info.SeriesID, &info.Name, &info.Position)
+		if err != nil {
+			return err
+		}
+		for _, book := range byWork[workID] {
+			book.Series = append(book.Series, info)
+		}
+	}
+
+	return rows.Err()
+}
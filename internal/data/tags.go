@@ -0,0 +1,137 @@
+package data
+
+import (
+	"context"
+	"strings"
+
+	"github.com/RayMC17/bookclub-api/internal/validator"
+)
+
+// MaxTagLength is the maximum length of a normalized tag name.
+const MaxTagLength = 50
+
+// TagCount is the number of users that applied a tag to a book.
+type TagCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// TaggedBook is a book together with the number of users that applied a tag to it.
+type TaggedBook struct {
+	*Book
+	TagCount int `json:"tag_count"`
+}
+
+// TagModel handles the database interactions for user tags.
+type TagModel struct {
+	DB       DBTX
+	Timeouts Timeouts
+}
+
+// NormalizeTagName folds the case of a tag and collapses surrounding and
+// repeated whitespace, so " Beach  Read" and "beach read" are the same tag.
+func NormalizeTagName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// ValidateTagName validates an already normalized tag name.
+func ValidateTagName(v *validator.Validator, name string) {
+	v.Check(name != "", "tag", "must be provided")
+	v.Check(validator.MaxLength(name, MaxTagLength), "tag", "must not be more than 50 characters long")
+}
+
+// Add applies a tag to a book on behalf of a user, creating the tag if needed.
+func (m *TagModel) Add(ctx context.Context, userID int, bookID int, name string) error {
+	ctx, cancel := m.Timeouts.write(ctx)
+	defer cancel()
+
+	tx, err := beginTx(ctx, m.DB)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var tagID int
+	query := `
+        INSERT INTO tags (name)
+        VALUES ($1)
+        ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name
+        RETURNING id`
+	err = tx.QueryRowContext(ctx, query, name).Scan(&tagID)
+	if err != nil {
+		return err
+	}
+
+	query = `
+        INSERT INTO user_book_tags (user_id, book_id, tag_id)
+        VALUES ($1, $2, $3)
+        ON CONFLICT DO NOTHING`
+	_, err = tx.ExecContext(ctx, query, userID, bookID, tagID)
+	if isForeignKeyViolation(err) {
+		return ErrRecordNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Remove takes a user's tag off a book.
+func (m *TagModel) Remove(ctx context.Context, userID int, bookID int, name string) error {
+	query := `
+        DELETE FROM user_book_tags
+        WHERE user_id = $1 AND book_id = $2
+        AND tag_id = (SELECT id FROM tags WHERE name = $3)`
+
+	ctx, cancel := m.Timeouts.write(ctx)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID, bookID, name)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GetCountsForBook returns every tag applied to a book with the number of
+// users that applied it, most popular first.
+func (m *TagModel) GetCountsForBook(ctx context.Context, bookID int) ([]*TagCount, error) {
+	query := `
+        SELECT t.name, COUNT(DISTINCT ubt.user_id)
+        FROM user_book_tags ubt
+        JOIN tags t ON t.id = ubt.tag_id
+        WHERE ubt.book_id = $1
+        GROUP BY t.name
+        ORDER BY 2 DESC, t.name ASC`
+
+	ctx, cancel := m.Timeouts.read(ctx)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, bookID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []*TagCount{}
+	for rows.Next() {
+		var count TagCount
+		err := rows.Scan(&count.Name, &count.Count)
+		if err != nil {
+			return nil, err
+		}
+		counts = append(counts, &count)
+	}
+
+	return counts, rows.Err()
+}
@@ -0,0 +1,52 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Default database timeouts, used when a model's Timeouts are left zero.
+const (
+	DefaultReadTimeout  = 3 * time.Second
+	DefaultWriteTimeout = 5 * time.Second
+)
+
+// Timeouts bounds how long a model waits for the database. The deadline is
+// applied on top of the caller's context, so a request that is cancelled
+// earlier still stops its query.
+type Timeouts struct {
+	Read  time.Duration
+	Write time.Duration
+}
+
+// read returns a context for a query that only reads data, which may be
+// sent to a replica.
+func (t Timeouts) read(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx = allowReplica(ctx)
+	if t.Read <= 0 {
+		return context.WithTimeout(ctx, DefaultReadTimeout)
+	}
+	return context.WithTimeout(ctx, t.Read)
+}
+
+// write returns a context for an operation that changes data.
+func (t Timeouts) write(ctx context.Context) (context.Context, context.CancelFunc) {
+	if t.Write <= 0 {
+		return context.WithTimeout(ctx, DefaultWriteTimeout)
+	}
+	return context.WithTimeout(ctx, t.Write)
+}
+
+// IsCanceled reports whether err means a database operation was cut short,
+// either because its context ended or because PostgreSQL cancelled the
+// statement (query_canceled, which is also raised by statement_timeout).
+func IsCanceled(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return true
+	}
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "57014"
+}
@@ -0,0 +1,77 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/RayMC17/bookclub-api/internal/trace"
+)
+
+// tracedDB records a span for every statement run through it, as a child of
+// the span in the statement's context. Without one, it adds no overhead
+// beyond a context lookup.
+type tracedDB struct {
+	DBTX
+}
+
+// traceDB wraps db in a tracedDB, unless it already is one.
+func traceDB(db DBTX) DBTX {
+	if _, ok := db.(tracedDB); ok {
+		return db
+	}
+	return tracedDB{db}
+}
+
+// untraceDB returns the DBTX wrapped by traceDB.
+func untraceDB(db DBTX) DBTX {
+	if traced, ok := db.(tracedDB); ok {
+		return traced.DBTX
+	}
+	return db
+}
+
+func (t tracedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := startQuerySpan(ctx, query)
+	defer span.End()
+
+	result, err := t.DBTX.ExecContext(ctx, query, args...)
+	span.RecordError(err)
+	return result, err
+}
+
+func (t tracedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := startQuerySpan(ctx, query)
+	defer span.End()
+
+	rows, err := t.DBTX.QueryContext(ctx, query, args...)
+	span.RecordError(err)
+	return rows, err
+}
+
+func (t tracedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := startQuerySpan(ctx, query)
+	defer span.End()
+
+	row := t.DBTX.QueryRowContext(ctx, query, args...)
+	if err := row.Err(); !errors.Is(err, sql.ErrNoRows) {
+		span.RecordError(err)
+	}
+	return row
+}
+
+// startQuerySpan starts a client span named after the SQL operation, such as
+// "SELECT", with the statement attached.
+func startQuerySpan(ctx context.Context, query string) (context.Context, *trace.Span) {
+	ctx, span := trace.Start(ctx, "db", trace.KindClient)
+	if !span.IsRecording() {
+		return ctx, span
+	}
+
+	statement := strings.Join(strings.Fields(query), " ")
+	operation, _, _ := strings.Cut(statement, " ")
+	span.SetName(strings.ToUpper(operation))
+	span.SetAttributes("db.system", "postgresql", "db.statement", statement)
+	return ctx, span
+}
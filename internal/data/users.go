@@ -1,47 +1,53 @@
 package data
 
 import (
-	//"context"
-	 //"errors"
+	"context"
 	"database/sql"
 	"time"
 )
 
 // User represents a user in the book club system.
 type User struct {
-	ID          int       `json:"id"`
-	Username    string    `json:"username"`
-	Email       string    `json:"email"`
-	Password    string    `json:"-"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID        int       `json:"id"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	Password  string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 // UserModel handles the database interactions for users.
 type UserModel struct {
-	DB *sql.DB
+	DB       DBTX
+	Timeouts Timeouts
 }
 
 // Insert adds a new user to the database.
-func (m *UserModel) Insert(user *User) error {
+func (m *UserModel) Insert(ctx context.Context, user *User) error {
 	query := `
 		INSERT INTO users (username, email, password, created_at)
 		VALUES ($1, $2, $3, $4)
 		RETURNING id`
 	args := []interface{}{user.Username, user.Email, user.Password, time.Now()}
 
-	return m.DB.QueryRow(query, args...).Scan(&user.ID)
+	ctx, cancel := m.Timeouts.write(ctx)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(&user.ID)
 }
 
 // Get retrieves a user by their ID.
-func (m *UserModel) Get(id int) (*User, error) {
+func (m *UserModel) Get(ctx context.Context, id int) (*User, error) {
 	query := `
 		SELECT id, username, email, created_at
 		FROM users
 		WHERE id = $1`
 
 	var user User
-	err := m.DB.QueryRow(query, id).Scan(
+	ctx, cancel := m.Timeouts.read(ctx)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
 		&user.ID, &user.Username, &user.Email, &user.CreatedAt,
 	)
 	if err == sql.ErrNoRows {
@@ -51,22 +57,40 @@ func (m *UserModel) Get(id int) (*User, error) {
 }
 
 // Update modifies an existing user in the database.
-func (m *UserModel) Update(user *User) error {
+func (m *UserModel) Update(ctx context.Context, user *User) error {
 	query := `
 		UPDATE users
 		SET username = $1, email = $2
 		WHERE id = $3`
 	args := []interface{}{user.Username, user.Email, user.ID}
 
-	_, err := m.DB.Exec(query, args...)
+	ctx, cancel := m.Timeouts.write(ctx)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, args...)
 	return err
 }
 
-// Delete removes a user by their ID.
-func (m *UserModel) Delete(id int) error {
+// Delete removes a user by their ID along with their reading lists and tags.
+func (m *UserModel) Delete(ctx context.Context, id int) error {
 	query := `DELETE FROM users WHERE id = $1`
-	_, err := m.DB.Exec(query, id)
-	return err
+	ctx, cancel := m.Timeouts.write(ctx)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
 }
 
 // // Get retrieves a user by ID.
@@ -81,7 +105,7 @@ func (m *UserModel) Delete(id int) error {
 //     var user User
 
 //     // Context with a timeout for the query execution
-//     ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+//     ctx, cancel := m.Timeouts.write(ctx)
 //     defer cancel()
 
 //     // Execute the query and scan the result into the user struct
@@ -102,4 +126,4 @@ func (m *UserModel) Delete(id int) error {
 //     }
 
 //     return &user, nil
-// }
\ No newline at end of file
+// }
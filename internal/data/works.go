@@ -0,0 +1,69 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Work groups the editions (translations, reprints, formats) of the same
+// book. Ratings are aggregated over the reviews of every edition.
+type Work struct {
+	ID            int       `json:"id"`
+	Title         string    `json:"title"`
+	Description   string    `json:"description"`
+	AverageRating float64   `json:"average_rating"`
+	RatingsCount  int       `json:"ratings_count"`
+	CreatedAt     time.Time `json:"created_at"`
+	Editions      []*Book   `json:"editions"`
+}
+
+// WorkModel handles the database interactions for works.
+type WorkModel struct {
+	DB       DBTX
+	Timeouts Timeouts
+}
+
+// Get retrieves a work by ID together with the ratings of all its editions.
+// Editions are loaded separately through BookModel.GetAllByWork.
+func (m *WorkModel) Get(ctx context.Context, id int) (*Work, error) {
+	query := `
+        SELECT w.id, w.title, w.description, w.created_at,
+            COALESCE(AVG(r.rating), 0), COUNT(r.id)
+        FROM works w
+        LEFT JOIN books b ON b.work_id = w.id
+        LEFT JOIN reviews r ON r.book_id = b.id
+        WHERE w.id = $1
+        GROUP BY w.id`
+
+	ctx, cancel := m.Timeouts.read(ctx)
+	defer cancel()
+
+	var work Work
+	err := m.DB.QueryRowContext(ctx, query, id).Scan(
+		&work.ID,
+		&work.Title,
+		&work.Description,
+		&work.CreatedAt,
+		&work.AverageRating,
+		&work.RatingsCount,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRecordNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &work, nil
+}
+
+// deleteWorkIfOrphaned removes the work when it no longer has any editions.
+func deleteWorkIfOrphaned(ctx context.Context, tx DBTX, workID int) error {
+	query := `
+        DELETE FROM works
+        WHERE id = $1 AND NOT EXISTS (SELECT 1 FROM books WHERE work_id = $1)`
+	_, err := tx.ExecContext(ctx, query, workID)
+	return err
+}
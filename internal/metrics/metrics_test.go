@@ -0,0 +1,184 @@
+package metrics
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func scrape(t *testing.T, r *Registry) string {
+	t.Helper()
+
+	var b strings.Builder
+	n, err := r.WriteTo(&b)
+	if err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	if n != int64(b.Len()) {
+		t.Errorf("WriteTo returned %d bytes, wrote %d", n, b.Len())
+	}
+	return b.String()
+}
+
+func TestCounterVec(t *testing.T) {
+	r := NewRegistry()
+	plain := r.NewCounterVec("jobs_total", "Jobs run.")
+	requests := r.NewCounterVec("requests_total", "Requests served.", "method", "status")
+
+	want := "# HELP jobs_total Jobs run.\n" +
+		"# TYPE jobs_total counter\n" +
+		"jobs_total 0\n" +
+		"# HELP requests_total Requests served.\n" +
+		"# TYPE requests_total counter\n"
+	if got := scrape(t, r); got != want {
+		t.Errorf("before any increment got\n%s\nwant\n%s", got, want)
+	}
+
+	plain.Add(2.5)
+	requests.Inc("POST", "201")
+	requests.Inc("GET", "200")
+	requests.Inc("GET", "200")
+
+	want = "# HELP jobs_total Jobs run.\n" +
+		"# TYPE jobs_total counter\n" +
+		"jobs_total 2.5\n" +
+		"# HELP requests_total Requests served.\n" +
+		"# TYPE requests_total counter\n" +
+		"requests_total{method=\"GET\",status=\"200\"} 2\n" +
+		"requests_total{method=\"POST\",status=\"201\"} 1\n"
+	if got := scrape(t, r); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestHistogramBuckets(t *testing.T) {
+	r := NewRegistry()
+	h := r.NewHistogramVec("latency_seconds", "Latency.", []float64{1, 0.5, 2}, "route")
+
+	// A value equal to a bound falls in that bucket (le is inclusive), one
+	// above every bound only in +Inf.
+	for _, v := range []float64{0.5, 0.75, 1, 2, 3} {
+		h.Observe(v, "/v1/books")
+	}
+
+	want := "# HELP latency_seconds Latency.\n" +
+		"# TYPE latency_seconds histogram\n" +
+		"latency_seconds_bucket{route=\"/v1/books\",le=\"0.5\"} 1\n" +
+		"latency_seconds_bucket{route=\"/v1/books\",le=\"1\"} 3\n" +
+		"latency_seconds_bucket{route=\"/v1/books\",le=\"2\"} 4\n" +
+		"latency_seconds_bucket{route=\"/v1/books\",le=\"+Inf\"} 5\n" +
+		"latency_seconds_sum{route=\"/v1/books\"} 7.25\n" +
+		"latency_seconds_count{route=\"/v1/books\"} 5\n"
+	if got := scrape(t, r); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestHistogramWithoutLabels(t *testing.T) {
+	r := NewRegistry()
+	h := r.NewHistogramVec("size_bytes", "Size.", []float64{10})
+	h.Observe(0)
+
+	got := scrape(t, r)
+	for _, line := range []string{`size_bytes_bucket{le="10"} 1`, `size_bytes_bucket{le="+Inf"} 1`, "size_bytes_sum 0", "size_bytes_count 1"} {
+		if !strings.Contains(got, line+"\n") {
+			t.Errorf("missing %q in\n%s", line, got)
+		}
+	}
+}
+
+func TestEscaping(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounterVec("errors_total", "Errors with a \\ and a\nnewline, \"quotes\" kept.", "message")
+	c.Inc("say \"hi\"\\n\n")
+
+	want := "# HELP errors_total Errors with a \\\\ and a\\nnewline, \"quotes\" kept.\n" +
+		"# TYPE errors_total counter\n" +
+		"errors_total{message=\"say \\\"hi\\\"\\\\n\\n\"} 1\n"
+	if got := scrape(t, r); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFuncMetrics(t *testing.T) {
+	r := NewRegistry()
+	value := 3.0
+	r.NewGaugeFunc("open_connections", "Open connections.", func() float64 { return value })
+	r.NewCounterFunc("uptime_seconds", "Uptime.", func() float64 { return math.Inf(1) })
+
+	value = 4
+	want := "# HELP open_connections Open connections.\n" +
+		"# TYPE open_connections gauge\n" +
+		"open_connections 4\n" +
+		"# HELP uptime_seconds Uptime.\n" +
+		"# TYPE uptime_seconds counter\n" +
+		"uptime_seconds +Inf\n"
+	if got := scrape(t, r); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFormatFloat(t *testing.T) {
+	tests := []struct {
+		value float64
+		want  string
+	}{
+		{0, "0"},
+		{42, "42"},
+		{0.005, "0.005"},
+		{1e21, "1e+21"},
+		{math.Inf(1), "+Inf"},
+		{math.Inf(-1), "-Inf"},
+		{math.NaN(), "NaN"},
+	}
+	for _, tt := range tests {
+		if got := formatFloat(tt.value); got != tt.want {
+			t.Errorf("formatFloat(%v) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestMisuse(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(r *Registry)
+	}{
+		{"duplicate name", func(r *Registry) {
+			r.NewCounterVec("a_total", "")
+			r.NewGaugeFunc("a_total", "", func() float64 { return 0 })
+		}},
+		{"negative counter delta", func(r *Registry) {
+			r.NewCounterVec("a_total", "").Add(-1)
+		}},
+		{"wrong label count", func(r *Registry) {
+			r.NewHistogramVec("a_seconds", "", DefaultBuckets, "route").Observe(1)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("didn't panic")
+				}
+			}()
+			tt.fn(NewRegistry())
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounterVec("jobs_total", "Jobs run.").Inc()
+
+	w := httptest.NewRecorder()
+	r.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if got := w.Header().Get("Content-Type"); got != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Errorf("got Content-Type %q", got)
+	}
+	if !strings.Contains(w.Body.String(), "jobs_total 1\n") {
+		t.Errorf("got body\n%s", w.Body.String())
+	}
+}
@@ -0,0 +1,467 @@
+// Package migrate applies the embedded SQL migrations and records the applied
+// versions in the schema_migrations table.
+//
+// Databases migrated before with golang-migrate have a schema_migrations table
+// of its own, holding only the current version and a dirty flag. It is
+// converted the first time migrations are applied or reverted; Version and
+// Status read either form.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// lockID is the PostgreSQL advisory lock key held while migrating, so that
+// several instances started with -migrate-on-start don't race each other.
+const lockID int64 = 4_206_031
+
+var ErrUnknownVersion = errors.New("unknown migration version")
+
+// ErrDirty is returned for a golang-migrate table left dirty by a failed
+// migration, which must be repaired by hand before it can be converted.
+var ErrDirty = errors.New("schema_migrations is marked dirty by golang-migrate")
+
+const createTableQuery = `
+    CREATE TABLE IF NOT EXISTS schema_migrations (
+        version BIGINT PRIMARY KEY,
+        name TEXT NOT NULL,
+        applied_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+    )`
+
+// The forms of the schema_migrations table found by tableForm.
+const (
+	tableMissing = iota
+	tableCurrent
+	tableLegacy
+)
+
+// queryer is the part of *sql.DB, *sql.Conn and *sql.Tx used to read the
+// applied versions.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+var filenameRX = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Migration is a numbered pair of up and down SQL scripts.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status reports whether a migration has been applied.
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// Migrator applies migrations to a database.
+type Migrator struct {
+	DB         *sql.DB
+	Migrations []Migration
+}
+
+// Load reads the migrations in fsys, ordered by version. Every version must
+// have both an up and a down script.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := filenameRX.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", entry.Name(), err)
+		}
+
+		contents, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, m.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			m.Up = string(contents)
+		} else {
+			m.Down = string(contents)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s must have both an up and a down script", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Latest returns the highest version known to the migrator, or 0 if there are
+// no migrations.
+func (m *Migrator) Latest() int64 {
+	if len(m.Migrations) == 0 {
+		return 0
+	}
+	return m.Migrations[len(m.Migrations)-1].Version
+}
+
+// Version returns the highest applied version, or 0 if nothing is applied.
+// A database that was never migrated, without a schema_migrations table, is
+// at version 0; Version only reads, so it doesn't create the table.
+func (m *Migrator) Version(ctx context.Context) (int64, error) {
+	form, err := tableForm(ctx, m.DB)
+	if err != nil || form == tableMissing {
+		return 0, err
+	}
+
+	var version sql.NullInt64
+	err = m.DB.QueryRowContext(ctx, `SELECT MAX(version) FROM schema_migrations`).Scan(&version)
+	if err != nil {
+		return 0, err
+	}
+	return version.Int64, nil
+}
+
+// Up applies every pending migration and returns the versions it applied.
+func (m *Migrator) Up(ctx context.Context) ([]int64, error) {
+	return m.Goto(ctx, m.Latest())
+}
+
+// Down reverts the most recently applied steps migrations.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]int64, error) {
+	var reverted []int64
+
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.Migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.Migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			err := m.revert(ctx, conn, migration)
+			if err != nil {
+				return err
+			}
+			reverted = append(reverted, migration.Version)
+		}
+		return nil
+	})
+
+	return reverted, err
+}
+
+// Goto migrates up or down until exactly the migrations up to and including
+// version are applied. Version 0 reverts everything.
+func (m *Migrator) Goto(ctx context.Context, version int64) ([]int64, error) {
+	if version != 0 && !m.known(version) {
+		return nil, ErrUnknownVersion
+	}
+
+	var changed []int64
+
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.Migrations) - 1; i >= 0; i-- {
+			migration := m.Migrations[i]
+			if _, ok := applied[migration.Version]; !ok || migration.Version <= version {
+				continue
+			}
+			err := m.revert(ctx, conn, migration)
+			if err != nil {
+				return err
+			}
+			changed = append(changed, migration.Version)
+		}
+
+		for _, migration := range m.Migrations {
+			if _, ok := applied[migration.Version]; ok || migration.Version > version {
+				continue
+			}
+			err := m.apply(ctx, conn, migration)
+			if err != nil {
+				return err
+			}
+			changed = append(changed, migration.Version)
+		}
+		return nil
+	})
+
+	return changed, err
+}
+
+// Status lists every known migration and whether it has been applied. It only
+// reads, so it neither waits for the advisory lock nor creates the table.
+// Migrations recorded by golang-migrate are applied at an unknown time.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	form, err := tableForm(ctx, m.DB)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int64]time.Time)
+	switch form {
+	case tableCurrent:
+		applied, err = appliedVersions(ctx, m.DB)
+	case tableLegacy:
+		applied, err = m.legacyVersions(ctx, m.DB)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(m.Migrations))
+	for _, migration := range m.Migrations {
+		appliedAt, ok := applied[migration.Version]
+		statuses = append(statuses, Status{Migration: migration, Applied: ok, AppliedAt: appliedAt})
+	}
+	return statuses, nil
+}
+
+func (m *Migrator) known(version int64) bool {
+	for _, migration := range m.Migrations {
+		if migration.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+// withLock runs fn on a dedicated connection holding the migration advisory
+// lock, creating the schema_migrations table first or converting the one of
+// golang-migrate if needed.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockID)
+	if err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockID)
+
+	form, err := tableForm(ctx, conn)
+	if err != nil {
+		return err
+	}
+	switch form {
+	case tableMissing:
+		_, err = conn.ExecContext(ctx, createTableQuery)
+	case tableLegacy:
+		err = m.convertLegacyTable(ctx, conn)
+	}
+	if err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+// convertLegacyTable replaces the schema_migrations table of golang-migrate
+// with this package's, recording every known migration up to its version.
+func (m *Migrator) convertLegacyTable(ctx context.Context, conn *sql.Conn) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	applied, err := m.legacyVersions(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `DROP TABLE schema_migrations`)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, createTableQuery)
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range m.Migrations {
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		_, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, migration.Version, migration.Name)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// apply runs an up script and records its version in the same transaction.
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	tx, err := begin(ctx, conn)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, migration.Up)
+	if err != nil {
+		return fmt.Errorf("migration %d_%s up: %w", migration.Version, migration.Name, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, migration.Version, migration.Name)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// revert runs a down script and forgets its version in the same transaction.
+func (m *Migrator) revert(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	tx, err := begin(ctx, conn)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, migration.Down)
+	if err != nil {
+		return fmt.Errorf("migration %d_%s down: %w", migration.Version, migration.Name, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// begin starts the transaction of a migration, lifting the statement timeout
+// the application may set on its connections, which long migrations such as
+// index builds would exceed.
+func begin(ctx context.Context, conn *sql.Conn) (*sql.Tx, error) {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `SET LOCAL statement_timeout = 0`)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return tx, nil
+}
+
+// tableForm tells whether the schema_migrations table is missing, is this
+// package's or is golang-migrate's, which has a dirty column.
+func tableForm(ctx context.Context, q queryer) (int, error) {
+	query := `
+        SELECT to_regclass('schema_migrations') IS NOT NULL, EXISTS (
+            SELECT 1 FROM information_schema.columns
+            WHERE table_schema = current_schema() AND table_name = 'schema_migrations' AND column_name = 'dirty'
+        )`
+
+	var exists, legacy bool
+	err := q.QueryRowContext(ctx, query).Scan(&exists, &legacy)
+	switch {
+	case err != nil:
+		return 0, err
+	case legacy:
+		return tableLegacy, nil
+	case exists:
+		return tableCurrent, nil
+	default:
+		return tableMissing, nil
+	}
+}
+
+// appliedVersions returns the applied versions and when they were applied.
+func appliedVersions(ctx context.Context, q queryer) (map[int64]time.Time, error) {
+	rows, err := q.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
+
+// legacyVersions returns the versions applied according to golang-migrate's
+// table, which are every known version up to the one it records, at an
+// unknown time.
+func (m *Migrator) legacyVersions(ctx context.Context, q queryer) (map[int64]time.Time, error) {
+	var version int64
+	var dirty bool
+	err := q.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations`).Scan(&version, &dirty)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		version = 0
+	case err != nil:
+		return nil, err
+	case dirty:
+		return nil, fmt.Errorf("%w at version %d: repair the schema and clear the flag first", ErrDirty, version)
+	case !m.known(version):
+		return nil, fmt.Errorf("golang-migrate recorded version %d: %w", version, ErrUnknownVersion)
+	}
+
+	applied := make(map[int64]time.Time)
+	for _, migration := range m.Migrations {
+		if migration.Version <= version {
+			applied[migration.Version] = time.Time{}
+		}
+	}
+	return applied, nil
+}
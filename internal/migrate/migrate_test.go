@@ -0,0 +1,593 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// fakeDB stands in for PostgreSQL: its driver understands the statements the
+// migrator sends and records the migration scripts it runs, with the
+// statement timeout in effect for each. Scripts containing FAIL fail.
+type fakeDB struct {
+	// lock is the advisory lock, held while it holds a value.
+	lock chan struct{}
+
+	// statementTimeout is the session setting of new connections.
+	statementTimeout string
+
+	mu      sync.Mutex
+	table   bool
+	applied map[int64]time.Time
+
+	// legacy is set while schema_migrations is golang-migrate's table,
+	// holding legacyVersion and legacyDirty.
+	legacy        bool
+	legacyVersion int64
+	legacyDirty   bool
+
+	executed []string
+	timeouts map[string]string
+}
+
+func newFakeDB() *fakeDB {
+	return &fakeDB{lock: make(chan struct{}, 1), applied: make(map[int64]time.Time), timeouts: make(map[string]string)}
+}
+
+// open returns a pool connected to db.
+func (db *fakeDB) open(t *testing.T) *sql.DB {
+	t.Helper()
+	pool := sql.OpenDB(fakeConnector{db})
+	t.Cleanup(func() { pool.Close() })
+	return pool
+}
+
+func (db *fakeDB) scripts() []string {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	return append([]string{}, db.executed...)
+}
+
+type fakeConnector struct{ db *fakeDB }
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	return &fakeConn{db: c.db, statementTimeout: c.db.statementTimeout}, nil
+}
+
+func (c fakeConnector) Driver() driver.Driver { return fakeDriver{} }
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return nil, errors.New("use fakeConnector") }
+
+// fakeConn runs statements against its fakeDB. A transaction snapshots the
+// schema_migrations rows and scripts, and rolling back restores them.
+type fakeConn struct {
+	db       *fakeDB
+	snapshot *fakeSnapshot
+
+	// statementTimeout is the session setting, localTimeout the one set
+	// with SET LOCAL for the current transaction.
+	statementTimeout string
+	localTimeout     string
+}
+
+type fakeSnapshot struct {
+	applied  map[int64]time.Time
+	executed []string
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *fakeConn) Close() error                        { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *fakeConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	applied := make(map[int64]time.Time, len(c.db.applied))
+	for version, at := range c.db.applied {
+		applied[version] = at
+	}
+	c.snapshot = &fakeSnapshot{applied: applied, executed: append([]string{}, c.db.executed...)}
+	return c, nil
+}
+
+func (c *fakeConn) Commit() error {
+	c.snapshot = nil
+	c.localTimeout = ""
+	return nil
+}
+
+func (c *fakeConn) Rollback() error {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	if c.snapshot != nil {
+		c.db.applied, c.db.executed = c.snapshot.applied, c.snapshot.executed
+		c.snapshot = nil
+	}
+	c.localTimeout = ""
+	return nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	_, err := c.run(ctx, query, args)
+	return driver.RowsAffected(0), err
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.run(ctx, query, args)
+}
+
+func (c *fakeConn) run(ctx context.Context, query string, args []driver.NamedValue) (*fakeRows, error) {
+	query = strings.Join(strings.Fields(query), " ")
+
+	switch {
+	case strings.HasPrefix(query, "SELECT pg_advisory_lock("):
+		select {
+		case c.db.lock <- struct{}{}:
+			return &fakeRows{columns: []string{"pg_advisory_lock"}, values: [][]driver.Value{{""}}}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	case strings.HasPrefix(query, "SELECT pg_advisory_unlock("):
+		<-c.db.lock
+		return &fakeRows{columns: []string{"pg_advisory_unlock"}, values: [][]driver.Value{{true}}}, nil
+	case strings.HasPrefix(query, "SET statement_timeout = "):
+		c.statementTimeout = strings.TrimPrefix(query, "SET statement_timeout = ")
+		return &fakeRows{}, nil
+	case strings.HasPrefix(query, "SET LOCAL statement_timeout = "):
+		// Outside a transaction, SET LOCAL has no effect
+		if c.snapshot != nil {
+			c.localTimeout = strings.TrimPrefix(query, "SET LOCAL statement_timeout = ")
+		}
+		return &fakeRows{}, nil
+	}
+
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+
+	switch {
+	case strings.HasPrefix(query, "CREATE TABLE IF NOT EXISTS schema_migrations"):
+		c.db.table = !c.db.legacy
+		return &fakeRows{}, nil
+	case strings.HasPrefix(query, "SELECT to_regclass('schema_migrations')"):
+		exists := c.db.table || c.db.legacy
+		return &fakeRows{columns: []string{"exists", "legacy"}, values: [][]driver.Value{{exists, c.db.legacy}}}, nil
+	case c.db.legacy && query == "SELECT version, dirty FROM schema_migrations":
+		return &fakeRows{columns: []string{"version", "dirty"}, values: [][]driver.Value{{c.db.legacyVersion, c.db.legacyDirty}}}, nil
+	case c.db.legacy && query == "SELECT MAX(version) FROM schema_migrations":
+		return &fakeRows{columns: []string{"max"}, values: [][]driver.Value{{c.db.legacyVersion}}}, nil
+	case c.db.legacy && query == "DROP TABLE schema_migrations":
+		c.db.legacy = false
+		return &fakeRows{}, nil
+	case c.db.legacy && strings.Contains(query, "schema_migrations"):
+		return nil, fmt.Errorf("pq: unexpected statement %q on golang-migrate's table", query)
+	case !c.db.table && strings.Contains(query, "schema_migrations"):
+		return nil, errors.New(`pq: relation "schema_migrations" does not exist`)
+	case query == "SELECT MAX(version) FROM schema_migrations":
+		var latest driver.Value
+		for version := range c.db.applied {
+			if latest == nil || version > latest.(int64) {
+				latest = version
+			}
+		}
+		return &fakeRows{columns: []string{"max"}, values: [][]driver.Value{{latest}}}, nil
+	case query == "SELECT version, applied_at FROM schema_migrations":
+		rows := &fakeRows{columns: []string{"version", "applied_at"}}
+		for version, at := range c.db.applied {
+			rows.values = append(rows.values, []driver.Value{version, at})
+		}
+		return rows, nil
+	case strings.HasPrefix(query, "INSERT INTO schema_migrations"):
+		c.db.applied[args[0].Value.(int64)] = time.Now()
+		return &fakeRows{}, nil
+	case strings.HasPrefix(query, "DELETE FROM schema_migrations"):
+		delete(c.db.applied, args[0].Value.(int64))
+		return &fakeRows{}, nil
+	case strings.Contains(query, "FAIL"):
+		return nil, fmt.Errorf("pq: syntax error in %q", query)
+	default:
+		c.db.executed = append(c.db.executed, query)
+		c.db.timeouts[query] = c.statementTimeout
+		if c.localTimeout != "" {
+			c.db.timeouts[query] = c.localTimeout
+		}
+		return &fakeRows{}, nil
+	}
+}
+
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+// testMigrations returns migrations 1 to n, whose scripts are "up N" and
+// "down N".
+func testMigrations(n int) []Migration {
+	var migrations []Migration
+	for i := 1; i <= n; i++ {
+		migrations = append(migrations, Migration{
+			Version: int64(i),
+			Name:    fmt.Sprintf("step_%d", i),
+			Up:      fmt.Sprintf("up %d", i),
+			Down:    fmt.Sprintf("down %d", i),
+		})
+	}
+	return migrations
+}
+
+func assertVersions(t *testing.T, what string, got, want []int64) {
+	t.Helper()
+	if len(got) == 0 && len(want) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%s: got versions %v, want %v", what, got, want)
+	}
+}
+
+func assertVersion(t *testing.T, m *Migrator, want int64) {
+	t.Helper()
+	version, err := m.Version(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != want {
+		t.Errorf("got version %d, want %d", version, want)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"000002_add_b.up.sql":     {Data: []byte("CREATE TABLE b ();")},
+		"000002_add_b.down.sql":   {Data: []byte("DROP TABLE b;")},
+		"000001_add_a.up.sql":     {Data: []byte("CREATE TABLE a ();")},
+		"000001_add_a.down.sql":   {Data: []byte("DROP TABLE a;")},
+		"README.md":               {Data: []byte("not a migration")},
+		"000003_skipped.sql.orig": {Data: []byte("not a migration either")},
+	}
+
+	migrations, err := Load(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Migration{
+		{Version: 1, Name: "add_a", Up: "CREATE TABLE a ();", Down: "DROP TABLE a;"},
+		{Version: 2, Name: "add_b", Up: "CREATE TABLE b ();", Down: "DROP TABLE b;"},
+	}
+	if !reflect.DeepEqual(migrations, want) {
+		t.Errorf("got %+v, want %+v", migrations, want)
+	}
+
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+		want string
+	}{
+		{"missing down", fstest.MapFS{"000001_a.up.sql": {Data: []byte("x")}}, "must have both an up and a down script"},
+		{"missing up", fstest.MapFS{"000001_a.down.sql": {Data: []byte("x")}}, "must have both an up and a down script"},
+		{"conflicting names", fstest.MapFS{
+			"000001_a.up.sql":   {Data: []byte("x")},
+			"000001_b.down.sql": {Data: []byte("x")},
+		}, "conflicting names"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(tt.fsys)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVersionOfFreshDatabase(t *testing.T) {
+	db := newFakeDB()
+	m := &Migrator{DB: db.open(t), Migrations: testMigrations(2)}
+
+	assertVersion(t, m, 0)
+	if db.table {
+		t.Error("Version created the schema_migrations table")
+	}
+}
+
+func TestStatusIsReadOnly(t *testing.T) {
+	db := newFakeDB()
+	m := &Migrator{DB: db.open(t), Migrations: testMigrations(2)}
+
+	// Another instance is migrating
+	db.lock <- struct{}{}
+	defer func() { <-db.lock }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 2 || statuses[0].Applied || statuses[1].Applied {
+		t.Errorf("got statuses %+v, want both migrations pending", statuses)
+	}
+	if db.table {
+		t.Error("Status created the schema_migrations table")
+	}
+}
+
+func TestLegacyTable(t *testing.T) {
+	ctx := context.Background()
+	db := newFakeDB()
+	db.legacy, db.legacyVersion = true, 2
+	m := &Migrator{DB: db.open(t), Migrations: testMigrations(3)}
+
+	// The table of golang-migrate is read as it is
+	assertVersion(t, m, 2)
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range statuses {
+		if want := status.Version <= 2; status.Applied != want {
+			t.Errorf("migration %d: got applied %t, want %t", status.Version, status.Applied, want)
+		}
+	}
+	if !db.legacy {
+		t.Fatal("Status converted the table")
+	}
+
+	// and converted before migrating
+	applied, err := m.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertVersions(t, "up", applied, []int64{3})
+	assertVersion(t, m, 3)
+	if db.legacy || !db.table {
+		t.Error("the table wasn't converted")
+	}
+	if got := db.scripts(); !reflect.DeepEqual(got, []string{"up 3"}) {
+		t.Errorf("ran scripts %q, want only \"up 3\"", got)
+	}
+
+	reverted, err := m.Down(ctx, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertVersions(t, "down 3", reverted, []int64{3, 2, 1})
+
+	t.Run("dirty", func(t *testing.T) {
+		db := newFakeDB()
+		db.legacy, db.legacyVersion, db.legacyDirty = true, 2, true
+		m := &Migrator{DB: db.open(t), Migrations: testMigrations(3)}
+
+		_, err := m.Up(ctx)
+		if !errors.Is(err, ErrDirty) {
+			t.Fatalf("got error %v, want ErrDirty", err)
+		}
+		_, err = m.Status(ctx)
+		if !errors.Is(err, ErrDirty) {
+			t.Errorf("status: got error %v, want ErrDirty", err)
+		}
+		if !db.legacy || len(db.scripts()) != 0 {
+			t.Error("a dirty table was migrated")
+		}
+	})
+
+	t.Run("unknown version", func(t *testing.T) {
+		db := newFakeDB()
+		db.legacy, db.legacyVersion = true, 9
+		m := &Migrator{DB: db.open(t), Migrations: testMigrations(3)}
+
+		_, err := m.Up(ctx)
+		if !errors.Is(err, ErrUnknownVersion) {
+			t.Errorf("got error %v, want ErrUnknownVersion", err)
+		}
+	})
+}
+
+func TestUpDownGoto(t *testing.T) {
+	ctx := context.Background()
+	db := newFakeDB()
+	m := &Migrator{DB: db.open(t), Migrations: testMigrations(3)}
+
+	if got := m.Latest(); got != 3 {
+		t.Errorf("got latest %d, want 3", got)
+	}
+
+	applied, err := m.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertVersions(t, "up", applied, []int64{1, 2, 3})
+	assertVersion(t, m, 3)
+
+	applied, err = m.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertVersions(t, "up again", applied, nil)
+
+	reverted, err := m.Down(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertVersions(t, "down 2", reverted, []int64{3, 2})
+	assertVersion(t, m, 1)
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, status := range statuses {
+		if want := status.Version == 1; status.Applied != want {
+			t.Errorf("migration %d: got applied %t, want %t", status.Version, status.Applied, want)
+		}
+		if status.Applied && status.AppliedAt.IsZero() {
+			t.Errorf("migration %d: applied without a time", status.Version)
+		}
+	}
+
+	changed, err := m.Goto(ctx, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertVersions(t, "goto 3", changed, []int64{2, 3})
+
+	changed, err = m.Goto(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertVersions(t, "goto 0", changed, []int64{3, 2, 1})
+	assertVersion(t, m, 0)
+
+	_, err = m.Goto(ctx, 7)
+	if !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("goto 7: got error %v, want ErrUnknownVersion", err)
+	}
+
+	want := []string{"up 1", "up 2", "up 3", "down 3", "down 2", "up 2", "up 3", "down 3", "down 2", "down 1"}
+	if got := db.scripts(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ran scripts %q, want %q", got, want)
+	}
+}
+
+func TestFailedMigrationIsRolledBack(t *testing.T) {
+	db := newFakeDB()
+	migrations := testMigrations(3)
+	migrations[1].Up = "up 2; FAIL"
+	m := &Migrator{DB: db.open(t), Migrations: migrations}
+
+	applied, err := m.Up(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "migration 2_step_2 up") {
+		t.Fatalf("got error %v, want migration 2 to fail", err)
+	}
+	assertVersions(t, "up", applied, []int64{1})
+	assertVersion(t, m, 1)
+
+	if len(db.lock) != 0 {
+		t.Error("the advisory lock is still held")
+	}
+}
+
+func TestMigrationsLiftStatementTimeout(t *testing.T) {
+	ctx := context.Background()
+	db := newFakeDB()
+	db.statementTimeout = "5000"
+	m := &Migrator{DB: db.open(t), Migrations: testMigrations(2)}
+
+	_, err := m.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = m.Down(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, script := range []string{"up 1", "up 2", "down 2"} {
+		if got := db.timeouts[script]; got != "0" {
+			t.Errorf("%q ran with statement_timeout %q, want 0", script, got)
+		}
+	}
+
+	// The setting doesn't outlive the migration's transaction
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_, err = conn.ExecContext(ctx, "after")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := db.timeouts["after"]; got != "5000" {
+		t.Errorf("a later statement ran with statement_timeout %q, want 5000", got)
+	}
+}
+
+func TestAdvisoryLock(t *testing.T) {
+	db := newFakeDB()
+	m := &Migrator{DB: db.open(t), Migrations: testMigrations(3)}
+
+	// Another instance holds the lock
+	db.lock <- struct{}{}
+
+	done := make(chan error)
+	go func() {
+		_, err := m.Up(context.Background())
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("Up returned %v while the lock was held", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if got := db.scripts(); len(got) != 0 {
+		t.Fatalf("ran %q while the lock was held", got)
+	}
+
+	<-db.lock
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if len(db.lock) != 0 {
+		t.Error("Up didn't release the advisory lock")
+	}
+
+	// Instances migrating at once apply every migration once
+	errs := make(chan error, 4)
+	db2 := newFakeDB()
+	for i := 0; i < cap(errs); i++ {
+		m := &Migrator{DB: db2.open(t), Migrations: testMigrations(3)}
+		go func() {
+			_, err := m.Up(context.Background())
+			errs <- err
+		}()
+	}
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := db2.scripts(), []string{"up 1", "up 2", "up 3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ran scripts %q, want %q", got, want)
+	}
+}
+
+func TestLockWaitHonoursContext(t *testing.T) {
+	db := newFakeDB()
+	m := &Migrator{DB: db.open(t), Migrations: testMigrations(1)}
+	db.lock <- struct{}{}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := m.Up(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got error %v, want context.DeadlineExceeded", err)
+	}
+}
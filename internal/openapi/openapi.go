@@ -0,0 +1,236 @@
+// Package openapi builds OpenAPI 3.1 documents. The schemas of the request
+// and response bodies are derived from Go types, their fields and json tags,
+// so that the document follows the code it describes.
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Version is the version of the OpenAPI specification the documents follow.
+const Version = "3.1.0"
+
+// Schema is a JSON Schema (draft 2020-12), as OpenAPI 3.1 uses them.
+type Schema map[string]any
+
+// Document is an OpenAPI document.
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem holds the operations of a path, keyed by lower-case method.
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string              `json:"operationId,omitempty"`
+	Summary     string              `json:"summary,omitempty"`
+	Tags        []string            `json:"tags,omitempty"`
+	Deprecated  bool                `json:"deprecated,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name        string `json:"name"`
+	In          string `json:"in"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	Schema      Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema Schema `json:"schema"`
+}
+
+// Response is a response, or a reference to one of the Components when Ref
+// is set.
+type Response struct {
+	Ref         string               `json:"$ref,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Headers     map[string]Header    `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type Header struct {
+	Description string `json:"description,omitempty"`
+	Schema      Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas   map[string]Schema   `json:"schemas"`
+	Responses map[string]Response `json:"responses,omitempty"`
+}
+
+// New returns a document without any paths.
+func New(title, version string) *Document {
+	return &Document{
+		OpenAPI: Version,
+		Info:    Info{Title: title, Version: version},
+		Paths:   make(map[string]PathItem),
+		Components: Components{
+			Schemas:   make(map[string]Schema),
+			Responses: make(map[string]Response),
+		},
+	}
+}
+
+// Path converts an httprouter pattern, such as "/v1/books/:id", into an
+// OpenAPI path template, "/v1/books/{id}".
+func Path(pattern string) string {
+	segments := strings.Split(pattern, "/")
+	for i, segment := range segments {
+		if name, ok := strings.CutPrefix(segment, ":"); ok {
+			segments[i] = "{" + name + "}"
+		} else if name, ok := strings.CutPrefix(segment, "*"); ok {
+			segments[i] = "{" + name + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// Add adds the operation of method on the route pattern.
+func (d *Document) Add(method, pattern string, op *Operation) {
+	path := Path(pattern)
+	item, ok := d.Paths[path]
+	if !ok {
+		item = make(PathItem)
+		d.Paths[path] = item
+	}
+	item[strings.ToLower(method)] = op
+}
+
+// Operation returns the operation of method on the route pattern, if any.
+func (d *Document) Operation(method, pattern string) (*Operation, bool) {
+	op, ok := d.Paths[Path(pattern)][strings.ToLower(method)]
+	return op, ok
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// SchemaOf returns the schema of the JSON encoding of v's type. Named struct
+// types are added to the components and referenced.
+func (d *Document) SchemaOf(v any) Schema {
+	if v == nil {
+		return Schema{}
+	}
+	return d.schema(reflect.TypeOf(v))
+}
+
+func (d *Document) schema(t reflect.Type) Schema {
+	if t == timeType {
+		return Schema{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := d.schema(t.Elem())
+		if typ, ok := schema["type"].(string); ok {
+			schema["type"] = []string{typ, "null"}
+		}
+		return schema
+	case reflect.Bool:
+		return Schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return Schema{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return Schema{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return Schema{"type": "number"}
+	case reflect.String:
+		return Schema{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return Schema{"type": "string", "contentEncoding": "base64"}
+		}
+		return Schema{"type": "array", "items": d.schema(t.Elem())}
+	case reflect.Map:
+		return Schema{"type": "object", "additionalProperties": d.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return d.object(t)
+		}
+		name := componentName(t)
+		if _, ok := d.Components.Schemas[name]; !ok {
+			// Registered before the fields so that recursive types end
+			d.Components.Schemas[name] = Schema{}
+			d.Components.Schemas[name] = d.object(t)
+		}
+		return Schema{"$ref": "#/components/schemas/" + name}
+	default:
+		return Schema{}
+	}
+}
+
+// object returns the schema of a struct, with the properties encoding/json
+// would write. A field tagged enum:"a,b" only takes the listed values, or
+// null if it's a pointer.
+func (d *Document) object(t reflect.Type) Schema {
+	properties := make(map[string]Schema)
+	d.addProperties(properties, t)
+	return Schema{"type": "object", "properties": properties}
+}
+
+func (d *Document) addProperties(properties map[string]Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				d.addProperties(properties, embedded)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		schema := d.schema(field.Type)
+		if values, ok := field.Tag.Lookup("enum"); ok {
+			enum := []any{}
+			for _, value := range strings.Split(values, ",") {
+				enum = append(enum, value)
+			}
+			if field.Type.Kind() == reflect.Pointer {
+				enum = append(enum, nil)
+			}
+			schema["enum"] = enum
+		}
+		properties[name] = schema
+	}
+}
+
+// componentName names the schema of a named type after it, capitalized.
+func componentName(t reflect.Type) string {
+	r, size := utf8.DecodeRuneInString(t.Name())
+	return string(unicode.ToUpper(r)) + t.Name()[size:]
+}
@@ -0,0 +1,124 @@
+// Package ratelimit implements token bucket rate limiting behind a Store, so
+// that the buckets can be kept in process or in a backend shared by several
+// instances of the API.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Policy is a token bucket: Burst requests are allowed at once, refilled at
+// Rate requests per second.
+type Policy struct {
+	Name  string
+	Rate  float64
+	Burst int
+}
+
+// ParsePolicy parses a policy written as "rate:burst", such as "0.5:3".
+func ParsePolicy(name, text string) (Policy, error) {
+	rateText, burstText, ok := strings.Cut(text, ":")
+	if !ok {
+		return Policy{}, fmt.Errorf("invalid policy %q: expected rate:burst", text)
+	}
+	r, err := strconv.ParseFloat(rateText, 64)
+	if err != nil || r <= 0 || math.IsInf(r, 0) {
+		return Policy{}, fmt.Errorf("invalid policy %q: rate must be a positive number", text)
+	}
+	burst, err := strconv.Atoi(burstText)
+	if err != nil || burst <= 0 {
+		return Policy{}, fmt.Errorf("invalid policy %q: burst must be a positive integer", text)
+	}
+	return Policy{Name: name, Rate: r, Burst: burst}, nil
+}
+
+// Result is the outcome of taking a token from a bucket.
+type Result struct {
+	Allowed bool
+	// Limit is the size of the bucket.
+	Limit int
+	// Remaining is the number of tokens left after this request.
+	Remaining int
+	// RetryAfter is the time until a token is available again, when the
+	// request isn't allowed.
+	RetryAfter time.Duration
+}
+
+// Store keeps the buckets, one per key and policy.
+type Store interface {
+	// Allow takes a token from the bucket of key under policy.
+	Allow(ctx context.Context, key string, policy Policy) (Result, error)
+}
+
+// MemoryStore keeps the buckets in process. Idle buckets are forgotten by
+// Sweep.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[bucketKey]*bucket
+	now     func() time.Time
+}
+
+type bucketKey struct {
+	policy string
+	key    string
+}
+
+type bucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// NewMemoryStore returns an empty in-process store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[bucketKey]*bucket), now: time.Now}
+}
+
+func (s *MemoryStore) Allow(ctx context.Context, key string, policy Policy) (Result, error) {
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := bucketKey{policy: policy.Name, key: key}
+	b, found := s.buckets[k]
+	if !found {
+		b = &bucket{limiter: rate.NewLimiter(rate.Limit(policy.Rate), policy.Burst)}
+		s.buckets[k] = b
+	}
+	b.lastSeen = now
+
+	result := Result{Limit: policy.Burst}
+	reservation := b.limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		result.RetryAfter = delay
+	} else {
+		result.Allowed = true
+	}
+	result.Remaining = int(math.Max(0, math.Floor(b.limiter.TokensAt(now))))
+	return result, nil
+}
+
+// Sweep forgets the buckets unused for longer than idle. A forgotten bucket
+// starts full again, so idle should be longer than the policies take to
+// refill.
+func (s *MemoryStore) Sweep(idle time.Duration) {
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for k, b := range s.buckets {
+		if now.Sub(b.lastSeen) > idle {
+			delete(s.buckets, k)
+		}
+	}
+}
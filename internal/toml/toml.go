@@ -0,0 +1,273 @@
+// Package toml decodes the subset of TOML accepted in config files:
+//
+//	# A comment
+//	port = 4000
+//
+//	[db]
+//	dsn = "postgres://localhost/bookclub"
+//	replica_dsn = [
+//	    "postgres://replica-1/bookclub",
+//	]
+//
+// Keys are bare, table headers are bare keys joined by dots, and values are
+// single-line basic or literal strings, decimal integers and floats, booleans
+// and arrays of those. Anything else, such as quoted or dotted keys, inline
+// tables, nested arrays, multi-line strings, dates and arrays of tables, is
+// rejected.
+package toml
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	bareKeyRX = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	integerRX = regexp.MustCompile(`^[+-]?[0-9]+$`)
+	floatRX   = regexp.MustCompile(`^[+-]?[0-9]+(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+)
+
+// Parse parses a TOML document into maps (map[string]any), slices ([]any) and
+// scalars (string, int64, float64 or bool).
+func Parse(data []byte) (map[string]any, error) {
+	root := map[string]any{}
+	table := root
+
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	for n := 0; n < len(lines); n++ {
+		number := n + 1
+		text := strings.TrimSpace(stripComment(lines[n]))
+		if text == "" {
+			continue
+		}
+
+		if strings.HasPrefix(text, "[") {
+			var err error
+			table, err = parseHeader(root, text)
+			if err != nil {
+				return nil, fmt.Errorf("toml: line %d: %v", number, err)
+			}
+			continue
+		}
+
+		key, rest, ok := strings.Cut(text, "=")
+		if !ok {
+			return nil, fmt.Errorf("toml: line %d: expected \"=\" after key", number)
+		}
+		key = strings.TrimSpace(key)
+		if !bareKeyRX.MatchString(key) {
+			return nil, fmt.Errorf("toml: line %d: invalid key %q: only bare keys are supported", number, key)
+		}
+		rest = strings.TrimSpace(rest)
+
+		// An array may continue on the following lines until it is closed.
+		for strings.HasPrefix(rest, "[") && !closed(rest) && n+1 < len(lines) {
+			n++
+			rest += " " + strings.TrimSpace(stripComment(lines[n]))
+		}
+
+		value, err := parseValue(rest)
+		if err != nil {
+			return nil, fmt.Errorf("toml: line %d: %v", number, err)
+		}
+		if _, exists := table[key]; exists {
+			return nil, fmt.Errorf("toml: line %d: duplicate key %q", number, key)
+		}
+		table[key] = value
+	}
+
+	return root, nil
+}
+
+// parseHeader returns the table named by a [table] header, creating it if
+// needed.
+func parseHeader(root map[string]any, text string) (map[string]any, error) {
+	if strings.HasPrefix(text, "[[") {
+		return nil, errors.New("arrays of tables are not supported")
+	}
+	name, ok := strings.CutSuffix(text[1:], "]")
+	if !ok {
+		return nil, errors.New("unterminated table header")
+	}
+
+	table := root
+	for _, key := range strings.Split(name, ".") {
+		key = strings.TrimSpace(key)
+		if !bareKeyRX.MatchString(key) {
+			return nil, fmt.Errorf("invalid table header %s", text)
+		}
+		switch next := table[key].(type) {
+		case nil:
+			created := map[string]any{}
+			table[key] = created
+			table = created
+		case map[string]any:
+			table = next
+		default:
+			return nil, fmt.Errorf("key %q is already defined as a value", key)
+		}
+	}
+	return table, nil
+}
+
+// parseValue parses text, which must hold exactly one value.
+func parseValue(text string) (any, error) {
+	if text == "" {
+		return nil, errors.New("missing value")
+	}
+	if strings.HasPrefix(text, "[") {
+		return parseArray(text)
+	}
+
+	value, rest, err := parseScalar(text)
+	if err != nil {
+		return nil, err
+	}
+	if rest = strings.TrimSpace(rest); rest != "" {
+		return nil, fmt.Errorf("unexpected content %q", rest)
+	}
+	return value, nil
+}
+
+// parseArray parses an array of scalars, which may end with a comma.
+func parseArray(text string) ([]any, error) {
+	items := []any{}
+	rest := strings.TrimSpace(text[1:])
+	for {
+		switch {
+		case rest == "":
+			return nil, errors.New("unterminated array")
+		case rest[0] == ']':
+			if tail := strings.TrimSpace(rest[1:]); tail != "" {
+				return nil, fmt.Errorf("unexpected content %q", tail)
+			}
+			return items, nil
+		case rest[0] == '[':
+			return nil, errors.New("nested arrays are not supported")
+		}
+
+		item, r, err := parseScalar(rest)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+
+		r = strings.TrimSpace(r)
+		if strings.HasPrefix(r, ",") {
+			r = strings.TrimSpace(r[1:])
+		} else if !strings.HasPrefix(r, "]") {
+			return nil, errors.New("unterminated array")
+		}
+		rest = r
+	}
+}
+
+// parseScalar parses the string, number or boolean at the start of text and
+// returns the unparsed remainder.
+func parseScalar(text string) (any, string, error) {
+	switch text[0] {
+	case '"', '\'':
+		return parseString(text)
+	case '{':
+		return nil, "", errors.New("inline tables are not supported")
+	}
+
+	end := strings.IndexAny(text, ",] \t")
+	if end < 0 {
+		end = len(text)
+	}
+	word := text[:end]
+
+	switch {
+	case word == "true":
+		return true, text[end:], nil
+	case word == "false":
+		return false, text[end:], nil
+	case integerRX.MatchString(word):
+		i, err := strconv.ParseInt(word, 10, 64)
+		if err == nil {
+			return i, text[end:], nil
+		}
+	case floatRX.MatchString(word):
+		f, err := strconv.ParseFloat(word, 64)
+		if err == nil {
+			return f, text[end:], nil
+		}
+	}
+	return nil, "", fmt.Errorf("invalid value %q", word)
+}
+
+// parseString parses a basic ("...") or literal ('...') string at the start of
+// text and returns the unparsed remainder.
+func parseString(text string) (string, string, error) {
+	if strings.HasPrefix(text, `"""`) || strings.HasPrefix(text, "'''") {
+		return "", "", errors.New("multi-line strings are not supported")
+	}
+
+	quote := text[0]
+	for i := 1; i < len(text); i++ {
+		switch {
+		case quote == '"' && text[i] == '\\':
+			i++
+		case text[i] == quote:
+			if quote == '\'' {
+				return text[1:i], text[i+1:], nil
+			}
+			s, err := strconv.Unquote(text[:i+1])
+			if err != nil {
+				return "", "", fmt.Errorf("invalid string %s", text[:i+1])
+			}
+			return s, text[i+1:], nil
+		}
+	}
+	return "", "", errors.New("unterminated string")
+}
+
+// closed reports whether the brackets opened in text, outside of strings, are
+// all closed.
+func closed(text string) bool {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+		}
+	}
+	return depth <= 0
+}
+
+// stripComment removes a trailing comment that isn't inside a string.
+func stripComment(text string) string {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			return text[:i]
+		}
+	}
+	return text
+}
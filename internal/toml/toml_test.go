@@ -0,0 +1,139 @@
+package toml
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  map[string]any
+	}{
+		{"empty", "", map[string]any{}},
+		{"only comments", "# nothing\n\n   # here\n", map[string]any{}},
+		{
+			"integers and floats",
+			"int = 42\nnegative = -7\nplus = +3\nfloat = 2.5\nwhole = 1.0\nexponent = 1e3\nnegexp = -2.5E-3\n",
+			map[string]any{
+				"int":      int64(42),
+				"negative": int64(-7),
+				"plus":     int64(3),
+				"float":    2.5,
+				"whole":    1.0,
+				"exponent": 1000.0,
+				"negexp":   -0.0025,
+			},
+		},
+		{"booleans", "on = true\noff = false\n", map[string]any{"on": true, "off": false}},
+		{
+			"strings and escapes",
+			`basic = "tab\there \"quoted\" \\ \u00e9"` + "\n" +
+				`literal = 'C:\path\no escapes'` + "\n" +
+				`hash = "# not a comment" # a comment` + "\n" +
+				`single = 'it has a # too'` + "\n" +
+				`empty = ""` + "\n",
+			map[string]any{
+				"basic":   "tab\there \"quoted\" \\ é",
+				"literal": `C:\path\no escapes`,
+				"hash":    "# not a comment",
+				"single":  "it has a # too",
+				"empty":   "",
+			},
+		},
+		{"bare keys", "bare-key_1 = 1\n  indented=2\n", map[string]any{"bare-key_1": int64(1), "indented": int64(2)}},
+		{
+			"tables",
+			"top = 1\n\n[db]\ndsn = \"postgres://localhost\"\n\n[db.pool]\nmax = 25\n\n[ http . cors ]\norigins = []\n\n[db]\nidle = 5\n",
+			map[string]any{
+				"top": int64(1),
+				"db": map[string]any{
+					"dsn":  "postgres://localhost",
+					"pool": map[string]any{"max": int64(25)},
+					"idle": int64(5),
+				},
+				"http": map[string]any{"cors": map[string]any{"origins": []any{}}},
+			},
+		},
+		{
+			"arrays",
+			"ints = [1, 2, 3]\nmixed = [\"a\", 'b', 1.5, true]\nbracket = [\"]\", '[']\ntrailing = [1, 2, ]\n",
+			map[string]any{
+				"ints":     []any{int64(1), int64(2), int64(3)},
+				"mixed":    []any{"a", "b", 1.5, true},
+				"bracket":  []any{"]", "["},
+				"trailing": []any{int64(1), int64(2)},
+			},
+		},
+		{
+			"multi-line array",
+			"origins = [\n  \"https://a.example\", # first\n  \"https://b.example\",\n]\nafter = 1\n",
+			map[string]any{
+				"origins": []any{"https://a.example", "https://b.example"},
+				"after":   int64(1),
+			},
+		},
+		{"windows line endings", "a = 1\r\nb = 2\r\n", map[string]any{"a": int64(1), "b": int64(2)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"array of tables", "[[books]]\n", "toml: line 1: arrays of tables are not supported"},
+		{"unterminated header", "a = 1\n[db\n", "toml: line 2: unterminated table header"},
+		{"invalid header", "[db x]\n", "toml: line 1: invalid table header [db x]"},
+		{"quoted header", "[\"db\"]\n", `toml: line 1: invalid table header ["db"]`},
+		{"table over a value", "db = 1\n[db.pool]\n", `toml: line 2: key "db" is already defined as a value`},
+		{"missing key", "= 1\n", `toml: line 1: invalid key "": only bare keys are supported`},
+		{"quoted key", "\"quoted key\" = 1\n", `toml: line 1: invalid key "\"quoted key\"": only bare keys are supported`},
+		{"dotted key", "site.name = \"x\"\n", `toml: line 1: invalid key "site.name": only bare keys are supported`},
+		{"missing equals", "\n\nport 4000\n", `toml: line 3: expected "=" after key`},
+		{"missing value", "port =\n", "toml: line 1: missing value"},
+		{"duplicate key", "port = 1\nport = 2\n", `toml: line 2: duplicate key "port"`},
+		{"duplicate key in a table", "[db]\ndsn = 'a'\n[limiter]\n[db]\ndsn = 'b'\n", `toml: line 5: duplicate key "dsn"`},
+		{"bare string", "name = bookclub\n", `toml: line 1: invalid value "bookclub"`},
+		{"hex integer", "n = 0x1F\n", `toml: line 1: invalid value "0x1F"`},
+		{"underscores", "n = 1_000\n", `toml: line 1: invalid value "1_000"`},
+		{"out of range", "n = 9223372036854775808\n", `toml: line 1: invalid value "9223372036854775808"`},
+		{"date", "d = 1979-05-27\n", `toml: line 1: invalid value "1979-05-27"`},
+		{"unterminated string", "name = \"bookclub\n", "toml: line 1: unterminated string"},
+		{"invalid escape", `name = "\q"` + "\n", `toml: line 1: invalid string "\q"`},
+		{"multi-line string", "name = \"\"\"x\"\"\"\n", "toml: line 1: multi-line strings are not supported"},
+		{"unterminated array", "a = [1, 2\n", "toml: line 1: unterminated array"},
+		{"array running to the end", "a = [\n  1,\n", "toml: line 1: unterminated array"},
+		{"array without commas", "a = [1 2]\n", "toml: line 1: unterminated array"},
+		{"nested array", "a = [[1], [2]]\n", "toml: line 1: nested arrays are not supported"},
+		{"inline table", "limiter = { rps = 2 }\n", "toml: line 1: inline tables are not supported"},
+		{"trailing content", "a = \"b\" c\n", `toml: line 1: unexpected content "c"`},
+		{"content after an array", "a = [1] 2\n", `toml: line 1: unexpected content "2"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.input))
+			if err == nil {
+				t.Fatalf("got no error, want %q", tt.want)
+			}
+			if err.Error() != tt.want {
+				t.Errorf("got error %q, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,408 @@
+// Package trace records spans with W3C Trace Context propagation and hands
+// them to an Exporter in batches. It follows the OpenTelemetry data model
+// closely enough for its spans to be sent to an OpenTelemetry collector.
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TraceID identifies a trace.
+type TraceID [16]byte
+
+// SpanID identifies a span within a trace.
+type SpanID [8]byte
+
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+func (id SpanID) String() string  { return hex.EncodeToString(id[:]) }
+
+// IsValid reports whether the ID is not all zeros.
+func (id TraceID) IsValid() bool { return id != TraceID{} }
+
+// IsValid reports whether the ID is not all zeros.
+func (id SpanID) IsValid() bool { return id != SpanID{} }
+
+// SpanContext is the part of a span that is propagated across processes.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+// IsValid reports whether both IDs are set.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// Traceparent formats the span context as a W3C traceparent header value.
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + flags
+}
+
+// ParseTraceparent parses a W3C traceparent header value. Versions other than
+// 00 are accepted as long as they start with the version 00 fields, as the
+// specification requires.
+func ParseTraceparent(header string) (SpanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return SpanContext{}, false
+	}
+	if parts[0] == "00" && len(parts) != 4 {
+		return SpanContext{}, false
+	}
+
+	var sc SpanContext
+	var flags [1]byte
+	if !decodeHex(sc.TraceID[:], parts[1]) || !decodeHex(sc.SpanID[:], parts[2]) || !decodeHex(flags[:], parts[3]) {
+		return SpanContext{}, false
+	}
+	if !decodeHex(make([]byte, 1), parts[0]) || !sc.IsValid() {
+		return SpanContext{}, false
+	}
+	sc.Sampled = flags[0]&1 == 1
+	return sc, true
+}
+
+// decodeHex decodes lowercase hex into dst, which s must fill exactly.
+func decodeHex(dst []byte, s string) bool {
+	if len(s) != 2*len(dst) || strings.ToLower(s) != s {
+		return false
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err == nil
+}
+
+// SpanKind says what role a span plays in a trace.
+type SpanKind int
+
+const (
+	KindInternal SpanKind = iota + 1
+	KindServer
+	KindClient
+)
+
+func (k SpanKind) String() string {
+	switch k {
+	case KindServer:
+		return "server"
+	case KindClient:
+		return "client"
+	default:
+		return "internal"
+	}
+}
+
+// Attribute is a key-value pair describing a span.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// SpanData is a finished span, as handed to an Exporter.
+type SpanData struct {
+	Name         string
+	SpanContext  SpanContext
+	ParentSpanID SpanID
+	Kind         SpanKind
+	Start        time.Time
+	End          time.Time
+	Attributes   []Attribute
+	Error        bool
+	ErrorMessage string
+}
+
+// Span is an operation being timed. A nil *Span is valid and records
+// nothing, so callers don't have to check whether tracing is enabled.
+type Span struct {
+	tracer *Tracer
+
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+type spanContextKey struct{}
+
+// SpanFromContext returns the span stored in ctx, or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanContextKey{}).(*Span)
+	return span
+}
+
+// ContextWithSpan returns a copy of ctx carrying span.
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, span)
+}
+
+// Start starts a child of the span in ctx. Without a span in ctx, tracing is
+// off for this operation and the returned span is nil.
+func Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	parent := SpanFromContext(ctx)
+	if parent == nil {
+		return ctx, nil
+	}
+
+	span := parent.tracer.newSpan(name, kind, parent.data.SpanContext.TraceID, parent.data.SpanContext.SpanID, parent.data.SpanContext.Sampled)
+	return ContextWithSpan(ctx, span), span
+}
+
+// SpanContext returns the propagated identity of the span.
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.data.SpanContext
+}
+
+// IsRecording reports whether the span will be exported.
+func (s *Span) IsRecording() bool {
+	return s != nil && s.data.SpanContext.Sampled
+}
+
+// SetName renames the span, for when a better name is only known later.
+func (s *Span) SetName(name string) {
+	if !s.IsRecording() {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Name = name
+}
+
+// SetAttributes adds attributes to the span, as alternating keys and values.
+func (s *Span) SetAttributes(keyValues ...any) {
+	if !s.IsRecording() {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := 0; i+1 < len(keyValues); i += 2 {
+		key := fmt.Sprint(keyValues[i])
+		s.data.Attributes = append(s.data.Attributes, Attribute{Key: key, Value: keyValues[i+1]})
+	}
+}
+
+// RecordError marks the span as failed with err, if err is not nil.
+func (s *Span) RecordError(err error) {
+	if err == nil || !s.IsRecording() {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Error = true
+	s.data.ErrorMessage = err.Error()
+}
+
+// End finishes the span and queues it for export. Calls after the first have
+// no effect.
+func (s *Span) End() {
+	if !s.IsRecording() {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+
+	s.tracer.enqueue(&data)
+}
+
+// Tracer starts root spans and exports the finished spans of a process.
+type Tracer struct {
+	exporter   Exporter
+	sampleRate float64
+	onError    func(error)
+
+	queue    chan *SpanData
+	flush    chan chan struct{}
+	stopOnce sync.Once
+	stopped  chan struct{}
+}
+
+const (
+	queueSize     = 2048
+	maxBatchSize  = 512
+	flushInterval = 2 * time.Second
+)
+
+// NewTracer returns a tracer that samples the given fraction (0 to 1) of the
+// traces it starts and sends their spans to exporter in the background.
+// Export errors are passed to onError, which may be nil.
+func NewTracer(exporter Exporter, sampleRate float64, onError func(error)) *Tracer {
+	t := &Tracer{
+		exporter:   exporter,
+		sampleRate: sampleRate,
+		onError:    onError,
+		queue:      make(chan *SpanData, queueSize),
+		flush:      make(chan chan struct{}),
+		stopped:    make(chan struct{}),
+	}
+	go t.run()
+	return t
+}
+
+// StartServer starts the root span of an incoming request. A valid
+// traceparent continues the caller's trace and keeps its sampling decision;
+// otherwise a new trace is started and sampled at the tracer's rate.
+func (t *Tracer) StartServer(ctx context.Context, name string, traceparent string) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+
+	var traceID TraceID
+	var parentID SpanID
+	var sampled bool
+
+	if remote, ok := ParseTraceparent(traceparent); ok {
+		traceID, parentID, sampled = remote.TraceID, remote.SpanID, remote.Sampled
+	} else {
+		rand.Read(traceID[:])
+		sampled = t.sample(traceID)
+	}
+
+	span := t.newSpan(name, KindServer, traceID, parentID, sampled)
+	return ContextWithSpan(ctx, span), span
+}
+
+// sample decides from the trace ID whether a new trace is recorded, so that
+// every service seeing the same ID makes the same decision.
+func (t *Tracer) sample(id TraceID) bool {
+	switch {
+	case t.sampleRate >= 1:
+		return true
+	case t.sampleRate <= 0:
+		return false
+	}
+	bound := uint64(t.sampleRate * (1 << 63))
+	return binary.BigEndian.Uint64(id[8:])>>1 < bound
+}
+
+func (t *Tracer) newSpan(name string, kind SpanKind, traceID TraceID, parentID SpanID, sampled bool) *Span {
+	span := &Span{tracer: t}
+	span.data = SpanData{
+		Name:         name,
+		SpanContext:  SpanContext{TraceID: traceID, Sampled: sampled},
+		ParentSpanID: parentID,
+		Kind:         kind,
+		Start:        time.Now(),
+	}
+	rand.Read(span.data.SpanContext.SpanID[:])
+	return span
+}
+
+func (t *Tracer) enqueue(span *SpanData) {
+	select {
+	case <-t.stopped:
+	case t.queue <- span:
+	default:
+		// Drop the span rather than slow down the request.
+		t.reportError(fmt.Errorf("trace: export queue full, dropped span %q", span.Name))
+	}
+}
+
+func (t *Tracer) run() {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*SpanData, 0, maxBatchSize)
+	export := func() {
+		if len(batch) == 0 {
+			return
+		}
+		err := t.exporter.Export(context.Background(), batch)
+		if err != nil {
+			t.reportError(err)
+		}
+		batch = make([]*SpanData, 0, maxBatchSize)
+	}
+	drain := func() {
+		for {
+			select {
+			case span := <-t.queue:
+				batch = append(batch, span)
+				if len(batch) == maxBatchSize {
+					export()
+				}
+			default:
+				export()
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case span := <-t.queue:
+			batch = append(batch, span)
+			if len(batch) == maxBatchSize {
+				export()
+			}
+		case <-ticker.C:
+			export()
+		case done := <-t.flush:
+			drain()
+			close(done)
+		case <-t.stopped:
+			drain()
+			return
+		}
+	}
+}
+
+// ForceFlush exports the spans that have ended so far.
+func (t *Tracer) ForceFlush(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	done := make(chan struct{})
+	select {
+	case t.flush <- done:
+	case <-t.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown exports the remaining spans and shuts the exporter down. Spans
+// ending afterwards are dropped.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	err := t.ForceFlush(ctx)
+	t.stopOnce.Do(func() { close(t.stopped) })
+	if err != nil {
+		return err
+	}
+	return t.exporter.Shutdown(ctx)
+}
+
+func (t *Tracer) reportError(err error) {
+	if t.onError != nil {
+		t.onError(err)
+	}
+}
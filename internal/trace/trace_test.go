@@ -0,0 +1,149 @@
+package trace
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	const (
+		traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		spanID  = "00f067aa0ba902b7"
+	)
+
+	tests := []struct {
+		name    string
+		header  string
+		ok      bool
+		sampled bool
+	}{
+		{"sampled", "00-" + traceID + "-" + spanID + "-01", true, true},
+		{"not sampled", "00-" + traceID + "-" + spanID + "-00", true, false},
+		{"other flags", "00-" + traceID + "-" + spanID + "-03", true, true},
+		{"surrounding space", " 00-" + traceID + "-" + spanID + "-01 ", true, true},
+		{"future version", "01-" + traceID + "-" + spanID + "-01", true, true},
+		{"future version with more fields", "cc-" + traceID + "-" + spanID + "-01-what-the-future-holds", true, true},
+		{"version 00 with more fields", "00-" + traceID + "-" + spanID + "-01-extra", false, false},
+		{"version ff", "ff-" + traceID + "-" + spanID + "-01", false, false},
+		{"uppercase version", "0A-" + traceID + "-" + spanID + "-01", false, false},
+		{"non-hex version", "zz-" + traceID + "-" + spanID + "-01", false, false},
+		{"long version", "000-" + traceID + "-" + spanID + "-01", false, false},
+		{"uppercase trace ID", "00-4BF92F3577B34DA6A3CE929D0E0E4736-" + spanID + "-01", false, false},
+		{"uppercase span ID", "00-" + traceID + "-00F067AA0BA902B7-01", false, false},
+		{"uppercase flags", "00-" + traceID + "-" + spanID + "-0A", false, false},
+		{"all-zero trace ID", "00-00000000000000000000000000000000-" + spanID + "-01", false, false},
+		{"all-zero span ID", "00-" + traceID + "-0000000000000000-01", false, false},
+		{"short trace ID", "00-" + traceID[2:] + "-" + spanID + "-01", false, false},
+		{"short span ID", "00-" + traceID + "-" + spanID[2:] + "-01", false, false},
+		{"short flags", "00-" + traceID + "-" + spanID + "-1", false, false},
+		{"missing flags", "00-" + traceID + "-" + spanID, false, false},
+		{"empty", "", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc, ok := ParseTraceparent(tt.header)
+			if ok != tt.ok {
+				t.Fatalf("ParseTraceparent(%q) ok = %t, want %t", tt.header, ok, tt.ok)
+			}
+			if !ok {
+				if sc != (SpanContext{}) {
+					t.Errorf("got %+v with a rejected header, want the zero SpanContext", sc)
+				}
+				return
+			}
+			if sc.TraceID.String() != traceID || sc.SpanID.String() != spanID {
+				t.Errorf("got IDs %s and %s", sc.TraceID, sc.SpanID)
+			}
+			if sc.Sampled != tt.sampled {
+				t.Errorf("got sampled %t, want %t", sc.Sampled, tt.sampled)
+			}
+		})
+	}
+}
+
+func TestTraceparentRoundTrip(t *testing.T) {
+	for _, sampled := range []bool{true, false} {
+		sc := SpanContext{Sampled: sampled}
+		copy(sc.TraceID[:], "0123456789abcdef")
+		copy(sc.SpanID[:], "01234567")
+
+		got, ok := ParseTraceparent(sc.Traceparent())
+		if !ok || got != sc {
+			t.Errorf("ParseTraceparent(%q) = %+v, %t, want %+v", sc.Traceparent(), got, ok, sc)
+		}
+	}
+}
+
+func TestSample(t *testing.T) {
+	// withLow returns a trace ID whose last eight bytes, which sample reads,
+	// are low.
+	withLow := func(low uint64) TraceID {
+		var id TraceID
+		id[0] = 0xff
+		binary.BigEndian.PutUint64(id[8:], low)
+		return id
+	}
+
+	tests := []struct {
+		rate float64
+		low  uint64
+		want bool
+	}{
+		{1, ^uint64(0), true},
+		{2, ^uint64(0), true},
+		{0, 0, false},
+		{-1, 0, false},
+		{0.5, 0, true},
+		{0.5, 1<<63 - 1, true},
+		{0.5, 1 << 63, false},
+		{0.5, ^uint64(0), false},
+		{0.25, 1<<62 - 1, true},
+		{0.25, 1 << 62, false},
+		{1e-9, 0, true},
+		{1e-9, 1 << 40, false},
+	}
+	for _, tt := range tests {
+		tracer := &Tracer{sampleRate: tt.rate}
+		if got := tracer.sample(withLow(tt.low)); got != tt.want {
+			t.Errorf("rate %v: sample(%#x) = %t, want %t", tt.rate, tt.low, got, tt.want)
+		}
+	}
+}
+
+func TestStartServer(t *testing.T) {
+	exporter := NewWriterExporter(io.Discard)
+	tracer := NewTracer(exporter, 0, nil)
+	defer tracer.Shutdown(context.Background())
+
+	// A remote sampling decision wins over the tracer's rate.
+	remote := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	ctx, span := tracer.StartServer(context.Background(), "GET /v1/books", remote)
+	if !span.IsRecording() {
+		t.Error("the span of a sampled remote trace isn't recording")
+	}
+	if got := span.SpanContext().TraceID.String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("got trace ID %s", got)
+	}
+	if span.data.ParentSpanID.String() != "00f067aa0ba902b7" {
+		t.Errorf("got parent span ID %s", span.data.ParentSpanID)
+	}
+
+	_, child := Start(ctx, "query", KindClient)
+	if child.SpanContext().TraceID != span.SpanContext().TraceID || child.data.ParentSpanID != span.SpanContext().SpanID {
+		t.Errorf("the child span %+v isn't part of its parent's trace", child.data)
+	}
+
+	// Without a traceparent, a rate of 0 samples nothing.
+	_, span = tracer.StartServer(context.Background(), "GET /v1/books", "")
+	if span.IsRecording() || !span.SpanContext().IsValid() {
+		t.Errorf("got span context %+v, want valid and unsampled", span.SpanContext())
+	}
+
+	// Without a span in the context, tracing is off.
+	if _, span := Start(context.Background(), "query", KindClient); span != nil {
+		t.Error("Start without a parent returned a span")
+	}
+}
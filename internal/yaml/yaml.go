@@ -0,0 +1,363 @@
+// Package yaml decodes the subset of YAML accepted in config files:
+//
+//	# A comment
+//	port: 4000
+//	db:
+//	  dsn: "postgres://localhost/bookclub"
+//	  replica_dsn:
+//	    - postgres://replica-1/bookclub
+//	    - postgres://replica-2/bookclub
+//	cors:
+//	  trusted_origins: [https://a.example, https://b.example]
+//
+// The document is a block mapping of bare keys whose values are nested block
+// mappings, plain or quoted scalars, or sequences of scalars, either as block
+// items or in [flow] form. Anything else, such as block scalars, flow mappings,
+// sequences of collections, nulls, anchors, tags and multi-document streams,
+// is rejected.
+package yaml
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	bareKeyRX = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	integerRX = regexp.MustCompile(`^[+-]?[0-9]+$`)
+	floatRX   = regexp.MustCompile(`^[+-]?[0-9]+(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+)
+
+// Parse parses a YAML document into maps (map[string]any), slices ([]any) and
+// scalars (string, int64, float64 or bool).
+func Parse(data []byte) (map[string]any, error) {
+	p := &parser{}
+	for n, text := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimLeft(text, " ")
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, fmt.Errorf("yaml: line %d: tabs are not allowed for indentation", n+1)
+		}
+		p.lines = append(p.lines, line{number: n + 1, indent: len(text) - len(trimmed), text: stripComment(trimmed)})
+	}
+
+	p.skipBlank()
+	if p.pos >= len(p.lines) {
+		return map[string]any{}, nil
+	}
+	if indent := p.lines[p.pos].indent; indent != 0 {
+		return nil, p.errorf("unexpected indentation")
+	}
+
+	mapping, err := p.parseMapping(0)
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipBlank()
+	if p.pos < len(p.lines) {
+		return nil, p.errorf("unexpected content %q", p.lines[p.pos].text)
+	}
+
+	return mapping, nil
+}
+
+type line struct {
+	number int
+	indent int
+	text   string
+}
+
+type parser struct {
+	lines []line
+	pos   int
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	number := 0
+	if p.pos < len(p.lines) {
+		number = p.lines[p.pos].number
+	}
+	return fmt.Errorf("yaml: line %d: %s", number, fmt.Sprintf(format, args...))
+}
+
+// skipBlank moves past empty and comment lines.
+func (p *parser) skipBlank() {
+	for p.pos < len(p.lines) && p.lines[p.pos].text == "" {
+		p.pos++
+	}
+}
+
+// parseMapping parses the block mapping whose keys are at indent.
+func (p *parser) parseMapping(indent int) (map[string]any, error) {
+	mapping := map[string]any{}
+
+	for {
+		p.skipBlank()
+		if p.pos >= len(p.lines) {
+			break
+		}
+		l := p.lines[p.pos]
+		if l.indent < indent {
+			break
+		}
+		if l.indent > indent {
+			return nil, p.errorf("unexpected indentation")
+		}
+		if l.text == "---" || l.text == "..." {
+			return nil, p.errorf("multi-document streams are not supported")
+		}
+		if isSequenceItem(l.text) {
+			return nil, p.errorf("expected \"key: value\", got %q", l.text)
+		}
+
+		key, rest, err := splitKey(l.text)
+		if err != nil {
+			return nil, p.errorf("%v", err)
+		}
+		if _, exists := mapping[key]; exists {
+			return nil, p.errorf("duplicate key %q", key)
+		}
+
+		if rest != "" {
+			value, err := parseValue(rest)
+			if err != nil {
+				return nil, p.errorf("%v", err)
+			}
+			mapping[key] = value
+			p.pos++
+			continue
+		}
+
+		// A key without a value on its line is followed by a nested mapping
+		// or by a sequence, which may be at the key's own indentation.
+		start := p.pos
+		p.pos++
+		p.skipBlank()
+		var next line
+		if p.pos < len(p.lines) {
+			next = p.lines[p.pos]
+		}
+		switch {
+		case p.pos < len(p.lines) && next.indent >= indent && isSequenceItem(next.text):
+			mapping[key], err = p.parseSequence(next.indent)
+		case p.pos < len(p.lines) && next.indent > indent:
+			mapping[key], err = p.parseMapping(next.indent)
+		default:
+			p.pos = start
+			return nil, p.errorf("missing value for %q", key)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return mapping, nil
+}
+
+// parseSequence parses the block sequence of scalars whose items are at
+// indent.
+func (p *parser) parseSequence(indent int) ([]any, error) {
+	items := []any{}
+
+	for {
+		p.skipBlank()
+		if p.pos >= len(p.lines) {
+			break
+		}
+		l := p.lines[p.pos]
+		if l.indent < indent || (l.indent == indent && !isSequenceItem(l.text)) {
+			break
+		}
+		if l.indent > indent {
+			return nil, p.errorf("unexpected indentation")
+		}
+
+		text := strings.TrimLeft(strings.TrimPrefix(l.text, "-"), " ")
+		if text == "" || text[0] == '[' || isSequenceItem(text) {
+			return nil, p.errorf("only sequences of scalars are supported")
+		}
+		if _, _, err := splitKey(text); err == nil {
+			return nil, p.errorf("only sequences of scalars are supported")
+		}
+
+		item, err := parseScalar(text)
+		if err != nil {
+			return nil, p.errorf("%v", err)
+		}
+		items = append(items, item)
+		p.pos++
+	}
+
+	return items, nil
+}
+
+func isSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// splitKey splits "key: value" into its bare key and the rest of the line.
+func splitKey(text string) (string, string, error) {
+	for i := 0; i < len(text); i++ {
+		if text[i] == ':' && (i == len(text)-1 || text[i+1] == ' ') {
+			key := strings.TrimSpace(text[:i])
+			if !bareKeyRX.MatchString(key) {
+				return "", "", fmt.Errorf("invalid key %q: only bare keys are supported", key)
+			}
+			return key, strings.TrimSpace(text[i+1:]), nil
+		}
+	}
+	return "", "", fmt.Errorf("expected \"key: value\", got %q", text)
+}
+
+// parseValue parses the value following "key: ", a scalar or a flow sequence
+// of scalars.
+func parseValue(text string) (any, error) {
+	if text[0] != '[' {
+		return parseScalar(text)
+	}
+
+	items := []any{}
+	rest := strings.TrimSpace(text[1:])
+	for {
+		if strings.HasPrefix(rest, "]") {
+			if tail := strings.TrimSpace(rest[1:]); tail != "" {
+				return nil, fmt.Errorf("unexpected %q after value", tail)
+			}
+			return items, nil
+		}
+
+		if rest != "" && (rest[0] == '"' || rest[0] == '\'') {
+			quoted, r, err := parseQuoted(rest)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, quoted)
+			rest = r
+		} else {
+			end := strings.IndexAny(rest, ",]")
+			if end < 0 {
+				return nil, errors.New("unterminated flow sequence")
+			}
+			item := strings.TrimSpace(rest[:end])
+			if item == "" {
+				return nil, errors.New("empty item in flow sequence")
+			}
+			value, err := parseScalar(item)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, value)
+			rest = rest[end:]
+		}
+
+		rest = strings.TrimSpace(rest)
+		if strings.HasPrefix(rest, ",") {
+			rest = strings.TrimSpace(rest[1:])
+		} else if !strings.HasPrefix(rest, "]") {
+			return nil, errors.New("unterminated flow sequence")
+		}
+	}
+}
+
+// parseScalar parses text, which must hold exactly one plain or quoted
+// scalar.
+func parseScalar(text string) (any, error) {
+	switch text[0] {
+	case '"', '\'':
+		s, rest, err := parseQuoted(text)
+		if err != nil {
+			return nil, err
+		}
+		if rest = strings.TrimSpace(rest); rest != "" {
+			return nil, fmt.Errorf("unexpected %q after value", rest)
+		}
+		return s, nil
+	case '[':
+		return nil, errors.New("nested sequences are not supported")
+	case '{':
+		return nil, errors.New("flow mappings are not supported")
+	case '|', '>':
+		return nil, errors.New("block scalars are not supported")
+	case '&', '*', '!':
+		return nil, errors.New("anchors, aliases and tags are not supported")
+	}
+	return parsePlain(text)
+}
+
+// parseQuoted parses a single- or double-quoted scalar at the start of text
+// and returns the unparsed remainder.
+func parseQuoted(text string) (string, string, error) {
+	quote := text[0]
+	for i := 1; i < len(text); i++ {
+		switch {
+		case quote == '"' && text[i] == '\\':
+			i++
+		case quote == '\'' && text[i] == '\'' && i+1 < len(text) && text[i+1] == '\'':
+			i++
+		case text[i] == quote:
+			if quote == '\'' {
+				return strings.ReplaceAll(text[1:i], "''", "'"), text[i+1:], nil
+			}
+			s, err := strconv.Unquote(text[:i+1])
+			if err != nil {
+				return "", "", fmt.Errorf("invalid quoted string %s", text[:i+1])
+			}
+			return s, text[i+1:], nil
+		}
+	}
+	return "", "", errors.New("unterminated quoted string")
+}
+
+// parsePlain resolves an unquoted scalar to a boolean, a decimal number or a
+// string.
+func parsePlain(text string) (any, error) {
+	switch text {
+	case "~", "null", "Null", "NULL":
+		return nil, errors.New("null values are not supported")
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	}
+	if strings.Contains(text, ": ") || strings.HasSuffix(text, ":") {
+		return nil, fmt.Errorf("unexpected mapping in value %q", text)
+	}
+
+	switch {
+	case integerRX.MatchString(text):
+		if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return i, nil
+		}
+	case floatRX.MatchString(text):
+		if f, err := strconv.ParseFloat(text, 64); err == nil {
+			return f, nil
+		}
+	}
+	return text, nil
+}
+
+// stripComment removes a trailing comment that isn't inside a quoted string.
+func stripComment(text string) string {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if i == 0 || strings.ContainsRune(" [,:-", rune(text[i-1])) {
+				quote = c
+			}
+		case c == '#' && (i == 0 || text[i-1] == ' '):
+			return strings.TrimRight(text[:i], " ")
+		}
+	}
+	return strings.TrimRight(text, " ")
+}
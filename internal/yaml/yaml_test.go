@@ -0,0 +1,147 @@
+package yaml
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  map[string]any
+	}{
+		{"empty", "", map[string]any{}},
+		{"only comments", "# nothing\n\n# here\n", map[string]any{}},
+		{
+			"plain scalars",
+			"int: 42\nnegative: -7\nfloat: 2.5\nexponent: 1e3\nhex: 0x1F\non: true\noff: False\nstring: hello world\nurl: postgres://localhost:5432/bookclub?sslmode=disable\ndate: 1965-08-01\n",
+			map[string]any{
+				"int":      int64(42),
+				"negative": int64(-7),
+				"float":    2.5,
+				"exponent": 1000.0,
+				"hex":      "0x1F",
+				"on":       true,
+				"off":      false,
+				"string":   "hello world",
+				"url":      "postgres://localhost:5432/bookclub?sslmode=disable",
+				"date":     "1965-08-01",
+			},
+		},
+		{
+			"quoted scalars",
+			`double: "4000"` + "\n" +
+				`escapes: "tab\there \"quoted\""` + "\n" +
+				`single: 'it''s'` + "\n" +
+				`colon: "a: b"` + "\n" +
+				`bool: "true"` + "\n",
+			map[string]any{
+				"double":  "4000",
+				"escapes": "tab\there \"quoted\"",
+				"single":  "it's",
+				"colon":   "a: b",
+				"bool":    "true",
+			},
+		},
+		{
+			"comments",
+			"# header\na: 1 # trailing\nb: \"# not a comment\"\nc: 'x # y'\nd: a#b\n",
+			map[string]any{"a": int64(1), "b": "# not a comment", "c": "x # y", "d": "a#b"},
+		},
+		{
+			"nested mappings",
+			"db:\n  dsn: postgres://localhost\n\n  pool:\n    max: 25\n    idle: 5\nport: 4000\n",
+			map[string]any{
+				"db": map[string]any{
+					"dsn":  "postgres://localhost",
+					"pool": map[string]any{"max": int64(25), "idle": int64(5)},
+				},
+				"port": int64(4000),
+			},
+		},
+		{
+			"block sequences",
+			"db:\n  replica_dsn:\n    - postgres://replica-1/bookclub\n    # a comment\n    - 'postgres://replica-2/bookclub'\n  dsn: x\norigins:\n- a\n- 2\nport: 1\n",
+			map[string]any{
+				"db": map[string]any{
+					"replica_dsn": []any{"postgres://replica-1/bookclub", "postgres://replica-2/bookclub"},
+					"dsn":         "x",
+				},
+				"origins": []any{"a", int64(2)},
+				"port":    int64(1),
+			},
+		},
+		{
+			"flow sequences",
+			`list: [1, "two, three", 'four', five]` + "\nempty: []\nspaced: [ a , b ]\n",
+			map[string]any{
+				"list":   []any{int64(1), "two, three", "four", "five"},
+				"empty":  []any{},
+				"spaced": []any{"a", "b"},
+			},
+		},
+		{"windows line endings", "a: 1\r\nb: 2\r\n", map[string]any{"a": int64(1), "b": int64(2)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"tab indentation", "a:\n\tb: 1\n", "yaml: line 2: tabs are not allowed for indentation"},
+		{"indented document", "  a: 1\n", "yaml: line 1: unexpected indentation"},
+		{"over-indented key", "a: 1\n  b: 2\n", "yaml: line 2: unexpected indentation"},
+		{"over-indented item", "a:\n  - x\n   - y\n", "yaml: line 3: unexpected indentation"},
+		{"top-level sequence", "- a\n", `yaml: line 1: expected "key: value", got "- a"`},
+		{"missing colon", "a: 1\nb\n", `yaml: line 2: expected "key: value", got "b"`},
+		{"quoted key", "\"a b\": 1\n", `yaml: line 1: invalid key "\"a b\"": only bare keys are supported`},
+		{"missing value", "a:\n# comment\nb: 1\n", `yaml: line 1: missing value for "a"`},
+		{"missing value at the end", "a: 1\nb:\n", `yaml: line 2: missing value for "b"`},
+		{"duplicate key", "a: 1\n# comment\na: 2\n", `yaml: line 3: duplicate key "a"`},
+		{"document marker", "---\na: 1\n", "yaml: line 1: multi-document streams are not supported"},
+		{"null", "a: ~\n", "yaml: line 1: null values are not supported"},
+		{"block scalar", "a: |\n  text\n", "yaml: line 1: block scalars are not supported"},
+		{"flow mapping", "a: {b: 1}\n", "yaml: line 1: flow mappings are not supported"},
+		{"anchor", "a: &x 1\n", "yaml: line 1: anchors, aliases and tags are not supported"},
+		{"tag", "a: !!str 1\n", "yaml: line 1: anchors, aliases and tags are not supported"},
+		{"mapping in a value", "a: b: c\n", `yaml: line 1: unexpected mapping in value "b: c"`},
+		{"sequence of mappings", "a:\n  - b: 1\n", "yaml: line 2: only sequences of scalars are supported"},
+		{"nested block sequence", "a:\n  - - b\n", "yaml: line 2: only sequences of scalars are supported"},
+		{"empty item", "a:\n  -\n", "yaml: line 2: only sequences of scalars are supported"},
+		{"nested flow sequence", "a: [[1], 2]\n", "yaml: line 1: nested sequences are not supported"},
+		{"unterminated flow sequence", "a: [1, 2\n", "yaml: line 1: unterminated flow sequence"},
+		{"empty flow item", "a: [1, , 2]\n", "yaml: line 1: empty item in flow sequence"},
+		{"unterminated double quote", "a: \"open\n", "yaml: line 1: unterminated quoted string"},
+		{"unterminated single quote", "\n\nb: 'open\n", "yaml: line 3: unterminated quoted string"},
+		{"invalid escape", `a: "\q"` + "\n", `yaml: line 1: invalid quoted string "\q"`},
+		{"trailing text", "a: \"b\" c\n", `yaml: line 1: unexpected "c" after value`},
+		{"text after a flow sequence", "a: [b] c\n", `yaml: line 1: unexpected "c" after value`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.input))
+			if err == nil {
+				t.Fatalf("got no error, want %q", tt.want)
+			}
+			if err.Error() != tt.want {
+				t.Errorf("got error %q, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -1,4 +1,5 @@
-DROP TABLE IF EXISTS books;
-DROP TABLE IF EXISTS reading_lists;
+DROP TABLE IF EXISTS reviews;
 DROP TABLE IF EXISTS book_reviews;
+DROP TABLE IF EXISTS reading_lists;
 DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS books;
@@ -9,6 +9,13 @@ CREATE TABLE books (
     average_rating FLOAT DEFAULT 0
 );
 
+CREATE TABLE users (
+    id SERIAL PRIMARY KEY,
+    username VARCHAR(50) UNIQUE NOT NULL,
+    email VARCHAR(100) UNIQUE NOT NULL,
+    reading_lists INT[] DEFAULT '{}',
+    reviews INT[] DEFAULT '{}'
+);
 
 CREATE TABLE reading_lists (
     id SERIAL PRIMARY KEY,
@@ -28,14 +35,6 @@ CREATE TABLE book_reviews (
     review_date DATE DEFAULT CURRENT_DATE
 );
 
-CREATE TABLE users (
-    id SERIAL PRIMARY KEY,
-    username VARCHAR(50) UNIQUE NOT NULL,
-    email VARCHAR(100) UNIQUE NOT NULL,
-    reading_lists INT[] DEFAULT '{}',
-    reviews INT[] DEFAULT '{}'
-);
-
 CREATE TABLE reviews (
     id SERIAL PRIMARY KEY,
     book_id INT NOT NULL REFERENCES books(id) ON DELETE CASCADE,
@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS authors (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    normalized_name VARCHAR(255) UNIQUE NOT NULL,
+    bio TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS book_authors (
+    book_id INT NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    author_id INT NOT NULL REFERENCES authors(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL DEFAULT 'author' CHECK (role IN ('author', 'translator', 'illustrator', 'editor')),
+    position INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (book_id, author_id, role)
+);
+
+CREATE INDEX IF NOT EXISTS book_authors_author_id_idx ON book_authors (author_id);
+
+-- Backfill authors from the legacy books.authors array. Names that only differ
+-- in case, punctuation or spacing ("J.R.R. Tolkien" / "J. R. R. Tolkien")
+-- collapse onto a single author.
+INSERT INTO authors (name, normalized_name)
+SELECT DISTINCT ON (normalized_name) name, normalized_name
+FROM (
+    SELECT btrim(a.name) AS name, regexp_replace(lower(a.name), '[^[:alnum:]]', '', 'g') AS normalized_name
+    FROM books CROSS JOIN LATERAL unnest(books.authors) AS a(name)
+) AS names
+WHERE normalized_name <> ''
+ORDER BY normalized_name, name
+ON CONFLICT (normalized_name) DO NOTHING;
+
+INSERT INTO book_authors (book_id, author_id, role, position)
+SELECT b.id, au.id, 'author', MIN(a.position)::INT - 1
+FROM books b
+CROSS JOIN LATERAL unnest(b.authors) WITH ORDINALITY AS a(name, position)
+JOIN authors au ON au.normalized_name = regexp_replace(lower(a.name), '[^[:alnum:]]', '', 'g')
+GROUP BY b.id, au.id
+ON CONFLICT DO NOTHING;
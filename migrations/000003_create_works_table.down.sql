@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS books_work_id_idx;
+
+ALTER TABLE books
+    DROP COLUMN IF EXISTS work_id,
+    DROP COLUMN IF EXISTS format,
+    DROP COLUMN IF EXISTS language,
+    DROP COLUMN IF EXISTS page_count,
+    DROP COLUMN IF EXISTS publisher;
+
+DROP TABLE IF EXISTS works;
@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS works (
+    id SERIAL PRIMARY KEY,
+    title VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Every book row becomes an edition of a work.
+ALTER TABLE books
+    ADD COLUMN IF NOT EXISTS work_id INT REFERENCES works(id) ON DELETE RESTRICT,
+    ADD COLUMN IF NOT EXISTS format VARCHAR(20) NOT NULL DEFAULT 'unknown'
+        CHECK (format IN ('hardcover', 'paperback', 'ebook', 'audiobook', 'unknown')),
+    ADD COLUMN IF NOT EXISTS language VARCHAR(10) NOT NULL DEFAULT 'en',
+    ADD COLUMN IF NOT EXISTS page_count INT NOT NULL DEFAULT 0 CHECK (page_count >= 0),
+    ADD COLUMN IF NOT EXISTS publisher VARCHAR(255) NOT NULL DEFAULT '';
+
+-- Backfill one work per existing book. Editions of the same work can be
+-- regrouped afterwards by updating books.work_id.
+DO $$
+DECLARE
+    b RECORD;
+    new_work_id INT;
+BEGIN
+    FOR b IN SELECT id, title, COALESCE(description, '') AS description FROM books WHERE work_id IS NULL LOOP
+        INSERT INTO works (title, description) VALUES (b.title, b.description) RETURNING id INTO new_work_id;
+        UPDATE books SET work_id = new_work_id WHERE id = b.id;
+    END LOOP;
+END $$;
+
+ALTER TABLE books ALTER COLUMN work_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS books_work_id_idx ON books (work_id);
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS series (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Entries point at works so that any edition counts towards the series.
+-- Positions are fractional so novellas can sit between numbered entries (2.5).
+CREATE TABLE IF NOT EXISTS series_entries (
+    series_id INT NOT NULL REFERENCES series(id) ON DELETE CASCADE,
+    work_id INT NOT NULL REFERENCES works(id) ON DELETE CASCADE,
+    position NUMERIC(6, 2) NOT NULL CHECK (position >= 0),
+    PRIMARY KEY (series_id, work_id),
+    UNIQUE (series_id, position)
+);
+
+CREATE INDEX IF NOT EXISTS series_entries_work_id_idx ON series_entries (work_id);
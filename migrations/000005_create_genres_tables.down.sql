@@ -0,0 +1,15 @@
+ALTER TABLE books ADD COLUMN IF NOT EXISTS genre VARCHAR(50);
+
+UPDATE books b
+SET genre = (
+    SELECT left(g.name, 50)
+    FROM book_genres bg
+    JOIN genres g ON g.id = bg.genre_id
+    WHERE bg.book_id = b.id
+    ORDER BY g.name
+    LIMIT 1
+);
+
+DROP TABLE IF EXISTS book_genres;
+DROP TABLE IF EXISTS genre_aliases;
+DROP TABLE IF EXISTS genres;
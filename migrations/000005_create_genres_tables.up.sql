@@ -0,0 +1,108 @@
+CREATE TABLE IF NOT EXISTS genres (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    slug VARCHAR(100) UNIQUE NOT NULL,
+    parent_id INT REFERENCES genres(id) ON DELETE RESTRICT,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS genres_parent_id_idx ON genres (parent_id);
+
+-- Aliases are stored normalized (lower-cased letters and digits only) so that
+-- "Sci-Fi", "sci fi" and "SciFi" all resolve to the same genre.
+CREATE TABLE IF NOT EXISTS genre_aliases (
+    alias VARCHAR(100) PRIMARY KEY,
+    genre_id INT NOT NULL REFERENCES genres(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS book_genres (
+    book_id INT NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    genre_id INT NOT NULL REFERENCES genres(id) ON DELETE CASCADE,
+    PRIMARY KEY (book_id, genre_id)
+);
+
+CREATE INDEX IF NOT EXISTS book_genres_genre_id_idx ON book_genres (genre_id);
+
+-- Base taxonomy.
+INSERT INTO genres (name, slug) VALUES
+    ('Fiction', 'fiction'),
+    ('Non-Fiction', 'non-fiction')
+ON CONFLICT (slug) DO NOTHING;
+
+INSERT INTO genres (name, slug, parent_id)
+SELECT v.name, v.slug, p.id
+FROM (VALUES
+    ('Science Fiction', 'science-fiction', 'fiction'),
+    ('Fantasy', 'fantasy', 'fiction'),
+    ('Mystery', 'mystery', 'fiction'),
+    ('Thriller', 'thriller', 'fiction'),
+    ('Romance', 'romance', 'fiction'),
+    ('Horror', 'horror', 'fiction'),
+    ('Historical Fiction', 'historical-fiction', 'fiction'),
+    ('Literary Fiction', 'literary-fiction', 'fiction'),
+    ('Young Adult', 'young-adult', 'fiction'),
+    ('Biography', 'biography', 'non-fiction'),
+    ('History', 'history', 'non-fiction'),
+    ('Science', 'science', 'non-fiction'),
+    ('Self-Help', 'self-help', 'non-fiction'),
+    ('Essays', 'essays', 'non-fiction')
+) AS v(name, slug, parent)
+JOIN genres p ON p.slug = v.parent
+ON CONFLICT (slug) DO NOTHING;
+
+INSERT INTO genres (name, slug, parent_id)
+SELECT v.name, v.slug, p.id
+FROM (VALUES
+    ('Space Opera', 'space-opera', 'science-fiction'),
+    ('Cyberpunk', 'cyberpunk', 'science-fiction'),
+    ('Epic Fantasy', 'epic-fantasy', 'fantasy'),
+    ('Urban Fantasy', 'urban-fantasy', 'fantasy'),
+    ('Memoir', 'memoir', 'biography')
+) AS v(name, slug, parent)
+JOIN genres p ON p.slug = v.parent
+ON CONFLICT (slug) DO NOTHING;
+
+INSERT INTO genre_aliases (alias, genre_id)
+SELECT v.alias, g.id
+FROM (VALUES
+    ('scifi', 'science-fiction'),
+    ('sf', 'science-fiction'),
+    ('nonfiction', 'non-fiction'),
+    ('ya', 'young-adult'),
+    ('autobiography', 'memoir'),
+    ('crime', 'mystery'),
+    ('suspense', 'thriller')
+) AS v(alias, slug)
+JOIN genres g ON g.slug = v.slug
+ON CONFLICT (alias) DO NOTHING;
+
+-- Legacy free-text genres that don't match the taxonomy become top-level genres.
+INSERT INTO genres (name, slug)
+SELECT name, trim(BOTH '-' FROM regexp_replace(lower(name), '[^[:alnum:]]+', '-', 'g'))
+FROM (
+    SELECT DISTINCT ON (alias) name, alias
+    FROM (
+        SELECT btrim(genre) AS name, regexp_replace(lower(genre), '[^[:alnum:]]', '', 'g') AS alias
+        FROM books
+        WHERE genre IS NOT NULL
+    ) AS legacy
+    WHERE alias <> ''
+    ORDER BY alias, name
+) AS legacy_genres
+WHERE alias NOT IN (SELECT regexp_replace(lower(name), '[^[:alnum:]]', '', 'g') FROM genres)
+AND alias NOT IN (SELECT alias FROM genre_aliases)
+ON CONFLICT (slug) DO NOTHING;
+
+-- Every genre name is an alias of itself.
+INSERT INTO genre_aliases (alias, genre_id)
+SELECT regexp_replace(lower(name), '[^[:alnum:]]', '', 'g'), id
+FROM genres
+ON CONFLICT (alias) DO NOTHING;
+
+INSERT INTO book_genres (book_id, genre_id)
+SELECT b.id, ga.genre_id
+FROM books b
+JOIN genre_aliases ga ON ga.alias = regexp_replace(lower(b.genre), '[^[:alnum:]]', '', 'g')
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE books DROP COLUMN IF EXISTS genre;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS user_book_tags;
+DROP TABLE IF EXISTS tags;
@@ -0,0 +1,12 @@
+ALTER TABLE reviews DROP COLUMN IF EXISTS user_id;
+
+DROP TABLE IF EXISTS reading_list_books;
+
+ALTER TABLE reading_lists
+    DROP COLUMN IF EXISTS updated_at,
+    DROP COLUMN IF EXISTS created_at;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS updated_at,
+    DROP COLUMN IF EXISTS created_at,
+    DROP COLUMN IF EXISTS password;
@@ -0,0 +1,25 @@
+ALTER TABLE users
+    ADD COLUMN password TEXT NOT NULL DEFAULT '',
+    ADD COLUMN created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    ADD COLUMN updated_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW();
+
+ALTER TABLE reading_lists
+    ADD COLUMN created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    ADD COLUMN updated_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW();
+
+CREATE TABLE IF NOT EXISTS reading_list_books (
+    reading_list_id INT NOT NULL REFERENCES reading_lists(id) ON DELETE CASCADE,
+    book_id INT NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    added_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (reading_list_id, book_id)
+);
+
+INSERT INTO reading_list_books (reading_list_id, book_id)
+SELECT rl.id, b.id
+FROM reading_lists rl
+CROSS JOIN LATERAL unnest(rl.books) AS list_book(id)
+JOIN books b ON b.id = list_book.id
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE reviews
+    ADD COLUMN user_id INT REFERENCES users(id) ON DELETE SET NULL;
@@ -0,0 +1 @@
+ALTER TABLE reading_list_books DROP COLUMN IF EXISTS position;
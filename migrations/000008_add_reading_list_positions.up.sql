@@ -0,0 +1,12 @@
+ALTER TABLE reading_list_books
+    ADD COLUMN position INT NOT NULL DEFAULT 0;
+
+UPDATE reading_list_books rlb
+SET position = ordered.position
+FROM (
+    SELECT reading_list_id, book_id,
+        ROW_NUMBER() OVER (PARTITION BY reading_list_id ORDER BY added_at, book_id) AS position
+    FROM reading_list_books
+) AS ordered
+WHERE rlb.reading_list_id = ordered.reading_list_id
+AND rlb.book_id = ordered.book_id;
@@ -0,0 +1,3 @@
+ALTER TABLE reviews DROP COLUMN IF EXISTS updated_at;
+
+ALTER TABLE books DROP COLUMN IF EXISTS updated_at;
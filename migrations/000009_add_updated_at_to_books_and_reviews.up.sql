@@ -0,0 +1,7 @@
+ALTER TABLE books
+    ADD COLUMN updated_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW();
+
+ALTER TABLE reviews
+    ADD COLUMN updated_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW();
+
+UPDATE reviews SET updated_at = created_at;
@@ -0,0 +1,10 @@
+// Package migrations embeds the SQL migration files so the api binary can
+// apply them without an external tool.
+package migrations
+
+import "embed"
+
+// Files holds every NNNNNN_name.up.sql and NNNNNN_name.down.sql migration.
+//
+//go:embed *.sql
+var Files embed.FS